@@ -0,0 +1,81 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// Accelerator is a number of accelerator cards, such as
+// GPUs, of the same type to attach to an instance.
+type Accelerator struct {
+	// Type is the accelerator type e.g "nvidia-tesla-t4".
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+}
+
+var (
+	errBlankAcceleratorType        = errors.New("expecting a non-blank accelerator type")
+	errNonPositiveAcceleratorCount = errors.New("expecting a positive accelerator count")
+)
+
+func (acc *Accelerator) Validate() error {
+	if acc == nil || acc.Type == "" {
+		return errBlankAcceleratorType
+	}
+	if acc.Count <= 0 {
+		return errNonPositiveAcceleratorCount
+	}
+	return nil
+}
+
+func (ireq *InstanceRequest) validateAccelerators() error {
+	for _, acc := range ireq.Accelerators {
+		if err := acc.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guestAccelerators returns the accelerators to attach, their types as
+// the zonal URLs that instances expect or else as the bare type names
+// that instance properties expect.
+func (ireq *InstanceRequest) guestAccelerators(selfLinks bool) []*compute.AcceleratorConfig {
+	var configs []*compute.AcceleratorConfig
+	for _, acc := range ireq.Accelerators {
+		acceleratorType := acc.Type
+		if selfLinks {
+			acceleratorType = SelfLink(ireq.Project, "zones", ireq.Zone, "acceleratorTypes", acc.Type)
+		}
+		configs = append(configs, &compute.AcceleratorConfig{
+			AcceleratorType:  acceleratorType,
+			AcceleratorCount: acc.Count,
+		})
+	}
+	return configs
+}
+
+func (c *Client) acceleratorTypesService() *compute.AcceleratorTypesService {
+	return compute.NewAcceleratorTypesService(c.computeSrvc)
+}
+
+// checkAcceleratorsAvailable checks that the zone offers each of the
+// accelerator types requested by ireq, in the requested numbers.
+func (c *Client) checkAcceleratorsAvailable(ctx context.Context, ireq *InstanceRequest) error {
+	for _, acc := range ireq.Accelerators {
+		at, err := c.acceleratorType(ctx, ireq.Project, ireq.Zone, acc.Type)
+		if err != nil {
+			if isNotFound(err) {
+				return fmt.Errorf("accelerator type %q isn't available in zone %q", acc.Type, ireq.Zone)
+			}
+			return err
+		}
+		if max := at.MaximumCardsPerInstance; max > 0 && acc.Count > max {
+			return fmt.Errorf("accelerator type %q allows at most %d cards per instance, got %d", acc.Type, max, acc.Count)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestCreateInstanceAccelerators(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	var inserted []*compute.Instance
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+		case "GET " + zonePath + "/acceleratorTypes/nvidia-tesla-t4":
+			writeJSON(w, &compute.AcceleratorType{Name: "nvidia-tesla-t4", MaximumCardsPerInstance: 4})
+		case "POST " + zonePath + "/instances":
+			instance := new(compute.Instance)
+			readJSON(t, req, instance)
+			inserted = append(inserted, instance)
+			writeJSON(w, &compute.Operation{Name: "op-" + instance.Name})
+		default:
+			writeNotFound(w)
+		}
+	})
+
+	newRequest := func(accs ...*Accelerator) *InstanceRequest {
+		return &InstanceRequest{
+			Project:          "sample",
+			Zone:             "us-central1-c",
+			Name:             "trainer",
+			NetworkInterface: BasicExternalNATNetworkInterface,
+			Accelerators:     accs,
+		}
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		ireq    *InstanceRequest
+		wantErr string
+	}{
+		{ireq: newRequest(&Accelerator{Type: "nvidia-tesla-a100", Count: 1}), wantErr: `accelerator type "nvidia-tesla-a100" isn't available in zone "us-central1-c"`},
+		{ireq: newRequest(&Accelerator{Type: "nvidia-tesla-t4", Count: 8}), wantErr: "at most 4 cards"},
+		{ireq: newRequest(&Accelerator{Type: "nvidia-tesla-t4"}), wantErr: errNonPositiveAcceleratorCount.Error()},
+	}
+	for i, tt := range tests {
+		results, err := client.CreateInstances(ctx, []*InstanceRequest{tt.ireq})
+		if err != nil {
+			t.Fatalf("#%d: CreateInstances: %v", i, err)
+		}
+		if err := results[0].Err; err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("#%d: got err %v, want it to contain %q", i, err, tt.wantErr)
+		}
+	}
+	if len(inserted) != 0 {
+		t.Fatalf("expected no inserts for unavailable accelerators, got %d", len(inserted))
+	}
+
+	results, err := client.CreateInstances(ctx, []*InstanceRequest{newRequest(&Accelerator{Type: "nvidia-tesla-t4", Count: 2})})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("available accelerator: unexpected err: %v %v", err, results[0].Err)
+	}
+	if len(inserted) != 1 {
+		t.Fatalf("expected the instance to be inserted, got %d inserts", len(inserted))
+	}
+	want := SelfLink("sample", "zones", "us-central1-c", "acceleratorTypes", "nvidia-tesla-t4")
+	if accs := inserted[0].GuestAccelerators; len(accs) != 1 || accs[0].AcceleratorType != want || accs[0].AcceleratorCount != 2 {
+		t.Errorf("guest accelerators: got %+v", accs)
+	}
+	if s := inserted[0].Scheduling; s == nil || s.OnHostMaintenance != "TERMINATE" {
+		t.Errorf("scheduling: got %+v, want instances with accelerators to terminate on host maintenance", s)
+	}
+
+	skipped := newRequest(&Accelerator{Type: "nvidia-tesla-a100", Count: 1})
+	skipped.SkipAcceleratorCheck = true
+	if results, _ := client.CreateInstances(ctx, []*InstanceRequest{skipped}); results[0].Err != nil {
+		t.Errorf("skipped check: unexpected err: %v", results[0].Err)
+	}
+}
@@ -0,0 +1,71 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// AdvancedFeatures are the features of the instance's CPUs.
+type AdvancedFeatures struct {
+	// ThreadsPerCore if set is the number of threads per physical core,
+	// 1 to disable simultaneous multithreading (SMT) or 2 to enable it.
+	ThreadsPerCore int64 `json:"threads_per_core,omitempty"`
+
+	// EnableNestedVirtualization when set lets the instance run VMs.
+	EnableNestedVirtualization bool `json:"enable_nested_virtualization,omitempty"`
+}
+
+var errInvalidThreadsPerCore = errors.New("expecting 1 or 2 threads per core")
+
+var (
+	// singleThreadedMachineFamilies are the machine families whose
+	// cores only have a thread, so their threads per core can't be set.
+	singleThreadedMachineFamilies = map[string]bool{
+		"t2a": true,
+		"t2d": true,
+	}
+
+	// noNestedVirtualizationMachineFamilies are the machine families
+	// that don't support nested virtualization, which needs Intel CPUs.
+	noNestedVirtualizationMachineFamilies = map[string]bool{
+		"e2":  true,
+		"n2d": true,
+		"c2d": true,
+		"c3d": true,
+		"t2a": true,
+		"t2d": true,
+	}
+)
+
+func (ireq *InstanceRequest) validateAdvancedFeatures() error {
+	af := ireq.AdvancedFeatures
+	if af == nil {
+		return nil
+	}
+	mt := ireq.machineTypeOrDefault()
+	if af.ThreadsPerCore != 0 {
+		if af.ThreadsPerCore != 1 && af.ThreadsPerCore != 2 {
+			return errInvalidThreadsPerCore
+		}
+		if singleThreadedMachineFamilies[mt.family()] {
+			return fmt.Errorf("machine type %q has a thread per core, its threads per core can't be set", mt.name())
+		}
+	}
+	if af.EnableNestedVirtualization && noNestedVirtualizationMachineFamilies[mt.family()] {
+		return fmt.Errorf("machine type %q doesn't support nested virtualization, expecting an Intel machine type such as %q", mt.name(), N1Standard1)
+	}
+	return nil
+}
+
+func (ireq *InstanceRequest) advancedMachineFeatures() *compute.AdvancedMachineFeatures {
+	af := ireq.AdvancedFeatures
+	if af == nil {
+		return nil
+	}
+	return &compute.AdvancedMachineFeatures{
+		ThreadsPerCore:             af.ThreadsPerCore,
+		EnableNestedVirtualization: af.EnableNestedVirtualization,
+	}
+}
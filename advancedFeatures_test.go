@@ -0,0 +1,54 @@
+package infra
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdvancedFeatures(t *testing.T) {
+	newRequest := func(mt *MachineType, af *AdvancedFeatures) *InstanceRequest {
+		return &InstanceRequest{
+			Project: "sample",
+			Zone:    "us-central1-c",
+			Name:    "web",
+
+			NetworkInterface: BasicExternalNATNetworkInterface,
+			MachineType:      mt,
+			AdvancedFeatures: af,
+		}
+	}
+
+	ireq := newRequest(&MachineType{Type: "n2-standard-8"}, &AdvancedFeatures{ThreadsPerCore: 1, EnableNestedVirtualization: true})
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	amf := ireq.toInstance().AdvancedMachineFeatures
+	if amf == nil || amf.ThreadsPerCore != 1 || !amf.EnableNestedVirtualization {
+		t.Errorf("got advanced machine features %+v", amf)
+	}
+	if amf := newRequest(nil, nil).toInstance().AdvancedMachineFeatures; amf != nil {
+		t.Errorf("expected no advanced machine features by default, got %+v", amf)
+	}
+
+	tests := []struct {
+		ireq    *InstanceRequest
+		wantErr string
+	}{
+		{ireq: newRequest(nil, &AdvancedFeatures{ThreadsPerCore: 3}), wantErr: "expecting 1 or 2 threads per core"},
+		{ireq: newRequest(&MachineType{Type: "t2d-standard-4"}, &AdvancedFeatures{ThreadsPerCore: 1}), wantErr: "can't be set"},
+		{ireq: newRequest(&MachineType{Type: "n2d-standard-4"}, &AdvancedFeatures{EnableNestedVirtualization: true}), wantErr: "doesn't support nested virtualization"},
+		{ireq: newRequest(&MachineType{CPUCount: 2, MemoryMBs: 4096}, &AdvancedFeatures{EnableNestedVirtualization: true})},
+	}
+	for i, tt := range tests {
+		err := tt.ireq.validateForCreate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected err: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("#%d: got err %v, want it to contain %q", i, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+var errZoneForAllZones = errors.New("expecting a blank zone to list the instances of all zones")
+
+func (ireq *InstancesRequest) validateForAllZones() error {
+	if ireq == nil || ireq.Project == "" {
+		return errBlankProject
+	}
+	if ireq.Zone != "" {
+		return errZoneForAllZones
+	}
+	return validateLabels(ireq.Labels)
+}
+
+// ListInstancesInAllZones is like ListInstances but lists the instances of
+// every zone of the project, with an aggregated list, so req.Zone must be
+// blank. Each page only has the instances of one zone, set in its Zone,
+// and pages of the same zone can repeat. The pages that the API returned
+// together share their PageNumber, which MaxPages limits.
+func (c *Client) ListInstancesInAllZones(ctx context.Context, req *InstancesRequest) (*InstancePagesResponse, error) {
+	req = inProject(req, c.DefaultProject)
+	if err := req.validateForAllZones(); err != nil {
+		return nil, err
+	}
+
+	maxResultsPerPage, err := resultsPerPageOrDefault(req.ResultsPerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelChan, cancelFn := makeCanceler(ctx)
+	pagesChan := make(chan *InstancePage, maxBufferedPagesOrDefault(req.MaxBufferedPages))
+	go func() {
+		defer close(pagesChan)
+		defer cancelFn()
+
+		alc := c.instancesService().AggregatedList(req.Project).Context(ctx)
+		alc.MaxResults(maxResultsPerPage)
+		if filter := req.filter(); filter != "" {
+			alc.Filter(filter)
+		}
+
+		if req.OrderBy != "" {
+			alc.OrderBy(req.OrderBy)
+		}
+		if req.PartialSuccess {
+			alc.ReturnPartialSuccess(true)
+		}
+
+		var alr *compute.InstanceAggregatedList
+		fetch := func(pageToken string) (_ string, err error) {
+			alr, err = alc.PageToken(pageToken).Do()
+			if err != nil {
+				return "", err
+			}
+			return alr.NextPageToken, nil
+		}
+		send := func(pageNumber int64, err error) bool {
+			pages := []*InstancePage{{PageNumber: pageNumber, Err: err}}
+			if err == nil {
+				pages = zonePages(pageNumber, alr)
+			}
+			for _, ipage := range pages {
+				select {
+				case pagesChan <- ipage:
+				case <-cancelChan:
+					return false
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		c.fetchPages(ctx, cancelChan, req.MaxPages, pageThrottleOrDefault(req.ThrottleMillis), fetch, send)
+	}()
+
+	ires := &InstancePagesResponse{
+		Pages:  pagesChan,
+		Cancel: cancelFn,
+	}
+
+	return ires, nil
+}
+
+// zonePages splits the aggregated list into a page per zone, in the order
+// of the zones' names, skipping the zones without instances.
+func zonePages(pageNumber int64, alr *compute.InstanceAggregatedList) []*InstancePage {
+	var scopes []string
+	for scope, scoped := range alr.Items {
+		if len(scoped.Instances) > 0 || (scoped.Warning != nil && scoped.Warning.Code != "NO_RESULTS_ON_PAGE") {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	var pages []*InstancePage
+	for _, scope := range scopes {
+		scoped := alr.Items[scope]
+		ipage := &InstancePage{
+			PageNumber: pageNumber,
+			Zone:       strings.TrimPrefix(scope, "zones/"),
+			Instances:  scoped.Instances,
+		}
+		if w := scoped.Warning; w != nil {
+			ipage.Warning = &compute.InstanceListWarning{Code: w.Code, Message: w.Message}
+		}
+		pages = append(pages, ipage)
+	}
+	return pages
+}
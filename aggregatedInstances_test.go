@@ -0,0 +1,90 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestListInstancesInAllZones(t *testing.T) {
+	pages := map[string]*compute.InstanceAggregatedList{
+		"": {
+			Items: map[string]compute.InstancesScopedList{
+				"zones/us-east1-b": {Instances: []*compute.Instance{{Name: "api"}}},
+				"zones/us-central1-c": {Instances: []*compute.Instance{
+					{Name: "web-1"},
+					{Name: "web-2"},
+				}},
+				"zones/europe-west1-b": {Warning: &compute.InstancesScopedListWarning{Code: "NO_RESULTS_ON_PAGE"}},
+			},
+			NextPageToken: "page-2",
+		},
+		"page-2": {
+			Items: map[string]compute.InstancesScopedList{
+				"zones/us-central1-c": {Instances: []*compute.Instance{{Name: "web-3"}}},
+			},
+			NextPageToken: "page-3",
+		},
+		"page-3": {
+			Items: map[string]compute.InstancesScopedList{
+				"zones/us-east1-b": {Instances: []*compute.Instance{{Name: "api-2"}}},
+			},
+		},
+	}
+	var queries []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/compute/v1/projects/sample/aggregated/instances" {
+			http.Error(w, "unexpected route "+req.URL.Path, http.StatusNotFound)
+			return
+		}
+		q := req.URL.Query()
+		queries = append(queries, q.Get("filter")+"|"+q.Get("orderBy")+"|"+q.Get("maxResults"))
+		writeJSON(w, pages[q.Get("pageToken")])
+	})
+
+	list := func(ireq *InstancesRequest) (zones, names []string) {
+		ires, err := client.ListInstancesInAllZones(context.Background(), ireq)
+		if err != nil {
+			t.Fatalf("ListInstancesInAllZones: %v", err)
+		}
+		for page := range ires.Pages {
+			if page.Err != nil {
+				t.Fatalf("page #%d: %v", page.PageNumber, page.Err)
+			}
+			for _, instance := range page.Instances {
+				zones = append(zones, page.Zone)
+				names = append(names, instance.Name)
+			}
+		}
+		return zones, names
+	}
+
+	zones, names := list(&InstancesRequest{
+		Project:        "sample",
+		Filter:         "status = RUNNING",
+		OrderBy:        "name",
+		ResultsPerPage: 3,
+	})
+	if want := []string{"web-1", "web-2", "api", "web-3", "api-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names: got %q want %q", names, want)
+	}
+	if want := []string{"us-central1-c", "us-central1-c", "us-east1-b", "us-central1-c", "us-east1-b"}; !reflect.DeepEqual(zones, want) {
+		t.Errorf("zones: got %q want %q", zones, want)
+	}
+	if want := []string{"status = RUNNING|name|3", "status = RUNNING|name|3", "status = RUNNING|name|3"}; !reflect.DeepEqual(queries, want) {
+		t.Errorf("queries: got %q want %q", queries, want)
+	}
+
+	// Like ListInstances, MaxPages is the last page number, counting from 0.
+	queries = nil
+	if _, names := list(&InstancesRequest{Project: "sample", MaxPages: 1}); len(names) != 4 || len(queries) != 2 {
+		t.Errorf("with MaxPages 1 got %q after %d requests, want the 4 instances of the first 2 pages", names, len(queries))
+	}
+
+	if _, err := client.ListInstancesInAllZones(context.Background(), &InstancesRequest{Project: "sample", Zone: "us-central1-c"}); err != errZoneForAllZones {
+		t.Errorf("got err %v want %v", err, errZoneForAllZones)
+	}
+}
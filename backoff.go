@@ -0,0 +1,54 @@
+package infra
+
+import "time"
+
+// Backoff computes exponentially growing delays, such as
+// between polls of an operation or retries of a failed call.
+// The zero value returns no delay; a Backoff must not be
+// used concurrently.
+type Backoff struct {
+	// Initial is the first delay returned by Next.
+	Initial time.Duration
+
+	// Max if set caps the delays returned by Next.
+	Max time.Duration
+
+	// Factor multiplies the delay after each call to Next.
+	// Factors below 1 keep the delay at Initial.
+	Factor float64
+
+	// Jitter when set randomly shortens or lengthens
+	// each delay by up to 20%, still capped at Max.
+	Jitter bool
+
+	next time.Duration
+}
+
+// Next returns the delay to wait before the next attempt.
+func (b *Backoff) Next() time.Duration {
+	d := b.next
+	if d == 0 {
+		d = b.Initial
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+
+	b.next = d
+	if b.Factor > 1 {
+		b.next = time.Duration(float64(d) * b.Factor)
+	}
+
+	if b.Jitter {
+		d = jitter(d)
+		if b.Max > 0 && d > b.Max {
+			d = b.Max
+		}
+	}
+	return d
+}
+
+// Reset makes the next call to Next return Initial again.
+func (b *Backoff) Reset() {
+	b.next = 0
+}
@@ -0,0 +1,53 @@
+package infra
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Factor: 2}
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := b.Next(); got != w {
+			t.Errorf("#%d: got %s want %s", i, got, w)
+		}
+	}
+
+	b.Reset()
+	if got := b.Next(); got != b.Initial {
+		t.Errorf("after Reset: got %s want %s", got, b.Initial)
+	}
+}
+
+func TestBackoffWithoutFactor(t *testing.T) {
+	b := &Backoff{Initial: 50 * time.Millisecond}
+	for i := 0; i < 3; i++ {
+		if got := b.Next(); got != b.Initial {
+			t.Errorf("#%d: got %s want %s", i, got, b.Initial)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := &Backoff{Initial: 100 * time.Millisecond, Max: 800 * time.Millisecond, Factor: 2, Jitter: true}
+	unjittered := &Backoff{Initial: b.Initial, Max: b.Max, Factor: b.Factor}
+	for i := 0; i < 20; i++ {
+		got, d := b.Next(), unjittered.Next()
+		min := time.Duration(float64(d) * (1 - throttleJitter))
+		max := time.Duration(float64(d) * (1 + throttleJitter))
+		if max > b.Max {
+			max = b.Max
+		}
+		if got < min || got > max {
+			t.Errorf("#%d: got %s, want within [%s, %s]", i, got, min, max)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// BulkInstanceRequest describes count identical instances to create
+// in a single bulk insert, named after NamePattern.
+type BulkInstanceRequest struct {
+	Project string `json:"project"`
+	Zone    string `json:"zone"`
+
+	// NamePattern names the instances, with its one continuous
+	// sequence of '#' replaced by the instance's number e.g
+	// "web-###" creates instances "web-001", "web-002" and so on.
+	NamePattern string `json:"name_pattern"`
+
+	Count int64 `json:"count"`
+
+	// MinCount if set is the minimum number of instances that
+	// must be created for the bulk insert to succeed, otherwise
+	// all Count instances must be created.
+	MinCount int64 `json:"min_count,omitempty"`
+
+	// Instance describes the properties shared by all the instances.
+	// Its Project, Zone and Name are ignored.
+	Instance *InstanceRequest `json:"instance"`
+}
+
+// bulkNamePatternRegexp matches name patterns with one continuous
+// sequence of up to 18 '#' placeholders for the instance numbers.
+var bulkNamePatternRegexp = regexp.MustCompile("^[a-z][-a-z0-9]*#{1,18}[-a-z0-9]*$")
+
+var (
+	errNilInstanceRequest = errors.New("expecting a non-nil instance request")
+	errMinCountExceeds    = errors.New("min count exceeds count")
+)
+
+func (breq *BulkInstanceRequest) Validate() error {
+	if breq == nil || breq.Instance == nil {
+		return errNilInstanceRequest
+	}
+	if !bulkNamePatternRegexp.MatchString(breq.NamePattern) {
+		return fmt.Errorf("%q is not a name pattern, expecting one with one sequence of '#' such as %q", breq.NamePattern, "web-###")
+	}
+	if breq.Count <= 0 {
+		return errNonPositiveCount
+	}
+	if breq.MinCount > breq.Count {
+		return errMinCountExceeds
+	}
+	return breq.instanceRequest().validateForCreate()
+}
+
+// instanceRequest returns the shared instance request
+// placed in the bulk request's project and zone.
+func (breq *BulkInstanceRequest) instanceRequest() *InstanceRequest {
+	ireq := *breq.Instance
+	ireq.Project = breq.Project
+	ireq.Zone = breq.Zone
+	ireq.Name = breq.NamePattern
+	return &ireq
+}
+
+// toInstanceProperties returns the properties of the instances that
+// the request describes, built from the same instance as toInstance so
+// that the two can't drift apart.
+func (ireq *InstanceRequest) toInstanceProperties(fromFiles []*generatedMetadata) *compute.InstanceProperties {
+	instance := ireq.instance(false)
+	return &compute.InstanceProperties{
+		Disks: instance.Disks,
+
+		Metadata:    ireq.metadata(fromFiles...),
+		Description: instance.Description,
+		MachineType: instance.MachineType,
+
+		ServiceAccounts: instance.ServiceAccounts,
+
+		NetworkInterfaces: instance.NetworkInterfaces,
+
+		ReservationAffinity: instance.ReservationAffinity,
+		ResourcePolicies:    instance.ResourcePolicies,
+
+		Labels:         instance.Labels,
+		Tags:           instance.Tags,
+		MinCpuPlatform: instance.MinCpuPlatform,
+
+		NetworkPerformanceConfig: instance.NetworkPerformanceConfig,
+		AdvancedMachineFeatures:  instance.AdvancedMachineFeatures,
+
+		GuestAccelerators: instance.GuestAccelerators,
+		Scheduling:        instance.Scheduling,
+	}
+}
+
+// BulkCreateInstances creates the instances described by breq with a
+// single bulk insert, which is far more efficient than creating them
+// one at a time when creating many identical instances.
+func (c *Client) BulkCreateInstances(ctx context.Context, breq *BulkInstanceRequest) (*compute.Operation, error) {
+	breq = inProject(breq, c.DefaultProject)
+	if err := breq.Validate(); err != nil {
+		return nil, err
+	}
+
+	ireq := breq.instanceRequest()
+	fromFiles, err := ireq.readMetadataFromFiles()
+	if err != nil {
+		return nil, err
+	}
+	req := c.instancesService().BulkInsert(breq.Project, breq.Zone, &compute.BulkInsertInstanceResource{
+		Count:              breq.Count,
+		MinCount:           breq.MinCount,
+		NamePattern:        breq.NamePattern,
+		InstanceProperties: ireq.toInstanceProperties(fromFiles),
+	})
+	return req.Context(ctx).Do()
+}
@@ -0,0 +1,97 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestBulkCreateInstances(t *testing.T) {
+	const bulkInsertPath = "/compute/v1/projects/sample/zones/us-central1-c/instances/bulkInsert"
+	var sent *compute.BulkInsertInstanceResource
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "POST "+bulkInsertPath {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		sent = new(compute.BulkInsertInstanceResource)
+		readJSON(t, req, sent)
+		writeJSON(w, &compute.Operation{Name: "op-bulk"})
+	})
+
+	breq := &BulkInstanceRequest{
+		Project:     "sample",
+		Zone:        "us-central1-c",
+		NamePattern: "web-###",
+		Count:       50,
+		MinCount:    40,
+		Instance: &InstanceRequest{
+			MachineType:      &MachineType{CPUCount: 2, MemoryMBs: 4096},
+			NetworkInterface: BasicExternalNATNetworkInterface,
+			StartupScript:    "#!/bin/sh\necho hello",
+		},
+	}
+	if _, err := client.BulkCreateInstances(context.Background(), breq); err != nil {
+		t.Fatalf("BulkCreateInstances: %v", err)
+	}
+
+	if sent.Count != 50 || sent.MinCount != 40 {
+		t.Errorf("got count %d and min count %d want 50 and 40", sent.Count, sent.MinCount)
+	}
+	if sent.NamePattern != "web-###" {
+		t.Errorf("name pattern: got %q want %q", sent.NamePattern, "web-###")
+	}
+	props := sent.InstanceProperties
+	if props == nil {
+		t.Fatal("expected instance properties")
+	}
+	if props.MachineType != "custom-2-4096" {
+		t.Errorf("machine type: got %q want %q", props.MachineType, "custom-2-4096")
+	}
+	if len(props.NetworkInterfaces) != 1 || len(props.Disks) != 1 {
+		t.Errorf("got %d network interfaces and %d disks want 1 of each", len(props.NetworkInterfaces), len(props.Disks))
+	}
+	if props.Metadata == nil || len(props.Metadata.Items) != 1 || props.Metadata.Items[0].Key != "startup-script" {
+		t.Errorf("metadata: got %+v want the startup script", props.Metadata)
+	}
+}
+
+func TestBulkInstanceRequestValidate(t *testing.T) {
+	valid := func() *BulkInstanceRequest {
+		return &BulkInstanceRequest{
+			Project:     "sample",
+			Zone:        "us-central1-c",
+			NamePattern: "web-###",
+			Count:       3,
+			Instance:    &InstanceRequest{NetworkInterface: BasicExternalNATNetworkInterface},
+		}
+	}
+
+	tests := [...]struct {
+		mutate  func(*BulkInstanceRequest)
+		wantErr bool
+	}{
+		0:  {mutate: func(*BulkInstanceRequest) {}},
+		1:  {mutate: func(breq *BulkInstanceRequest) { breq.NamePattern = "web-#-#" }, wantErr: true},
+		2:  {mutate: func(breq *BulkInstanceRequest) { breq.NamePattern = "web" }, wantErr: true},
+		3:  {mutate: func(breq *BulkInstanceRequest) { breq.NamePattern = "###-web" }, wantErr: true},
+		4:  {mutate: func(breq *BulkInstanceRequest) { breq.NamePattern = "Web-###" }, wantErr: true},
+		5:  {mutate: func(breq *BulkInstanceRequest) { breq.NamePattern = "web-###################" }, wantErr: true},
+		6:  {mutate: func(breq *BulkInstanceRequest) { breq.NamePattern = "web-##-canary" }},
+		7:  {mutate: func(breq *BulkInstanceRequest) { breq.Count = 0 }, wantErr: true},
+		8:  {mutate: func(breq *BulkInstanceRequest) { breq.MinCount = 4 }, wantErr: true},
+		9:  {mutate: func(breq *BulkInstanceRequest) { breq.Instance = nil }, wantErr: true},
+		10: {mutate: func(breq *BulkInstanceRequest) { breq.Instance.NetworkInterface = nil }, wantErr: true},
+	}
+
+	for i, tt := range tests {
+		breq := valid()
+		tt.mutate(breq)
+		err := breq.Validate()
+		if tt.wantErr != (err != nil) {
+			t.Errorf("#%d: got err %v, want an error: %t", i, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ObjectChecksum returns the CRC32C checksum and the size of the object
+// from its metadata, without downloading it. Compare them with those
+// of FileChecksum to verify that a local file matches the object.
+func (c *Client) ObjectChecksum(ctx context.Context, bucket, object string) (crc32c uint32, size int64, err error) {
+	params := &DownloadParams{Bucket: bucket, Name: object}
+	if err := params.Validate(); err != nil {
+		return 0, 0, err
+	}
+	obj, err := c.objectsService().Get(bucket, object).Context(ctx).Do()
+	if err != nil {
+		return 0, 0, err
+	}
+	crc32c, err = decodeCRC32C(obj.Crc32c)
+	if err != nil {
+		return 0, 0, fmt.Errorf("object %q: %w", object, err)
+	}
+	return crc32c, int64(obj.Size), nil
+}
+
+// decodeCRC32C decodes a CRC32C checksum encoded as Cloud Storage
+// does, that is base64 encoded in big-endian byte order.
+func decodeCRC32C(encoded string) (uint32, error) {
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("decoding CRC32C %q: %w", encoded, err)
+	}
+	if len(blob) != 4 {
+		return 0, fmt.Errorf("decoding CRC32C %q: got %d bytes, expecting 4", encoded, len(blob))
+	}
+	return binary.BigEndian.Uint32(blob), nil
+}
+
+// FileChecksum returns the CRC32C checksum and the size of the local file.
+func FileChecksum(path string) (crc32c uint32, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	h := crc32.New(crc32cTable)
+	size, err = io.Copy(h, f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return h.Sum32(), size, nil
+}
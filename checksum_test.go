@@ -0,0 +1,57 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/api/storage/v1"
+)
+
+func TestObjectChecksum(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/storage/v1/b/assets/o/hello.txt" {
+			writeNotFound(w)
+			return
+		}
+		// The CRC32C of "hello world" is 0xc99465aa.
+		writeJSON(w, &storage.Object{Name: "hello.txt", Bucket: "assets", Crc32c: "yZRlqg==", Size: 11})
+	})
+
+	crc, size, err := client.ObjectChecksum(context.Background(), "assets", "hello.txt")
+	if err != nil {
+		t.Fatalf("ObjectChecksum: %v", err)
+	}
+	if crc != 0xc99465aa {
+		t.Errorf("crc32c: got %#x want %#x", crc, 0xc99465aa)
+	}
+	if size != 11 {
+		t.Errorf("size: got %d want %d", size, 11)
+	}
+
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileCRC, fileSize, err := FileChecksum(path)
+	if err != nil {
+		t.Fatalf("FileChecksum: %v", err)
+	}
+	if fileCRC != crc || fileSize != size {
+		t.Errorf("local file: got crc32c %#x and size %d want %#x and %d", fileCRC, fileSize, crc, size)
+	}
+
+	if _, _, err := client.ObjectChecksum(context.Background(), "assets", "missing.txt"); !isNotFound(err) {
+		t.Errorf("missing object: got err %v want a not found error", err)
+	}
+}
+
+func TestDecodeCRC32C(t *testing.T) {
+	for i, encoded := range []string{"", "yZRl", "not base64!"} {
+		if _, err := decodeCRC32C(encoded); err == nil {
+			t.Errorf("#%d: %q: expected an error", i, encoded)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package infra
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// withContentAddressedName returns a copy of params named after the
+// SHA-256 digest of its content. Content from Reader is buffered so
+// that the copy uploads what was hashed.
+func (params *UploadParams) withContentAddressedName() (*UploadParams, error) {
+	copied := *params
+	h := sha256.New()
+	if params.ReaderAt != nil {
+		if _, err := io.Copy(h, io.NewSectionReader(params.ReaderAt, 0, params.Size)); err != nil {
+			return nil, err
+		}
+	} else {
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(io.MultiWriter(h, buf), params.Reader()); err != nil {
+			return nil, err
+		}
+		content := buf.Bytes()
+		copied.Reader = func() io.Reader { return bytes.NewReader(content) }
+	}
+	copied.Name = params.NamePrefix + hex.EncodeToString(h.Sum(nil))
+	return &copied, nil
+}
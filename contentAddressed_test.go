@@ -0,0 +1,53 @@
+package infra
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestUploadContentAddressed(t *testing.T) {
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+	ctx := context.Background()
+
+	upload := func(params *UploadParams) string {
+		t.Helper()
+		params.Project = "sample"
+		params.Bucket = "blobs"
+		params.ContentAddressed = true
+		params.NamePrefix = "sha256/"
+		obj, err := client.UploadWithParams(ctx, params)
+		if err != nil {
+			t.Fatalf("UploadWithParams: %v", err)
+		}
+		return obj.Name
+	}
+
+	const content = "the same bytes"
+	first := upload(&UploadParams{Reader: func() io.Reader { return strings.NewReader(content) }})
+	second := upload(&UploadParams{ReaderAt: strings.NewReader(content), Size: int64(len(content))})
+	other := upload(&UploadParams{Reader: func() io.Reader { return strings.NewReader("other bytes") }})
+
+	sum := sha256.Sum256([]byte(content))
+	if want := "sha256/" + hex.EncodeToString(sum[:]); first != want {
+		t.Errorf("name: got %q want %q", first, want)
+	}
+	if second != first {
+		t.Errorf("identical content got names %q and %q", first, second)
+	}
+	if other == first {
+		t.Errorf("different content got the same name %q", other)
+	}
+	if got := string(fs.contents["blobs/"+first]); got != content {
+		t.Errorf("content: got %q want %q", got, content)
+	}
+
+	// A set name wins over the content's.
+	if name := upload(&UploadParams{Name: "readme.txt", Reader: func() io.Reader { return strings.NewReader(content) }}); name != "readme.txt" {
+		t.Errorf("got name %q want %q", name, "readme.txt")
+	}
+}
@@ -0,0 +1,108 @@
+package infra
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/api/dns/v1"
+)
+
+func (c *Client) managedZonesService() *dns.ManagedZonesService {
+	return dns.NewManagedZonesService(c.dnsSrvc)
+}
+
+// allRecordSets returns every record set of the managed zone.
+func (c *Client) allRecordSets(ctx context.Context, project, zone string) ([]*dns.ResourceRecordSet, error) {
+	rreq := &RecordSetRequest{Project: project, Zone: zone}
+	if err := rreq.Validate(); err != nil {
+		return nil, err
+	}
+	maxResultsPerPage, err := resultsPerPageOrDefault(rreq.ResultsPerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrsets []*dns.ResourceRecordSet
+	req := c.recordSetsService().List(project, zone).MaxResults(maxResultsPerPage)
+	err = req.Pages(ctx, func(res *dns.ResourceRecordSetsListResponse) error {
+		rrsets = append(rrsets, res.Rrsets...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rrsets, nil
+}
+
+// CopyZoneRecords adds the record sets of the managed zone srcZone to
+// dstZone, e.g to set up a staging zone that mirrors production. The SOA
+// and NS record sets of the source's apex are skipped since the
+// destination zone has its own. If rewriteApex is set, the names under
+// the source's DNS name are moved under the destination's DNS name e.g
+// "www.orijtech.com." to "www.staging.orijtech.com.", otherwise the names
+// are copied as they are. The record data, such as the targets of CNAME
+// records, is copied as is.
+func (c *Client) CopyZoneRecords(ctx context.Context, srcProject, srcZone, dstProject, dstZone string, rewriteApex bool) (*dns.Change, error) {
+	srcProject, dstProject = c.projectOrDefault(srcProject), c.projectOrDefault(dstProject)
+	if srcProject == "" || dstProject == "" {
+		return nil, errEmptyProject
+	}
+	if srcZone == "" || dstZone == "" {
+		return nil, errEmptyZone
+	}
+
+	src, err := c.managedZonesService().Get(srcProject, srcZone).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	dstApex := src.DnsName
+	if rewriteApex {
+		dst, err := c.managedZonesService().Get(dstProject, dstZone).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		dstApex = dst.DnsName
+	}
+
+	rrsets, err := c.allRecordSets(ctx, srcProject, srcZone)
+	if err != nil {
+		return nil, err
+	}
+	var additions []*dns.ResourceRecordSet
+	for _, rrset := range rrsets {
+		if rrset.Name == src.DnsName && (rrset.Type == "SOA" || rrset.Type == string(NS)) {
+			continue
+		}
+		copied := &dns.ResourceRecordSet{
+			Name:    rewriteName(rrset.Name, src.DnsName, dstApex),
+			Type:    rrset.Type,
+			Ttl:     rrset.Ttl,
+			Rrdatas: rrset.Rrdatas,
+
+			RoutingPolicy:    rrset.RoutingPolicy,
+			SignatureRrdatas: rrset.SignatureRrdatas,
+		}
+		additions = append(additions, copied)
+	}
+	if len(additions) == 0 {
+		// Cloud DNS rejects empty changes.
+		return new(dns.Change), nil
+	}
+
+	if err := c.checkCNAMEConflicts(ctx, dstProject, dstZone, additions, nil); err != nil {
+		return nil, err
+	}
+	change := &dns.Change{Additions: additions}
+	return c.changesService().Create(dstProject, dstZone, change).Context(ctx).Do()
+}
+
+// rewriteName moves name from under the DNS name srcApex to under dstApex.
+func rewriteName(name, srcApex, dstApex string) string {
+	if name == srcApex {
+		return dstApex
+	}
+	if strings.HasSuffix(name, "."+srcApex) {
+		return strings.TrimSuffix(name, srcApex) + dstApex
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/dns/v1"
+)
+
+func TestCopyZoneRecords(t *testing.T) {
+	const prodPath = "/dns/v1/projects/sample/managedZones/prod"
+	prodRecordSets := []*dns.ResourceRecordSet{
+		{Name: "orijtech.com.", Type: "SOA", Ttl: 21600, Rrdatas: []string{"ns-cloud-a1.googledomains.com. admin. 1 21600 3600 259200 300"}},
+		{Name: "orijtech.com.", Type: "NS", Ttl: 21600, Rrdatas: []string{"ns-cloud-a1.googledomains.com."}},
+		{Name: "orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.1"}},
+		{Name: "www.orijtech.com.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"orijtech.com."}},
+		{Name: "dev.orijtech.com.", Type: "NS", Ttl: 300, Rrdatas: []string{"ns1.dev.orijtech.com."}},
+	}
+
+	for _, rewriteApex := range []bool{false, true} {
+		fz := new(fakeZone)
+		client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+			switch route := req.Method + " " + req.URL.Path; route {
+			case "GET " + prodPath:
+				writeJSON(w, &dns.ManagedZone{Name: "prod", DnsName: "orijtech.com."})
+			case "GET " + prodPath + "/rrsets":
+				writeJSON(w, &dns.ResourceRecordSetsListResponse{Rrsets: prodRecordSets})
+			case "GET " + fakeZonePath:
+				writeJSON(w, &dns.ManagedZone{Name: "zone", DnsName: "staging.orijtech.com."})
+			default:
+				fz.ServeHTTP(w, req)
+			}
+		})
+
+		if _, err := client.CopyZoneRecords(context.Background(), "sample", "prod", "sample", "zone", rewriteApex); err != nil {
+			t.Fatalf("rewriteApex %t: CopyZoneRecords: %v", rewriteApex, err)
+		}
+
+		var got []string
+		for _, rrset := range fz.rrsets {
+			got = append(got, rrset.Type+" "+rrset.Name)
+		}
+		want := []string{"A orijtech.com.", "CNAME www.orijtech.com.", "NS dev.orijtech.com."}
+		if rewriteApex {
+			want = []string{"A staging.orijtech.com.", "CNAME www.staging.orijtech.com.", "NS dev.staging.orijtech.com."}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("rewriteApex %t: got record sets %q want %q", rewriteApex, got, want)
+		}
+	}
+}
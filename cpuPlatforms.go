@@ -0,0 +1,38 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+)
+
+// AvailableCPUPlatforms returns the CPU platforms that the zone offers
+// e.g "Intel Ice Lake", which an instance's MinCPUPlatform must be one of.
+func (c *Client) AvailableCPUPlatforms(ctx context.Context, project, zone string) ([]string, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	if err := validateZone(zone); err != nil {
+		return nil, err
+	}
+	z, err := c.zone(ctx, project, zone)
+	if err != nil {
+		return nil, err
+	}
+	// The zone may be cached, so hand out a copy of its platforms.
+	return append([]string(nil), z.AvailableCpuPlatforms...), nil
+}
+
+// validateMinCPUPlatform checks that platforms, those of the
+// zone, has the CPU platform requested by ireq if it requests one.
+func (ireq *InstanceRequest) validateMinCPUPlatform(platforms []string) error {
+	if ireq.MinCPUPlatform == "" {
+		return nil
+	}
+	for _, platform := range platforms {
+		if platform == ireq.MinCPUPlatform {
+			return nil
+		}
+	}
+	return fmt.Errorf("CPU platform %q isn't available in zone %q, expecting one of %q", ireq.MinCPUPlatform, ireq.Zone, platforms)
+}
@@ -0,0 +1,69 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestAvailableCPUPlatforms(t *testing.T) {
+	platforms := []string{"Intel Cascade Lake", "Intel Ice Lake", "AMD Milan"}
+	var minCPUPlatform string
+	inserts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET /compute/v1/projects/sample/zones/us-central1-c":
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP", AvailableCpuPlatforms: platforms})
+		case "POST /compute/v1/projects/sample/zones/us-central1-c/instances":
+			inserts++
+			instance := new(compute.Instance)
+			readJSON(t, req, instance)
+			minCPUPlatform = instance.MinCpuPlatform
+			writeJSON(w, &compute.Operation{Name: "op-" + instance.Name})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	got, err := client.AvailableCPUPlatforms(ctx, "sample", "us-central1-c")
+	if err != nil {
+		t.Fatalf("AvailableCPUPlatforms: %v", err)
+	}
+	if !reflect.DeepEqual(got, platforms) {
+		t.Errorf("platforms: got %q want %q", got, platforms)
+	}
+
+	newRequest := func(platform string) *InstanceRequest {
+		return &InstanceRequest{
+			Project:          "sample",
+			Zone:             "us-central1-c",
+			Name:             "web",
+			NetworkInterface: BasicExternalNATNetworkInterface,
+			MinCPUPlatform:   platform,
+		}
+	}
+
+	results, err := client.CreateInstances(ctx, []*InstanceRequest{newRequest("Intel Ice Lake")})
+	if err != nil {
+		t.Fatalf("CreateInstances: %v", err)
+	}
+	if err := results[0].Err; err != nil {
+		t.Fatalf("available platform: unexpected err: %v", err)
+	}
+	if minCPUPlatform != "Intel Ice Lake" {
+		t.Errorf("min CPU platform: got %q want %q", minCPUPlatform, "Intel Ice Lake")
+	}
+
+	_, err = client.CreateInstance(ctx, newRequest("Intel Sapphire Rapids"))
+	if err == nil || !strings.Contains(err.Error(), "isn't available") {
+		t.Errorf("unavailable platform: got err %v, want it rejected", err)
+	}
+	if inserts != 1 {
+		t.Errorf("inserts: got %d want 1, the unavailable platform mustn't be inserted", inserts)
+	}
+}
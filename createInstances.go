@@ -0,0 +1,102 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// InstanceResult is the outcome of creating one of the
+// instances requested in a call to CreateInstances.
+type InstanceResult struct {
+	Request *InstanceRequest `json:"request"`
+
+	// Operation is the insert operation, waited on until done
+	// if the request set BlockUntilCompletion.
+	Operation *compute.Operation `json:"operation,omitempty"`
+
+	// Err is the reason why the instance couldn't be created.
+	Err error `json:"-"`
+}
+
+const defaultCreateInstancesConcurrency = 8
+
+var errNoInstanceRequests = errors.New("expecting at least one instance request")
+
+// CreateInstances creates the requested instances concurrently and
+// reports the outcome of each request in the result at its index.
+// Every request is validated before any instance is created, and the
+// invalid ones are reported without being sent. Requests that set
+// BlockUntilCompletion wait for their instance's creation to complete.
+func (c *Client) CreateInstances(ctx context.Context, reqs []*InstanceRequest) ([]*InstanceResult, error) {
+	if len(reqs) == 0 {
+		return nil, errNoInstanceRequests
+	}
+
+	results := make([]*InstanceResult, len(reqs))
+	instances := make([]*compute.Instance, len(reqs))
+	for i, ireq := range reqs {
+		ireq = inProject(ireq, c.DefaultProject)
+		results[i] = &InstanceResult{Request: ireq}
+		instances[i], results[i].Err = ireq.buildInstance()
+	}
+
+	var wg sync.WaitGroup
+	sema := make(chan bool, defaultCreateInstancesConcurrency)
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+
+		sema <- true
+		wg.Add(1)
+		go func(res *InstanceResult, instance *compute.Instance) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			res.Operation, res.Err = c.insertInstance(ctx, res.Request, instance)
+		}(res, instances[i])
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+func (c *Client) insertInstance(ctx context.Context, ireq *InstanceRequest, instance *compute.Instance) (*compute.Operation, error) {
+	if err := c.prepareInstance(ctx, ireq, instance); err != nil {
+		return nil, err
+	}
+	return c.insertPreparedInstance(ctx, ireq, instance)
+}
+
+// prepareInstance checks that the instance built from ireq can be
+// created in its zone, then applies the project's default labels and
+// resolves its boot image family, if ireq asks for those.
+func (c *Client) prepareInstance(ctx context.Context, ireq *InstanceRequest, instance *compute.Instance) error {
+	if err := c.validateZoneForCreate(ctx, ireq); err != nil {
+		return err
+	}
+	if err := c.applyProjectDefaultLabels(ctx, ireq, instance); err != nil {
+		return err
+	}
+	return c.resolveBootImage(ctx, ireq, instance)
+}
+
+// insertPreparedInstance inserts the instance once prepared
+// with prepareInstance, waiting for the insertion to complete
+// if ireq.BlockUntilCompletion is set.
+func (c *Client) insertPreparedInstance(ctx context.Context, ireq *InstanceRequest, instance *compute.Instance) (*compute.Operation, error) {
+	req := c.instancesService().Insert(ireq.Project, ireq.Zone, instance)
+	op, err := req.Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if !ireq.BlockUntilCompletion {
+		return op, operationError(op)
+	}
+	return c.waitForZoneOperation(ctx, ireq.Project, ireq.Zone, op)
+}
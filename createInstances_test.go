@@ -0,0 +1,110 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestCreateInstances(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	var (
+		mu       sync.Mutex
+		inserted []string
+		polls    int
+	)
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if isWaitRoute(req) {
+			writeWaitUnsupported(w)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch route := req.Method + " " + req.URL.Path; {
+		case route == "GET "+zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+
+		case route == "POST "+zonePath+"/instances":
+			instance := new(compute.Instance)
+			readJSON(t, req, instance)
+			inserted = append(inserted, instance.Name)
+			if instance.Name == "quota-exceeded" {
+				writeJSON(w, &compute.Operation{
+					Name:   "op-" + instance.Name,
+					Status: "DONE",
+					Error: &compute.OperationError{
+						Errors: []*compute.OperationErrorErrors{{Code: "QUOTA_EXCEEDED", Message: "CPUS quota exceeded"}},
+					},
+				})
+				return
+			}
+			writeJSON(w, &compute.Operation{Name: "op-" + instance.Name, Status: "RUNNING"})
+
+		case strings.HasPrefix(route, "GET "+zonePath+"/operations/"):
+			polls++
+			writeJSON(w, &compute.Operation{Name: strings.TrimPrefix(route, "GET "+zonePath+"/operations/"), Status: "DONE"})
+
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	newRequest := func(name string) *InstanceRequest {
+		return &InstanceRequest{
+			Project:          "sample",
+			Zone:             "us-central1-c",
+			Name:             name,
+			NetworkInterface: BasicExternalNATNetworkInterface,
+		}
+	}
+	waited := newRequest("web-1")
+	waited.BlockUntilCompletion = true
+	invalid := newRequest("no-nic")
+	invalid.NetworkInterface = nil
+
+	reqs := []*InstanceRequest{waited, newRequest("web-2"), invalid, newRequest("quota-exceeded")}
+	results, err := client.CreateInstances(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("CreateInstances: %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("got %d results want %d", len(results), len(reqs))
+	}
+	for i, res := range results {
+		if res.Request != reqs[i] {
+			t.Errorf("#%d: result is for request %q", i, res.Request.Name)
+		}
+	}
+
+	if res := results[0]; res.Err != nil || res.Operation.Status != "DONE" {
+		t.Errorf("waited request: got err %v and operation %+v, want a done operation", res.Err, res.Operation)
+	}
+	if res := results[1]; res.Err != nil || res.Operation.Status != "RUNNING" {
+		t.Errorf("unwaited request: got err %v and operation %+v, want a running operation", res.Err, res.Operation)
+	}
+	if res := results[2]; res.Err != errEmptyNetworkInterface || res.Operation != nil {
+		t.Errorf("invalid request: got err %v want %v", res.Err, errEmptyNetworkInterface)
+	}
+	if res := results[3]; res.Err == nil || !strings.Contains(res.Err.Error(), "QUOTA_EXCEEDED") {
+		t.Errorf("failed request: got err %v, want the operation's error", res.Err)
+	}
+
+	if len(inserted) != 3 {
+		t.Errorf("inserts: got %v, want the 3 valid requests inserted", inserted)
+	}
+	for _, name := range inserted {
+		if name == "no-nic" {
+			t.Error("the invalid request was inserted")
+		}
+	}
+	if polls != 1 {
+		t.Errorf("operation polls: got %d want %d", polls, 1)
+	}
+}
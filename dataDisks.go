@@ -0,0 +1,84 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// DataDiskSpec describes a persistent disk, other than the boot disk,
+// to create along with an instance and attach to it.
+type DataDiskSpec struct {
+	// Name names both the disk and the device that it is attached as,
+	// so that it shows up as /dev/disk/by-id/google-<Name>.
+	Name   string `json:"name"`
+	SizeGB int64  `json:"size_gb"`
+
+	// Type is the disk type e.g "pd-ssd". If blank,
+	// the zone's default of "pd-standard" is used.
+	Type string `json:"type,omitempty"`
+
+	// AutoDelete when set deletes the disk along with the instance.
+	AutoDelete bool `json:"auto_delete,omitempty"`
+}
+
+var (
+	errBlankDataDiskName       = errors.New("expecting a non-blank data disk name")
+	errNonPositiveDataDiskSize = errors.New("expecting a positive data disk size")
+	errDataDiskNamedAsBootDisk = errors.New("data disk can't be named after the instance, whose boot disk has that name")
+	errDuplicateDataDiskName   = errors.New("data disk names must be unique")
+)
+
+func (spec *DataDiskSpec) Validate() error {
+	if spec == nil || spec.Name == "" {
+		return errBlankDataDiskName
+	}
+	if spec.SizeGB <= 0 {
+		return errNonPositiveDataDiskSize
+	}
+	return nil
+}
+
+func (ireq *InstanceRequest) validateDataDisks() error {
+	seen := make(map[string]bool)
+	for _, spec := range ireq.DataDisks {
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+		if spec.Name == ireq.Name {
+			return fmt.Errorf("%w: %q", errDataDiskNamedAsBootDisk, spec.Name)
+		}
+		if seen[spec.Name] {
+			return fmt.Errorf("%w: %q", errDuplicateDataDiskName, spec.Name)
+		}
+		seen[spec.Name] = true
+	}
+	return nil
+}
+
+// dataDisks returns the non-boot disks to create and attach, their types
+// as the zonal URLs that instances expect or else as the bare type names
+// that instance properties expect.
+func (ireq *InstanceRequest) dataDisks(selfLinks bool) []*compute.AttachedDisk {
+	var disks []*compute.AttachedDisk
+	for _, spec := range ireq.DataDisks {
+		diskType := spec.Type
+		if selfLinks && diskType != "" {
+			diskType = SelfLink(ireq.Project, "zones", ireq.Zone, "diskTypes", spec.Type)
+		}
+		disks = append(disks, &compute.AttachedDisk{
+			AutoDelete: spec.AutoDelete,
+			DeviceName: spec.Name,
+			Type:       "PERSISTENT",
+			Mode:       "READ_WRITE",
+
+			InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskName:   spec.Name,
+				DiskSizeGb: spec.SizeGB,
+				DiskType:   diskType,
+			},
+		})
+	}
+	return disks
+}
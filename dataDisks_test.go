@@ -0,0 +1,75 @@
+package infra
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDataDisksAttachedAfterBootDisk(t *testing.T) {
+	ireq := &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "db",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		DataDisks: []*DataDiskSpec{
+			{Name: "db-data", SizeGB: 500, Type: "pd-ssd", AutoDelete: true},
+		},
+	}
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("validateForCreate: %v", err)
+	}
+
+	disks := ireq.toInstance().Disks
+	if len(disks) != 2 {
+		t.Fatalf("got %d disks want the boot disk and the data disk", len(disks))
+	}
+	if !disks[0].Boot {
+		t.Errorf("expected the boot disk first, got %+v", disks[0])
+	}
+	want := &compute.AttachedDisk{
+		AutoDelete: true,
+		DeviceName: "db-data",
+		Type:       "PERSISTENT",
+		Mode:       "READ_WRITE",
+		InitializeParams: &compute.AttachedDiskInitializeParams{
+			DiskName:   "db-data",
+			DiskSizeGb: 500,
+			DiskType:   "https://www.googleapis.com/compute/v1/projects/sample/zones/us-central1-c/diskTypes/pd-ssd",
+		},
+	}
+	if !reflect.DeepEqual(disks[1], want) {
+		t.Errorf("data disk: got %+v want %+v", disks[1], want)
+	}
+
+	// Instance properties take the bare disk type.
+	props := ireq.toInstanceProperties(nil)
+	if got := props.Disks[1].InitializeParams.DiskType; got != "pd-ssd" {
+		t.Errorf("instance properties disk type: got %q want %q", got, "pd-ssd")
+	}
+}
+
+func TestValidateDataDisks(t *testing.T) {
+	tests := []struct {
+		disks   []*DataDiskSpec
+		wantErr error
+	}{
+		{disks: []*DataDiskSpec{{SizeGB: 10}}, wantErr: errBlankDataDiskName},
+		{disks: []*DataDiskSpec{{Name: "data"}}, wantErr: errNonPositiveDataDiskSize},
+		{disks: []*DataDiskSpec{{Name: "db", SizeGB: 10}}, wantErr: errDataDiskNamedAsBootDisk},
+		{
+			disks:   []*DataDiskSpec{{Name: "data", SizeGB: 10}, {Name: "data", SizeGB: 20}},
+			wantErr: errDuplicateDataDiskName,
+		},
+		{disks: []*DataDiskSpec{{Name: "data", SizeGB: 10}, {Name: "logs", SizeGB: 20}}},
+	}
+
+	for i, tt := range tests {
+		ireq := &InstanceRequest{Name: "db", DataDisks: tt.disks}
+		if err := ireq.validateDataDisks(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.wantErr)
+		}
+	}
+}
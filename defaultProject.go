@@ -0,0 +1,41 @@
+package infra
+
+// projectOrDefault returns project, or the
+// client's DefaultProject if project is empty.
+func (c *Client) projectOrDefault(project string) string {
+	if project == "" {
+		return c.DefaultProject
+	}
+	return project
+}
+
+// projectScoped is a pointer to a request that names its project.
+type projectScoped[T any] interface {
+	*T
+	projectField() *string
+}
+
+// inProject returns req as is if it sets a project, otherwise a copy
+// of it in project, so that the caller's request is never modified.
+func inProject[T any, P projectScoped[T]](req P, project string) P {
+	if req == nil || *req.projectField() != "" || project == "" {
+		return req
+	}
+	copied := *req
+	*P(&copied).projectField() = project
+	return &copied
+}
+
+func (ireq *InstanceRequest) projectField() *string         { return &ireq.Project }
+func (ireq *InstancesRequest) projectField() *string        { return &ireq.Project }
+func (zreq *ZoneRequest) projectField() *string             { return &zreq.Project }
+func (rreq *RecordSetRequest) projectField() *string        { return &rreq.Project }
+func (ureq *UpdateRequest) projectField() *string           { return &ureq.Project }
+func (bc *BucketCheck) projectField() *string               { return &bc.Project }
+func (params *UploadParams) projectField() *string          { return &params.Project }
+func (params *UploadDirParams) projectField() *string       { return &params.Project }
+func (req *Setup) projectField() *string                    { return &req.Project }
+func (rreq *ReservationRequest) projectField() *string      { return &rreq.Project }
+func (breq *BulkInstanceRequest) projectField() *string     { return &breq.Project }
+func (treq *InstanceTemplateRequest) projectField() *string { return &treq.Project }
+func (preq *PrivateZoneRequest) projectField() *string      { return &preq.Project }
@@ -1,6 +1,8 @@
 package infra
 
 import (
+	"context"
+
 	"google.golang.org/api/compute/v1"
 )
 
@@ -18,3 +20,37 @@ var (
 		},
 	}
 )
+
+// InstanceDisks returns the disks attached to the instance, with details such
+// as their sizes and types. Attached disks without a source, like local SSDs,
+// aren't separate resources and so are skipped.
+func (c *Client) InstanceDisks(ctx context.Context, ireq *InstanceRequest) ([]*compute.Disk, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	instance, err := c.FindInstance(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []*compute.Disk
+	for _, attached := range instance.Disks {
+		if attached.Source == "" {
+			continue
+		}
+		disk, err := c.diskFromURL(ctx, attached.Source)
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
+// diskFromURL fetches the disk that the URL refers to, which
+// for regional persistent disks is under a region not a zone.
+func (c *Client) diskFromURL(ctx context.Context, diskURL string) (*compute.Disk, error) {
+	project, name := projectFromURL(diskURL), lastURLSegment(diskURL)
+	if region := segmentAfter(diskURL, "regions"); region != "" {
+		return compute.NewRegionDisksService(c.computeSrvc).Get(project, region, name).Context(ctx).Do()
+	}
+	return c.disksService().Get(project, zoneFromURL(diskURL), name).Context(ctx).Do()
+}
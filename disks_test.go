@@ -0,0 +1,52 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestInstanceDisks(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + zonePath + "/instances/web":
+			writeJSON(w, &compute.Instance{
+				Name: "web",
+				Disks: []*compute.AttachedDisk{
+					{Boot: true, Source: SelfLink("sample", "zones", "us-central1-c", "disks", "web")},
+					{Source: SelfLink("sample", "regions", "us-central1", "disks", "data")},
+					{Type: "SCRATCH"},
+				},
+			})
+		case "GET " + zonePath + "/disks/web":
+			writeJSON(w, &compute.Disk{Name: "web", SizeGb: 10, Type: "pd-balanced"})
+		case "GET /compute/v1/projects/sample/regions/us-central1/disks/data":
+			writeJSON(w, &compute.Disk{Name: "data", SizeGb: 500, Type: "pd-ssd"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	disks, err := client.InstanceDisks(context.Background(), &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "web"})
+	if err != nil {
+		t.Fatalf("InstanceDisks: %v", err)
+	}
+	var got []string
+	for _, disk := range disks {
+		got = append(got, disk.Name)
+	}
+	if want := []string{"web", "data"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("disks: got %q want %q", got, want)
+	}
+	if disks[1].SizeGb != 500 {
+		t.Errorf("data disk size: got %d want %d", disks[1].SizeGb, 500)
+	}
+
+	if _, err := client.InstanceDisks(context.Background(), &InstanceRequest{Project: "sample", Zone: "us-central1-c"}); err != errBlankName {
+		t.Errorf("got err %v want %v", err, errBlankName)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -25,8 +26,20 @@ type RecordSetRequest struct {
 	// return records with this fully qualified domain name.
 	DomainName string `json:"domain_name"`
 
+	// Type if set restricts listing to only return records of this
+	// type. Cloud DNS only filters by type along with DomainName,
+	// so without DomainName the records are filtered as listed.
+	Type RecordType `json:"type,omitempty"`
+
 	MaxPages       int64 `json:"max_pages"`
 	ResultsPerPage int64 `json:"results_per_page"`
+
+	// MaxBufferedPages is the most pages fetched ahead of the consumer,
+	// if unset defaultMaxBufferedPages is used.
+	MaxBufferedPages int `json:"max_buffered_pages,omitempty"`
+
+	// ThrottleMillis is the wait between pages, 350ms if unset, none if negative.
+	ThrottleMillis int64 `json:"throttle_millis,omitempty"`
 }
 
 type RecordSetPagesResponse struct {
@@ -41,6 +54,11 @@ func (rreq *RecordSetRequest) Validate() error {
 	if rreq.Zone == "" {
 		return errEmptyZone
 	}
+	if rreq.Type != "" {
+		if err := rreq.Type.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -49,72 +67,54 @@ func (c *Client) recordSetsService() *dns.ResourceRecordSetsService {
 }
 
 func (c *Client) ListDNSRecordSets(ctx context.Context, rreq *RecordSetRequest) (*RecordSetPagesResponse, error) {
+	rreq = inProject(rreq, c.DefaultProject)
 	if err := rreq.Validate(); err != nil {
 		return nil, err
 	}
 
-	maxPageNumber := rreq.MaxPages
-	pageExceedsMax := func(pageNumber int64) bool {
-		if maxPageNumber <= 0 {
-			return false
-		}
-		return pageNumber > maxPageNumber
-	}
-
-	maxResultsPerPage := int64(40)
-	if rreq.ResultsPerPage > 0 {
-		maxResultsPerPage = rreq.ResultsPerPage
+	maxResultsPerPage, err := resultsPerPageOrDefault(rreq.ResultsPerPage)
+	if err != nil {
+		return nil, err
 	}
 
-	cancelChan, cancelFn := makeCanceler()
-	pagesChan := make(chan *RecordSetPage)
+	ctx, cancelChan, cancelFn := makeCanceler(ctx)
+	pagesChan := make(chan *RecordSetPage, maxBufferedPagesOrDefault(rreq.MaxBufferedPages))
 	go func() {
 		defer close(pagesChan)
+		defer cancelFn()
 
 		dnsLc := c.recordSetsService().List(rreq.Project, rreq.Zone).Context(ctx)
 		dnsLc.MaxResults(maxResultsPerPage)
 
 		if rreq.DomainName != "" {
 			dnsLc.Name(ensureHasTrailingDot(rreq.DomainName))
+			if rreq.Type != "" {
+				dnsLc.Type(string(rreq.Type))
+			}
 		}
 
-		pageToken := ""
-		pageNumber := int64(0)
-		throttleDuration := time.Duration(350 * time.Millisecond)
-
-		for {
-			dnsLc.PageToken(pageToken)
-			dPage := new(RecordSetPage)
-			dPage.PageNumber = pageNumber
-
-			dRes, err := dnsLc.Do()
+		var dRes *dns.ResourceRecordSetsListResponse
+		fetch := func(pageToken string) (_ string, err error) {
+			dRes, err = dnsLc.PageToken(pageToken).Do()
 			if err != nil {
-				dPage.Err = err
-				pagesChan <- dPage
-				return
+				return "", err
 			}
-
-			dPage.RecordSets = dRes.Rrsets
-			pagesChan <- dPage
-
-			pageNumber += 1
-			if pageExceedsMax(pageNumber) {
-				return
+			return dRes.NextPageToken, nil
+		}
+		send := func(pageNumber int64, err error) bool {
+			dPage := &RecordSetPage{PageNumber: pageNumber, Err: err}
+			if err == nil {
+				dPage.RecordSets = rreq.filterByType(dRes.Rrsets)
 			}
-
-			pageToken := dRes.NextPageToken
-
 			select {
+			case pagesChan <- dPage:
+				return true
 			case <-cancelChan:
-				return
-			case <-time.After(throttleDuration):
-			}
-
-			if pageToken == "" {
-				// No more results left
-				break
+			case <-ctx.Done():
 			}
+			return false
 		}
+		c.fetchPages(ctx, cancelChan, rreq.MaxPages, pageThrottleOrDefault(rreq.ThrottleMillis), fetch, send)
 	}()
 
 	rres := &RecordSetPagesResponse{
@@ -129,7 +129,7 @@ func (c *Client) ListDNSRecordSets(ctx context.Context, rreq *RecordSetRequest)
 type RecordType string
 
 const (
-	AAAName RecordType = "AAA"
+	AAAName RecordType = "AAAA"
 	AName   RecordType = "A"
 	CName   RecordType = "CNAME"
 	CAA     RecordType = "CAA"
@@ -140,6 +140,31 @@ const (
 	TXT     RecordType = "TXT"
 )
 
+// Validate reports whether the record type is one of the known types.
+func (rt RecordType) Validate() error {
+	switch rt {
+	case AAAName, AName, CName, CAA, MX, NS, SPF, SRV, TXT:
+		return nil
+	default:
+		return fmt.Errorf("unknown recordType: %q", rt)
+	}
+}
+
+// filterByType returns the record sets of the requested
+// type, or all of them if no type was requested.
+func (rreq *RecordSetRequest) filterByType(rrsets []*dns.ResourceRecordSet) []*dns.ResourceRecordSet {
+	if rreq.Type == "" {
+		return rrsets
+	}
+	var filtered []*dns.ResourceRecordSet
+	for _, rrset := range rrsets {
+		if rrset.Type == string(rreq.Type) {
+			filtered = append(filtered, rrset)
+		}
+	}
+	return filtered
+}
+
 type Record struct {
 	DNSName string     `json:"dns_name"`
 	TTL     int64      `json:"ttl"`
@@ -159,6 +184,13 @@ type Record struct {
 	SRVData []string `json:"srv_data"`
 
 	TXTRecords []string `json:"txt_records"`
+
+	// RawType and RawRrdatas are an escape hatch for the record types
+	// that the typed fields don't cover, such as "HTTPS", "SVCB" or
+	// "NAPTR": the record set is created with RawType and RawRrdatas
+	// as they are. They can't be combined with Type or the typed fields.
+	RawType    string   `json:"raw_type,omitempty"`
+	RawRrdatas []string `json:"raw_rrdatas,omitempty"`
 }
 
 func ensureHasTrailingDot(s string) string {
@@ -168,6 +200,25 @@ func ensureHasTrailingDot(s string) string {
 	return s
 }
 
+// isDualStack reports whether the record is an A or AAAA record with both
+// IPv4 and IPv6 addresses, which expands into an A and an AAAA record set.
+func (r *Record) isDualStack() bool {
+	return (r.Type == AName || r.Type == AAAName) && len(r.IPV4Addresses) > 0 && len(r.IPV6Addresses) > 0
+}
+
+// recordSets returns the record sets that the record expands into: an A
+// and an AAAA record set for a dual-stack record, otherwise its only
+// record set. Changes are built from recordSets rather than toRecordSet.
+func (r *Record) recordSets() []*dns.ResourceRecordSet {
+	if !r.isDualStack() {
+		return []*dns.ResourceRecordSet{r.toRecordSet()}
+	}
+	ipv4 := &Record{DNSName: r.DNSName, TTL: r.TTL, Type: AName, IPV4Addresses: r.IPV4Addresses}
+	ipv6 := &Record{DNSName: r.DNSName, TTL: r.TTL, Type: AAAName, IPV6Addresses: r.IPV6Addresses}
+	return []*dns.ResourceRecordSet{ipv4.toRecordSet(), ipv6.toRecordSet()}
+}
+
+// toRecordSet returns the record set of a record of a single type.
 func (r *Record) toRecordSet() *dns.ResourceRecordSet {
 	rrset := &dns.ResourceRecordSet{
 		// DNSNames without trailing dots are rejected as
@@ -176,6 +227,9 @@ func (r *Record) toRecordSet() *dns.ResourceRecordSet {
 		Type: string(r.Type),
 		Ttl:  r.TTL,
 	}
+	if r.RawType != "" {
+		rrset.Type = r.RawType
+	}
 
 	if r.CanonicalName != "" {
 		rrset.Rrdatas = append(rrset.Rrdatas, ensureHasTrailingDot(r.CanonicalName))
@@ -189,6 +243,7 @@ func (r *Record) toRecordSet() *dns.ResourceRecordSet {
 	rrset.Rrdatas = append(rrset.Rrdatas, r.SPFData...)
 	rrset.Rrdatas = append(rrset.Rrdatas, r.SRVData...)
 	rrset.Rrdatas = append(rrset.Rrdatas, r.TXTRecords...)
+	rrset.Rrdatas = append(rrset.Rrdatas, r.RawRrdatas...)
 	return rrset
 }
 
@@ -200,6 +255,22 @@ type UpdateRequest struct {
 
 	Additions []*Record `json:"additions"`
 	Deletions []*Record `json:"deletions"`
+
+	// IgnoreMissingDeletions when set skips the deletions of record
+	// sets that don't exist instead of failing the whole change, so
+	// that deleting the same records more than once succeeds.
+	IgnoreMissingDeletions bool `json:"ignore_missing_deletions,omitempty"`
+
+	// Preconditions if set are the records that the zone is expected
+	// to currently have, with the same TTLs and data. They are checked
+	// before submitting the change, which isn't submitted and fails with
+	// ErrPreconditionFailed if any of them was changed out of band.
+	Preconditions []*Record `json:"preconditions,omitempty"`
+
+	// SkipBatchRollback when set keeps the batches that were applied
+	// when a later batch of AddRecordSetsInBatches fails, rather than
+	// reverting them to leave the zone as it was.
+	SkipBatchRollback bool `json:"skip_batch_rollback,omitempty"`
 }
 
 var (
@@ -217,7 +288,12 @@ var (
 
 	errEmptyPreferenceAndMailServers = errors.New("expecting at least one preferenceAndMailServer")
 
+	errBlankRawType        = errors.New("expecting a non-blank raw type with raw rrdatas")
+	errEmptyRawRrdatas     = errors.New("expecting at least one raw rrdata")
+	errRawAndTypedMixedUse = errors.New("expecting either the raw or the typed fields of a record, not both")
+
 	errBlankUpdateRequest = errors.New("expecting a non-blank updateRequest")
+	errNilChange          = errors.New("expecting a non-nil change")
 )
 
 func (r *Record) validateForAAAName() error {
@@ -230,6 +306,28 @@ func (r *Record) validateForAAAName() error {
 	return nil
 }
 
+// validateForDualStack checks that the record's
+// addresses are of their respective families.
+func (r *Record) validateForDualStack() error {
+	if err := r.validateForAName(); err != nil {
+		return err
+	}
+	if err := r.validateForAAAName(); err != nil {
+		return err
+	}
+	for _, addr := range r.IPV4Addresses {
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("%q is not an IPv4 address", addr)
+		}
+	}
+	for _, addr := range r.IPV6Addresses {
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("%q is not an IPv6 address", addr)
+		}
+	}
+	return nil
+}
+
 func (r *Record) validateForSPF() error {
 	uniqs := dedup(r.SPFData...)
 	if len(uniqs) == 0 {
@@ -320,7 +418,40 @@ func dedup(items ...string) []string {
 	return uniqRecords
 }
 
+// hasTypedFields reports whether any of the typed fields of the record is set.
+func (r *Record) hasTypedFields() bool {
+	return r.Type != "" || r.CanonicalName != "" ||
+		len(r.IPV4Addresses) > 0 || len(r.IPV6Addresses) > 0 ||
+		len(r.NameServers) > 0 || len(r.CertificateAuthorityAuthorizations) > 0 ||
+		len(r.PreferenceAndMailServers) > 0 || len(r.SPFData) > 0 ||
+		len(r.SRVData) > 0 || len(r.TXTRecords) > 0
+}
+
+// validateForRaw only checks that the raw fields are set,
+// the record's data is left for Cloud DNS to validate.
+func (r *Record) validateForRaw() error {
+	if r.hasTypedFields() {
+		return errRawAndTypedMixedUse
+	}
+	if strings.TrimSpace(r.RawType) == "" {
+		return errBlankRawType
+	}
+	uniqs := dedup(r.RawRrdatas...)
+	if len(uniqs) == 0 {
+		return errEmptyRawRrdatas
+	}
+	r.RawType = strings.TrimSpace(r.RawType)
+	r.RawRrdatas = uniqs
+	return nil
+}
+
 func (r *Record) Validate() error {
+	if r.RawType != "" || len(r.RawRrdatas) > 0 {
+		return r.validateForRaw()
+	}
+	if r.isDualStack() {
+		return r.validateForDualStack()
+	}
 	switch r.Type {
 	default:
 		return fmt.Errorf("unknown recordType: %q", r.Type)
@@ -356,6 +487,7 @@ func (ureq *UpdateRequest) validate() error {
 }
 
 func (c *Client) UpdateRecordSets(ctx context.Context, ureq *UpdateRequest) (*dns.Change, error) {
+	ureq = inProject(ureq, c.DefaultProject)
 	if err := ureq.validate(); err != nil {
 		return nil, err
 	}
@@ -367,29 +499,174 @@ func (c *Client) UpdateRecordSets(ctx context.Context, ureq *UpdateRequest) (*dn
 	if err != nil {
 		return nil, err
 	}
+	return c.updateRecordSets(ctx, ureq, additions, deletions)
+}
+
+// updateRecordSets submits a change of the additions and deletions,
+// already expanded from the records of ureq, once ureq's preconditions
+// hold and the change wouldn't make any CNAME conflict.
+func (c *Client) updateRecordSets(ctx context.Context, ureq *UpdateRequest, additions, deletions []*dns.ResourceRecordSet) (*dns.Change, error) {
+	if err := c.checkPreconditions(ctx, ureq); err != nil {
+		return nil, err
+	}
+	if ureq.IgnoreMissingDeletions {
+		var err error
+		deletions, err = c.presentRecordSets(ctx, ureq.Project, ureq.Zone, deletions)
+		if err != nil {
+			return nil, err
+		}
+		if len(deletions) == 0 && len(additions) == 0 {
+			// Nothing left to change, and Cloud DNS rejects empty changes.
+			return new(dns.Change), nil
+		}
+	}
+
+	if err := c.checkCNAMEConflicts(ctx, ureq.Project, ureq.Zone, additions, deletions); err != nil {
+		return nil, err
+	}
 
 	change := &dns.Change{
 		Additions: additions,
 		Deletions: deletions,
 	}
 
-	cl := c.changesService().Create(ureq.Project, ureq.Zone, change).Context(ctx)
-	return cl.Do()
+	var created *dns.Change
+	err := c.retryMutation(ctx, func() (err error) {
+		created, err = c.changesService().Create(ureq.Project, ureq.Zone, change).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
+// maxChangeRecordSets is the most record sets that
+// Cloud DNS accepts to add in a single change.
+const maxChangeRecordSets = 1000
+
+var errTooManyRecordSets = fmt.Errorf("expecting at most %d record sets in a single change, AddRecordSetsInBatches adds more", maxChangeRecordSets)
+
+// AddRecordSets adds the records of areq in a single change. Records that
+// expand into more record sets than Cloud DNS accepts in one change are
+// refused, see AddRecordSetsInBatches to add those.
 func (c *Client) AddRecordSets(ctx context.Context, areq *UpdateRequest) (*dns.Change, error) {
+	areq = inProject(areq, c.DefaultProject)
 	if areq == nil {
 		return nil, errBlankUpdateRequest
 	}
+	if err := areq.validate(); err != nil {
+		return nil, err
+	}
+	// Dual-stack records expand into two record sets
+	// so it is the record sets that are counted.
+	rrsets, err := toRecordSets(areq.Records...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rrsets) > maxChangeRecordSets {
+		return nil, errTooManyRecordSets
+	}
 
-	return c.UpdateRecordSets(ctx, &UpdateRequest{
-		Zone:      areq.Zone,
-		Project:   areq.Project,
-		Additions: areq.Records[:],
-	})
+	return c.updateRecordSets(ctx, &UpdateRequest{
+		Zone:    areq.Zone,
+		Project: areq.Project,
+
+		Preconditions: areq.Preconditions,
+	}, rrsets, nil)
+}
+
+// AddRecordSetsInBatches adds the records of areq in changes of at most
+// 1000 record sets, the limit of Cloud DNS, counting both the A and AAAA
+// record sets of dual-stack records, submitting each change once
+// the previous one is done. If a batch fails, the batches that were
+// applied are reverted, latest first, unless areq sets SkipBatchRollback.
+// It returns the changes that were applied and not reverted, along with
+// the error that stopped the batches if any.
+func (c *Client) AddRecordSetsInBatches(ctx context.Context, areq *UpdateRequest) ([]*dns.Change, error) {
+	areq = inProject(areq, c.DefaultProject)
+	if areq == nil {
+		return nil, errBlankUpdateRequest
+	}
+
+	if err := areq.validate(); err != nil {
+		return nil, err
+	}
+	rrsets, err := toRecordSets(areq.Records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*dns.Change
+	for start := 0; start < len(rrsets); start += maxChangeRecordSets {
+		end := start + maxChangeRecordSets
+		if end > len(rrsets) {
+			end = len(rrsets)
+		}
+		ureq := &UpdateRequest{Zone: areq.Zone, Project: areq.Project}
+		if start == 0 {
+			// The preconditions are about the zone before any batch.
+			ureq.Preconditions = areq.Preconditions
+		}
+		change, err := c.updateRecordSets(ctx, ureq, rrsets[start:end], nil)
+		if err == nil {
+			change, err = c.waitForChange(ctx, areq.Project, areq.Zone, change)
+		}
+		if err != nil {
+			if areq.SkipBatchRollback || len(changes) == 0 {
+				return changes, err
+			}
+			return c.revertBatches(ctx, areq.Project, areq.Zone, changes, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// revertBatches reverts the applied changes, latest first, after a batch
+// failed with err. It returns the changes that couldn't be reverted along
+// with the error to return.
+func (c *Client) revertBatches(ctx context.Context, project, zone string, applied []*dns.Change, err error) ([]*dns.Change, error) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		inverse, rerr := c.RevertChange(ctx, project, zone, applied[i])
+		if rerr == nil {
+			_, rerr = c.waitForChange(ctx, project, zone, inverse)
+		}
+		if rerr != nil {
+			return applied[:i+1], fmt.Errorf("%w; reverting the applied batches failed, %d remain applied: %v", err, i+1, rerr)
+		}
+	}
+	return nil, fmt.Errorf("%w; reverted the %d batches that were applied", err, len(applied))
+}
+
+// waitForChange polls the change until it is done,
+// giving up after Client.OperationPollTimeout.
+func (c *Client) waitForChange(ctx context.Context, project, zone string, change *dns.Change) (*dns.Change, error) {
+	timeout := c.operationPollTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	backoff := c.pollBackoff()
+
+	for change.Status != "done" {
+		select {
+		case <-ctx.Done():
+			return change, ctx.Err()
+		case <-timer.C:
+			return change, fmt.Errorf("DNS change %q was not done within %s", change.Id, timeout)
+		case <-time.After(backoff.Next()):
+		}
+
+		var err error
+		change, err = c.changesService().Get(project, zone, change.Id).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return change, nil
 }
 
 func (c *Client) DeleteRecordSets(ctx context.Context, dreq *UpdateRequest) (*dns.Change, error) {
+	dreq = inProject(dreq, c.DefaultProject)
 	if dreq == nil {
 		return nil, errBlankUpdateRequest
 	}
@@ -398,20 +675,233 @@ func (c *Client) DeleteRecordSets(ctx context.Context, dreq *UpdateRequest) (*dn
 		Zone:      dreq.Zone,
 		Project:   dreq.Project,
 		Deletions: dreq.Records[:],
+
+		IgnoreMissingDeletions: dreq.IgnoreMissingDeletions,
+		Preconditions:          dreq.Preconditions,
 	})
 }
 
+// ErrPreconditionFailed is returned when the zone no longer
+// has the records that an update was conditioned on.
+var ErrPreconditionFailed = errors.New("DNS precondition failed")
+
+// checkPreconditions checks that the zone currently has
+// each of the records that ureq is conditioned on.
+func (c *Client) checkPreconditions(ctx context.Context, ureq *UpdateRequest) error {
+	expected, err := toRecordSets(ureq.Preconditions...)
+	if err != nil {
+		return err
+	}
+	for _, want := range expected {
+		current, err := c.findRecordSet(ctx, ureq.Project, ureq.Zone, want.Name, want.Type)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return fmt.Errorf("%w: record set %s %q doesn't exist", ErrPreconditionFailed, want.Type, want.Name)
+		}
+		if !sameRecordSet(current, want) {
+			return fmt.Errorf("%w: record set %s %q was changed", ErrPreconditionFailed, want.Type, want.Name)
+		}
+	}
+	return nil
+}
+
+// presentRecordSets returns the record sets of rrsets
+// whose name and type exist in the zone.
+func (c *Client) presentRecordSets(ctx context.Context, project, zone string, rrsets []*dns.ResourceRecordSet) ([]*dns.ResourceRecordSet, error) {
+	var present []*dns.ResourceRecordSet
+	for _, rrset := range rrsets {
+		current, err := c.findRecordSet(ctx, project, zone, rrset.Name, rrset.Type)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			present = append(present, rrset)
+		}
+	}
+	return present, nil
+}
+
 func toRecordSets(records ...*Record) ([]*dns.ResourceRecordSet, error) {
 	var rrsets []*dns.ResourceRecordSet
 	for _, rec := range records {
 		if err := rec.Validate(); err != nil {
 			return nil, err
 		}
-		rrsets = append(rrsets, rec.toRecordSet())
+		rrsets = append(rrsets, rec.recordSets()...)
 	}
 	return rrsets, nil
 }
 
+// ErrCNAMEConflict is returned when a change would make a CNAME
+// record coexist with other records of the same name, which DNS forbids.
+var ErrCNAMEConflict = errors.New("a CNAME record can't coexist with other records of the same name")
+
+// checkCNAMEConflicts checks that, once the additions and deletions are
+// applied to the zone, none of the added names has both a CNAME record
+// and records of other types.
+func (c *Client) checkCNAMEConflicts(ctx context.Context, project, zone string, additions, deletions []*dns.ResourceRecordSet) error {
+	if len(additions) == 0 {
+		return nil
+	}
+
+	deleted := make(map[string]bool)
+	for _, rrset := range deletions {
+		deleted[rrset.Name+" "+rrset.Type] = true
+	}
+
+	var names []string
+	typesByName := make(map[string][]string)
+	for _, rrset := range additions {
+		if _, seen := typesByName[rrset.Name]; !seen {
+			names = append(names, rrset.Name)
+		}
+		typesByName[rrset.Name] = append(typesByName[rrset.Name], rrset.Type)
+	}
+
+	// Only the added names are looked up, as listing
+	// the whole zone can take hundreds of pages.
+	for _, name := range names {
+		dRes, err := c.recordSetsService().List(project, zone).Name(name).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		for _, rrset := range dRes.Rrsets {
+			if !deleted[rrset.Name+" "+rrset.Type] {
+				typesByName[name] = append(typesByName[name], rrset.Type)
+			}
+		}
+
+		types := dedup(typesByName[name]...)
+		for _, rtype := range types {
+			if rtype == string(CName) && len(types) > 1 {
+				return fmt.Errorf("%w: %q would have records of types %s", ErrCNAMEConflict, name, strings.Join(types, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// RevertChange rolls back a previously applied change by submitting its
+// inverse, that is a change that deletes what it added and adds back what
+// it deleted. Cloud DNS changes can't be undone directly so before
+// reverting, it checks that the zone still reflects the original change,
+// refusing to clobber record sets that were modified since.
+func (c *Client) RevertChange(ctx context.Context, project, zone string, change *dns.Change) (*dns.Change, error) {
+	project = c.projectOrDefault(project)
+	if zone == "" {
+		return nil, errBlankZone
+	}
+	if project == "" {
+		return nil, errBlankProject
+	}
+	if change == nil {
+		return nil, errNilChange
+	}
+
+	for _, added := range change.Additions {
+		current, err := c.findRecordSet(ctx, project, zone, added.Name, added.Type)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || !sameRecordSet(current, added) {
+			return nil, fmt.Errorf("record set %s %q changed since change %q, refusing to revert", added.Type, added.Name, change.Id)
+		}
+	}
+	for _, deleted := range change.Deletions {
+		current, err := c.findRecordSet(ctx, project, zone, deleted.Name, deleted.Type)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			return nil, fmt.Errorf("record set %s %q was recreated since change %q, refusing to revert", deleted.Type, deleted.Name, change.Id)
+		}
+	}
+
+	inverse := &dns.Change{
+		Additions: change.Deletions,
+		Deletions: change.Additions,
+	}
+	return c.changesService().Create(project, zone, inverse).Context(ctx).Do()
+}
+
+// findRecordSet returns the record set with the given name and type
+// or nil if the zone has no such record set.
+func (c *Client) findRecordSet(ctx context.Context, project, zone, name, recordType string) (*dns.ResourceRecordSet, error) {
+	dnsLc := c.recordSetsService().List(project, zone).Context(ctx)
+	dnsLc.Name(ensureHasTrailingDot(name)).Type(recordType)
+	dRes, err := dnsLc.Do()
+	if err != nil {
+		return nil, err
+	}
+	if len(dRes.Rrsets) == 0 {
+		return nil, nil
+	}
+	return dRes.Rrsets[0], nil
+}
+
+// sameRecordSet reports whether a and b have the same name,
+// type, TTL and rrdatas, regardless of the order of rrdatas.
+func sameRecordSet(a, b *dns.ResourceRecordSet) bool {
+	if ensureHasTrailingDot(a.Name) != ensureHasTrailingDot(b.Name) || a.Type != b.Type || a.Ttl != b.Ttl {
+		return false
+	}
+	if len(a.Rrdatas) != len(b.Rrdatas) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, rrdata := range a.Rrdatas {
+		counts[rrdata] += 1
+	}
+	for _, rrdata := range b.Rrdatas {
+		if counts[rrdata] == 0 {
+			return false
+		}
+		counts[rrdata] -= 1
+	}
+	return true
+}
+
 func (c *Client) changesService() *dns.ChangesService {
 	return dns.NewChangesService(c.dnsSrvc)
 }
+
+// ZoneStats summarizes the records of a managed zone, for example to
+// monitor how close the zone is to its per-zone record quotas.
+type ZoneStats struct {
+	// RecordSets is the number of record sets in the zone.
+	RecordSets int64 `json:"record_sets"`
+
+	// Records is the number of records, that is rrdatas,
+	// across all the record sets in the zone.
+	Records int64 `json:"records"`
+}
+
+// ZoneRecordStats counts the record sets and records of the managed
+// zone by listing all its record sets.
+func (c *Client) ZoneRecordStats(ctx context.Context, project, zone string) (*ZoneStats, error) {
+	project = c.projectOrDefault(project)
+	rreq := &RecordSetRequest{Project: project, Zone: zone}
+	if err := rreq.Validate(); err != nil {
+		return nil, err
+	}
+	maxResultsPerPage, err := resultsPerPageOrDefault(rreq.ResultsPerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := new(ZoneStats)
+	req := c.recordSetsService().List(project, zone).MaxResults(maxResultsPerPage)
+	err = req.Pages(ctx, func(res *dns.ResourceRecordSetsListResponse) error {
+		for _, rrset := range res.Rrsets {
+			stats.RecordSets += 1
+			stats.Records += int64(len(rrset.Rrdatas))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,655 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/dns/v1"
+)
+
+// fakeZone is an in-memory Cloud DNS managed zone
+// called "zone" in the project "sample".
+type fakeZone struct {
+	mu      sync.Mutex
+	rrsets  []*dns.ResourceRecordSet
+	changes []*dns.Change
+}
+
+const fakeZonePath = "/dns/v1/projects/sample/managedZones/zone"
+
+func (fz *fakeZone) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	fz.mu.Lock()
+	defer fz.mu.Unlock()
+
+	switch route := req.Method + " " + req.URL.Path; route {
+	case "GET " + fakeZonePath + "/rrsets":
+		query := req.URL.Query()
+		var matches []*dns.ResourceRecordSet
+		for _, rrset := range fz.rrsets {
+			if name := query.Get("name"); name != "" && rrset.Name != name {
+				continue
+			}
+			if rtype := query.Get("type"); rtype != "" && rrset.Type != rtype {
+				continue
+			}
+			matches = append(matches, rrset)
+		}
+		writeJSON(w, &dns.ResourceRecordSetsListResponse{Rrsets: matches})
+
+	case "POST " + fakeZonePath + "/changes":
+		change := new(dns.Change)
+		if err := json.NewDecoder(req.Body).Decode(change); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := fz.apply(change); err != nil {
+			w.WriteHeader(http.StatusConflict)
+			writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": http.StatusConflict, "message": err.Error()}})
+			return
+		}
+		change.Id = fmt.Sprintf("%d", len(fz.changes))
+		change.Status = "done"
+		fz.changes = append(fz.changes, change)
+		writeJSON(w, change)
+
+	default:
+		http.Error(w, "unexpected route "+route, http.StatusNotFound)
+	}
+}
+
+func (fz *fakeZone) apply(change *dns.Change) error {
+	rrsets := fz.rrsets[:len(fz.rrsets):len(fz.rrsets)]
+	for _, deletion := range change.Deletions {
+		i := fz.indexOf(rrsets, deletion.Name, deletion.Type)
+		if i < 0 || !sameRecordSet(rrsets[i], deletion) {
+			return fmt.Errorf("%s %s: not found", deletion.Type, deletion.Name)
+		}
+		rrsets = append(rrsets[:i:i], rrsets[i+1:]...)
+	}
+	for _, addition := range change.Additions {
+		if fz.indexOf(rrsets, addition.Name, addition.Type) >= 0 {
+			return fmt.Errorf("%s %s: already exists", addition.Type, addition.Name)
+		}
+		rrsets = append(rrsets, addition)
+	}
+	fz.rrsets = rrsets
+	return nil
+}
+
+func (fz *fakeZone) indexOf(rrsets []*dns.ResourceRecordSet, name, rtype string) int {
+	for i, rrset := range rrsets {
+		if rrset.Name == name && rrset.Type == rtype {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRevertChange(t *testing.T) {
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			{Name: "old.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.1"}},
+		},
+	}
+	client := newTestClient(t, fz.ServeHTTP)
+	ctx := context.Background()
+
+	applied, err := client.UpdateRecordSets(ctx, &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+
+		Additions: []*Record{{Type: AName, DNSName: "new.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.2"}}},
+		Deletions: []*Record{{Type: AName, DNSName: "old.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.1"}}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateRecordSets: %v", err)
+	}
+
+	reverted, err := client.RevertChange(ctx, "sample", "zone", applied)
+	if err != nil {
+		t.Fatalf("RevertChange: %v", err)
+	}
+	if len(reverted.Additions) != 1 || reverted.Additions[0].Name != "old.orijtech.com." {
+		t.Errorf("inverse change additions: %+v", reverted.Additions)
+	}
+	if len(reverted.Deletions) != 1 || reverted.Deletions[0].Name != "new.orijtech.com." {
+		t.Errorf("inverse change deletions: %+v", reverted.Deletions)
+	}
+	if len(fz.rrsets) != 1 || fz.rrsets[0].Name != "old.orijtech.com." {
+		t.Errorf("zone not restored, got: %+v", fz.rrsets)
+	}
+}
+
+func TestRevertChangeRefusesDriftedRecords(t *testing.T) {
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			// Modified out-of-band since the change below was applied.
+			{Name: "new.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.3"}},
+		},
+	}
+	client := newTestClient(t, fz.ServeHTTP)
+
+	change := &dns.Change{
+		Id: "7",
+		Additions: []*dns.ResourceRecordSet{
+			{Name: "new.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.2"}},
+		},
+	}
+	_, err := client.RevertChange(context.Background(), "sample", "zone", change)
+	if err == nil || !strings.Contains(err.Error(), "refusing to revert") {
+		t.Errorf("got err %v, want a refusal to revert", err)
+	}
+	if len(fz.changes) != 0 {
+		t.Errorf("expected no change to be submitted, got %d", len(fz.changes))
+	}
+}
+
+func TestDeleteRecordSetsIgnoreMissing(t *testing.T) {
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			{Name: "www.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.1"}},
+			{Name: "api.orijtech.com.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"www.orijtech.com."}},
+		},
+	}
+	client := newTestClient(t, fz.ServeHTTP)
+
+	dreq := &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+		Records: []*Record{
+			{Type: AName, DNSName: "www.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.1"}},
+			{Type: AName, DNSName: "gone.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.9"}},
+			{Type: CName, DNSName: "api.orijtech.com", TTL: 300, CanonicalName: "www.orijtech.com"},
+			{Type: TXT, DNSName: "www.orijtech.com", TTL: 300, TXTRecords: []string{"never-created"}},
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := client.DeleteRecordSets(ctx, dreq); err == nil {
+		t.Fatal("expected deleting missing records to fail without IgnoreMissingDeletions")
+	}
+
+	dreq.IgnoreMissingDeletions = true
+	change, err := client.DeleteRecordSets(ctx, dreq)
+	if err != nil {
+		t.Fatalf("DeleteRecordSets: %v", err)
+	}
+	var deleted []string
+	for _, rrset := range change.Deletions {
+		deleted = append(deleted, rrset.Type+" "+rrset.Name)
+	}
+	if want := []string{"A www.orijtech.com.", "CNAME api.orijtech.com."}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deletions: got %q want %q", deleted, want)
+	}
+	if len(fz.rrsets) != 0 {
+		t.Errorf("expected the zone to be empty, got %+v", fz.rrsets)
+	}
+
+	// Deleting again is a no-op that doesn't submit a change.
+	submitted := len(fz.changes)
+	if _, err := client.DeleteRecordSets(ctx, dreq); err != nil {
+		t.Fatalf("repeated DeleteRecordSets: %v", err)
+	}
+	if len(fz.changes) != submitted {
+		t.Errorf("expected no change to be submitted, got %d more", len(fz.changes)-submitted)
+	}
+}
+
+func TestZoneRecordStats(t *testing.T) {
+	var pageTokens []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "GET "+fakeZonePath+"/rrsets" {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		pageToken := req.URL.Query().Get("pageToken")
+		pageTokens = append(pageTokens, pageToken)
+		switch pageToken {
+		case "":
+			writeJSON(w, &dns.ResourceRecordSetsListResponse{
+				Rrsets: []*dns.ResourceRecordSet{
+					{Name: "orijtech.com.", Type: "NS", Rrdatas: []string{"ns-cloud-a1.googledomains.com.", "ns-cloud-a2.googledomains.com."}},
+					{Name: "orijtech.com.", Type: "SOA", Rrdatas: []string{"ns-cloud-a1.googledomains.com. 1 21600 3600 259200 300"}},
+				},
+				NextPageToken: "page-2",
+			})
+		case "page-2":
+			writeJSON(w, &dns.ResourceRecordSetsListResponse{
+				Rrsets: []*dns.ResourceRecordSet{
+					{Name: "www.orijtech.com.", Type: "A", Rrdatas: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+				},
+			})
+		default:
+			http.Error(w, "unexpected page token", http.StatusBadRequest)
+		}
+	})
+
+	stats, err := client.ZoneRecordStats(context.Background(), "sample", "zone")
+	if err != nil {
+		t.Fatalf("ZoneRecordStats: %v", err)
+	}
+	if want := (ZoneStats{RecordSets: 3, Records: 6}); *stats != want {
+		t.Errorf("got %+v want %+v", *stats, want)
+	}
+	if want := []string{"", "page-2"}; !reflect.DeepEqual(pageTokens, want) {
+		t.Errorf("page tokens: got %q want %q", pageTokens, want)
+	}
+
+	if _, err := client.ZoneRecordStats(context.Background(), "sample", ""); err != errEmptyZone {
+		t.Errorf("blank zone: got err %v want %v", err, errEmptyZone)
+	}
+}
+
+func TestListDNSRecordSetsByType(t *testing.T) {
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			{Name: "www.orijtech.com.", Type: "A", Rrdatas: []string{"10.0.0.1"}},
+			{Name: "www.orijtech.com.", Type: "TXT", Rrdatas: []string{"v=spf1 -all"}},
+			{Name: "api.orijtech.com.", Type: "CNAME", Rrdatas: []string{"www.orijtech.com."}},
+			{Name: "docs.orijtech.com.", Type: "CNAME", Rrdatas: []string{"www.orijtech.com."}},
+		},
+	}
+	var typeFilters []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		typeFilters = append(typeFilters, req.URL.Query().Get("type"))
+		fz.ServeHTTP(w, req)
+	})
+
+	list := func(rreq *RecordSetRequest) []string {
+		t.Helper()
+		rres, err := client.ListDNSRecordSets(context.Background(), rreq)
+		if err != nil {
+			t.Fatalf("ListDNSRecordSets: %v", err)
+		}
+		var found []string
+		for page := range rres.Pages {
+			if page.Err != nil {
+				t.Fatalf("page #%d: %v", page.PageNumber, page.Err)
+			}
+			for _, rrset := range page.RecordSets {
+				found = append(found, rrset.Type+" "+rrset.Name)
+			}
+		}
+		return found
+	}
+
+	got := list(&RecordSetRequest{Project: "sample", Zone: "zone", DomainName: "www.orijtech.com", Type: TXT})
+	if want := []string{"TXT www.orijtech.com."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("name and type: got %q want %q", got, want)
+	}
+	if want := []string{"TXT"}; !reflect.DeepEqual(typeFilters, want) {
+		t.Errorf("type filters: got %q want %q", typeFilters, want)
+	}
+
+	// Without a name, the type can't be sent and is filtered as listed.
+	typeFilters = nil
+	got = list(&RecordSetRequest{Project: "sample", Zone: "zone", Type: CName})
+	if want := []string{"CNAME api.orijtech.com.", "CNAME docs.orijtech.com."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("type only: got %q want %q", got, want)
+	}
+	if want := []string{""}; !reflect.DeepEqual(typeFilters, want) {
+		t.Errorf("type filters: got %q want %q", typeFilters, want)
+	}
+
+	_, err := client.ListDNSRecordSets(context.Background(), &RecordSetRequest{Project: "sample", Zone: "zone", DomainName: "www.orijtech.com", Type: "BOGUS"})
+	if err == nil {
+		t.Error("expected an error for an unknown record type")
+	}
+}
+
+func TestAddRecordSetsCNAMEConflict(t *testing.T) {
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			{Name: "www.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.1"}},
+		},
+	}
+	client := newTestClient(t, fz.ServeHTTP)
+	ctx := context.Background()
+
+	tests := [...]struct {
+		ureq    *UpdateRequest
+		wantErr error
+	}{
+		// An A and a CNAME record added together at the same name.
+		0: {
+			ureq: &UpdateRequest{Additions: []*Record{
+				{Type: AName, DNSName: "api.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.2"}},
+				{Type: CName, DNSName: "api.orijtech.com", TTL: 300, CanonicalName: "www.orijtech.com"},
+			}},
+			wantErr: ErrCNAMEConflict,
+		},
+		// A CNAME added at the name of an existing A record.
+		1: {
+			ureq: &UpdateRequest{Additions: []*Record{
+				{Type: CName, DNSName: "www.orijtech.com", TTL: 300, CanonicalName: "orijtech.com"},
+			}},
+			wantErr: ErrCNAMEConflict,
+		},
+		// Replacing the existing A record by a CNAME is fine.
+		2: {
+			ureq: &UpdateRequest{
+				Deletions: []*Record{{Type: AName, DNSName: "www.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.1"}}},
+				Additions: []*Record{{Type: CName, DNSName: "www.orijtech.com", TTL: 300, CanonicalName: "orijtech.com"}},
+			},
+		},
+		// Other types coexist at the same name.
+		3: {
+			ureq: &UpdateRequest{Additions: []*Record{
+				{Type: AName, DNSName: "orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.3"}},
+				{Type: TXT, DNSName: "orijtech.com", TTL: 300, TXTRecords: []string{"v=spf1 -all"}},
+			}},
+		},
+	}
+
+	for i, tt := range tests {
+		tt.ureq.Project, tt.ureq.Zone = "sample", "zone"
+		submitted := len(fz.changes)
+		_, err := client.UpdateRecordSets(ctx, tt.ureq)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("#%d: got err %v want %v", i, err, tt.wantErr)
+			}
+			if len(fz.changes) != submitted {
+				t.Errorf("#%d: expected the conflicting change not to be submitted", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected err: %v", i, err)
+		}
+	}
+}
+
+func TestCNAMEConflictsLooksUpAddedNames(t *testing.T) {
+	var listed []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "GET "+fakeZonePath+"/rrsets" {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		name := req.URL.Query().Get("name")
+		listed = append(listed, name)
+		res := new(dns.ResourceRecordSetsListResponse)
+		if name == "www.orijtech.com." {
+			res.Rrsets = []*dns.ResourceRecordSet{{Name: name, Type: "A", Rrdatas: []string{"10.0.0.2"}}}
+		}
+		writeJSON(w, res)
+	})
+	ctx := context.Background()
+
+	// A single addition mustn't list the whole zone.
+	additions := []*dns.ResourceRecordSet{{Name: "www.orijtech.com.", Type: "CNAME", Rrdatas: []string{"orijtech.com."}}}
+	err := client.checkCNAMEConflicts(ctx, "sample", "zone", additions, nil)
+	if !errors.Is(err, ErrCNAMEConflict) {
+		t.Errorf("got err %v want %v", err, ErrCNAMEConflict)
+	}
+	if want := []string{"www.orijtech.com."}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("listed %q, want only the added name %q", listed, want)
+	}
+
+	// Each added name is looked up once, and deleted records don't conflict.
+	listed = nil
+	additions = append(additions,
+		&dns.ResourceRecordSet{Name: "api.orijtech.com.", Type: "A", Rrdatas: []string{"10.0.0.3"}},
+		&dns.ResourceRecordSet{Name: "api.orijtech.com.", Type: "AAAA", Rrdatas: []string{"2001:db8::3"}},
+	)
+	deletions := []*dns.ResourceRecordSet{{Name: "www.orijtech.com.", Type: "A", Rrdatas: []string{"10.0.0.2"}}}
+	if err := client.checkCNAMEConflicts(ctx, "sample", "zone", additions, deletions); err != nil {
+		t.Errorf("with the A record deleted: %v", err)
+	}
+	if want := []string{"www.orijtech.com.", "api.orijtech.com."}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("listed %q want %q", listed, want)
+	}
+}
+
+func TestAddRecordSetsRawHTTPSRecord(t *testing.T) {
+	fz := new(fakeZone)
+	client := newTestClient(t, fz.ServeHTTP)
+
+	_, err := client.AddRecordSets(context.Background(), &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+		Records: []*Record{{
+			DNSName:    "www.orijtech.com",
+			TTL:        300,
+			RawType:    "HTTPS",
+			RawRrdatas: []string{`1 . alpn="h3,h2"`, `1 . alpn="h3,h2"`},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("AddRecordSets: %v", err)
+	}
+	want := []*dns.ResourceRecordSet{
+		{Name: "www.orijtech.com.", Type: "HTTPS", Ttl: 300, Rrdatas: []string{`1 . alpn="h3,h2"`}},
+	}
+	if !reflect.DeepEqual(fz.rrsets, want) {
+		t.Errorf("got record sets %+v want %+v", fz.rrsets, want)
+	}
+}
+
+func TestRecordValidateRaw(t *testing.T) {
+	tests := [...]struct {
+		rec     *Record
+		wantErr error
+	}{
+		0: {rec: &Record{RawType: "NAPTR", RawRrdatas: []string{`100 10 "U" "E2U+sip" "!^.*$!sip:info@orijtech.com!" .`}}},
+		1: {rec: &Record{RawType: "SVCB", RawRrdatas: []string{"1 ."}, Type: AName}, wantErr: errRawAndTypedMixedUse},
+		2: {rec: &Record{RawType: "SVCB", RawRrdatas: []string{"1 ."}, TXTRecords: []string{"hello"}}, wantErr: errRawAndTypedMixedUse},
+		3: {rec: &Record{RawRrdatas: []string{"1 ."}}, wantErr: errBlankRawType},
+		4: {rec: &Record{RawType: "HTTPS", RawRrdatas: []string{" "}}, wantErr: errEmptyRawRrdatas},
+	}
+
+	for i, tt := range tests {
+		if err := tt.rec.Validate(); err != tt.wantErr {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAddRecordSetsInBatches(t *testing.T) {
+	fz := new(fakeZone)
+	client := newTestClient(t, fz.ServeHTTP)
+
+	var records []*Record
+	for i := 0; i < 1500; i++ {
+		records = append(records, &Record{
+			Type:          AName,
+			DNSName:       fmt.Sprintf("host-%d.orijtech.com", i),
+			TTL:           300,
+			IPV4Addresses: []string{"10.0.0.1"},
+		})
+	}
+	areq := &UpdateRequest{Project: "sample", Zone: "zone", Records: records}
+
+	changes, err := client.AddRecordSetsInBatches(context.Background(), areq)
+	if err != nil {
+		t.Fatalf("AddRecordSetsInBatches: %v", err)
+	}
+	if len(changes) != 2 || len(fz.changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d returned and %d submitted", len(changes), len(fz.changes))
+	}
+	if n0, n1 := len(fz.changes[0].Additions), len(fz.changes[1].Additions); n0 != 1000 || n1 != 500 {
+		t.Errorf("change sizes: got %d and %d want 1000 and 500", n0, n1)
+	}
+	if changes[0].Id == changes[1].Id {
+		t.Errorf("expected distinct change IDs, got %q twice", changes[0].Id)
+	}
+	if len(fz.rrsets) != 1500 {
+		t.Errorf("record sets: got %d want 1500", len(fz.rrsets))
+	}
+
+	// AddRecordSets only submits single changes.
+	fz = new(fakeZone)
+	client = newTestClient(t, fz.ServeHTTP)
+	if _, err := client.AddRecordSets(context.Background(), areq); err != errTooManyRecordSets {
+		t.Errorf("AddRecordSets: got err %v want %v", err, errTooManyRecordSets)
+	}
+	if len(fz.changes) != 0 {
+		t.Errorf("AddRecordSets: expected no change to be submitted, got %d", len(fz.changes))
+	}
+}
+
+func TestAddRecordSetsInBatchesDualStack(t *testing.T) {
+	fz := new(fakeZone)
+	client := newTestClient(t, fz.ServeHTTP)
+
+	var records []*Record
+	for i := 0; i < 600; i++ {
+		records = append(records, &Record{
+			Type:          AName,
+			DNSName:       fmt.Sprintf("host-%d.orijtech.com", i),
+			TTL:           300,
+			IPV4Addresses: []string{"10.0.0.1"},
+			IPV6Addresses: []string{"2001:db8::1"},
+		})
+	}
+	changes, err := client.AddRecordSetsInBatches(context.Background(), &UpdateRequest{Project: "sample", Zone: "zone", Records: records})
+	if err != nil {
+		t.Fatalf("AddRecordSetsInBatches: %v", err)
+	}
+	if len(changes) != 2 || len(fz.changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d returned and %d submitted", len(changes), len(fz.changes))
+	}
+	for i, change := range fz.changes {
+		if n := len(change.Additions); n > maxChangeRecordSets {
+			t.Errorf("change #%d: got %d additions, want at most %d", i, n, maxChangeRecordSets)
+		}
+	}
+	if len(fz.rrsets) != 1200 {
+		t.Errorf("record sets: got %d want 1200", len(fz.rrsets))
+	}
+}
+
+func TestUpdateRecordSetsPreconditions(t *testing.T) {
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			// Modified out-of-band from 10.0.0.1.
+			{Name: "www.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.9"}},
+		},
+	}
+	client := newTestClient(t, fz.ServeHTTP)
+	ctx := context.Background()
+
+	ureq := &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+
+		Additions:     []*Record{{Type: AName, DNSName: "www.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.2"}}},
+		Deletions:     []*Record{{Type: AName, DNSName: "www.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.1"}}},
+		Preconditions: []*Record{{Type: AName, DNSName: "www.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.1"}}},
+	}
+	if _, err := client.UpdateRecordSets(ctx, ureq); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("drifted zone: got err %v want %v", err, ErrPreconditionFailed)
+	}
+	if len(fz.changes) != 0 {
+		t.Errorf("drifted zone: expected no change to be submitted, got %d", len(fz.changes))
+	}
+
+	ureq.Deletions[0].IPV4Addresses = []string{"10.0.0.9"}
+	ureq.Preconditions[0].IPV4Addresses = []string{"10.0.0.9"}
+	if _, err := client.UpdateRecordSets(ctx, ureq); err != nil {
+		t.Fatalf("matching zone: unexpected err: %v", err)
+	}
+	if len(fz.changes) != 1 {
+		t.Errorf("matching zone: expected the change to be submitted, got %d changes", len(fz.changes))
+	}
+}
+
+func TestAddRecordSetsDualStack(t *testing.T) {
+	fz := new(fakeZone)
+	client := newTestClient(t, fz.ServeHTTP)
+
+	ctx := context.Background()
+	change, err := client.AddRecordSets(ctx, &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+		Records: []*Record{{
+			Type:          AName,
+			DNSName:       "www.orijtech.com",
+			TTL:           300,
+			IPV4Addresses: []string{"10.0.0.1"},
+			IPV6Addresses: []string{"2001:db8::1"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("AddRecordSets: %v", err)
+	}
+	want := []*dns.ResourceRecordSet{
+		{Name: "www.orijtech.com.", Type: "A", Ttl: 300, Rrdatas: []string{"10.0.0.1"}},
+		{Name: "www.orijtech.com.", Type: "AAAA", Ttl: 300, Rrdatas: []string{"2001:db8::1"}},
+	}
+	if len(change.Additions) != len(want) {
+		t.Fatalf("got %d additions want %d", len(change.Additions), len(want))
+	}
+	for i, rrset := range change.Additions {
+		if !sameRecordSet(rrset, want[i]) {
+			t.Errorf("#%d: got %+v want %+v", i, rrset, want[i])
+		}
+	}
+
+	_, err = client.AddRecordSets(ctx, &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+		Records: []*Record{{
+			Type:          AName,
+			DNSName:       "api.orijtech.com",
+			IPV4Addresses: []string{"2001:db8::2"},
+			IPV6Addresses: []string{"2001:db8::1"},
+		}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "is not an IPv4 address") {
+		t.Errorf("mixed up families: got err %v", err)
+	}
+}
+
+func TestAddRecordSetsInBatchesRollback(t *testing.T) {
+	var records []*Record
+	for i := 0; i < 1200; i++ {
+		records = append(records, &Record{
+			Type:          AName,
+			DNSName:       fmt.Sprintf("host-%d.orijtech.com", i),
+			TTL:           300,
+			IPV4Addresses: []string{"10.0.0.1"},
+		})
+	}
+
+	for _, skipRollback := range []bool{false, true} {
+		fz := new(fakeZone)
+		var posts int
+		client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == "POST" {
+				posts++
+				if posts == 2 {
+					http.Error(w, "backend error", http.StatusInternalServerError)
+					return
+				}
+			}
+			fz.ServeHTTP(w, req)
+		})
+
+		areq := &UpdateRequest{Project: "sample", Zone: "zone", Records: records, SkipBatchRollback: skipRollback}
+		changes, err := client.AddRecordSetsInBatches(context.Background(), areq)
+		if err == nil || !strings.Contains(err.Error(), "backend error") {
+			t.Fatalf("skipRollback %v: got err %v, want the second batch's failure", skipRollback, err)
+		}
+		if skipRollback {
+			if len(changes) != 1 || len(fz.rrsets) != 1000 {
+				t.Errorf("without rollback: got %d changes and %d record sets, want the first batch kept", len(changes), len(fz.rrsets))
+			}
+			continue
+		}
+		if len(changes) != 0 || len(fz.rrsets) != 0 {
+			t.Errorf("with rollback: got %d changes and %d record sets, want the first batch reverted", len(changes), len(fz.rrsets))
+		}
+		if !strings.Contains(err.Error(), "reverted the 1 batches that were applied") {
+			t.Errorf("with rollback: got err %v, want it to report the revert", err)
+		}
+	}
+}
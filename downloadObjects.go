@@ -0,0 +1,118 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const defaultDownloadObjectsConcurrency = 4
+
+// DownloadObjects downloads the objects of the bucket concurrently into
+// destDir, each at its name as a path relative to destDir, returning the
+// paths of the written files in the order of objects. On failure the
+// remaining downloads are cancelled and the first error is returned
+// along with the paths of the files that were written.
+func (c *Client) DownloadObjects(ctx context.Context, bucket string, objects []string, destDir string) ([]string, error) {
+	if bucket == "" {
+		return nil, errEmptyBucket
+	}
+	if destDir == "" {
+		return nil, errEmptyDir
+	}
+	destPaths := make([]string, len(objects))
+	for i, object := range objects {
+		destPath, err := objectDestPath(destDir, object)
+		if err != nil {
+			return nil, err
+		}
+		destPaths[i] = destPath
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	written := make([]bool, len(objects))
+	sema := make(chan bool, defaultDownloadObjectsConcurrency)
+	for i := range objects {
+		sema <- true
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			err := c.downloadToFile(ctx, bucket, objects[i], destPaths[i])
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			written[i] = true
+		}(i)
+	}
+	wg.Wait()
+
+	var paths []string
+	for i, ok := range written {
+		if ok {
+			paths = append(paths, destPaths[i])
+		}
+	}
+	return paths, firstErr
+}
+
+// objectDestPath returns the path under destDir that the object is
+// downloaded to, rejecting names such as "../x" that would escape it.
+func objectDestPath(destDir, object string) (string, error) {
+	if object == "" {
+		return "", errEmptyName
+	}
+	rel := filepath.FromSlash(object)
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("object %q would be downloaded outside of %q", object, destDir)
+	}
+	destPath := filepath.Join(destDir, rel)
+	if cleanDir := filepath.Clean(destDir); !strings.HasPrefix(destPath, cleanDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("object %q would be downloaded outside of %q", object, destDir)
+	}
+	return destPath, nil
+}
+
+func (c *Client) downloadToFile(ctx context.Context, bucket, object, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+	body, err := c.Download(ctx, bucket, object)
+	if err != nil {
+		return fmt.Errorf("object %q: %w", object, err)
+	}
+	defer body.Close()
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return fmt.Errorf("object %q: %w", object, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,64 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadObjects(t *testing.T) {
+	fs := newFakeStorage()
+	contents := map[string]string{
+		"index.html":       "<html></html>",
+		"css/site.css":     "body {}",
+		"js/vendor/lib.js": "var lib;",
+		"js/app.js":        "lib();",
+	}
+	var objects []string
+	for name, content := range contents {
+		fs.objects["site/"+name] = nil
+		fs.contents["site/"+name] = []byte(content)
+		objects = append(objects, name)
+	}
+	client := newTestClient(t, fs.ServeHTTP)
+
+	destDir := t.TempDir()
+	paths, err := client.DownloadObjects(context.Background(), "site", objects, destDir)
+	if err != nil {
+		t.Fatalf("DownloadObjects: %v", err)
+	}
+	if len(paths) != len(objects) {
+		t.Fatalf("got %d paths want %d", len(paths), len(objects))
+	}
+	for i, object := range objects {
+		if want := filepath.Join(destDir, filepath.FromSlash(object)); paths[i] != want {
+			t.Errorf("#%d: got path %q want %q", i, paths[i], want)
+		}
+		blob, err := os.ReadFile(paths[i])
+		if err != nil {
+			t.Errorf("%q: %v", object, err)
+			continue
+		}
+		if got, want := string(blob), contents[object]; got != want {
+			t.Errorf("%q: got content %q want %q", object, got, want)
+		}
+	}
+
+	_, err = client.DownloadObjects(context.Background(), "site", []string{"../escape.txt"}, destDir)
+	if err == nil || !strings.Contains(err.Error(), "outside of") {
+		t.Errorf("escaping name: got err %v", err)
+	}
+
+	paths, err = client.DownloadObjects(context.Background(), "site", []string{"index.html", "missing.html"}, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "missing.html") {
+		t.Errorf("missing object: got err %v", err)
+	}
+	// The failure cancels the other download, so it may not be written.
+	for _, p := range paths {
+		if filepath.Base(p) != "index.html" {
+			t.Errorf("missing object: got path %q that wasn't written", p)
+		}
+	}
+}
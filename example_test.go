@@ -102,6 +102,25 @@ func Example_client_FindInstance() {
 	fmt.Printf("Retrieved instance: %s\n", blob)
 }
 
+func Example_client_ResetInstance() {
+	ctx := context.Background()
+	client, err := infra.NewDefaultClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	op, err := client.ResetInstance(ctx, &infra.InstanceRequest{
+		Project: "sample-981058",
+		Zone:    "us-central1-c",
+		Name:    "archomp",
+
+		BlockUntilCompletion: true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Reset instance, operation: %q status: %s\n", op.Name, op.Status)
+}
+
 func Example_client_ListDNSRecordSets() {
 	ctx := context.Background()
 	client, err := infra.NewDefaultClient(ctx)
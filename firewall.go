@@ -0,0 +1,177 @@
+package infra
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func (c *Client) firewallsService() *compute.FirewallsService {
+	return compute.NewFirewallsService(c.computeSrvc)
+}
+
+// ListFirewallRules returns all the firewall rules of the project.
+func (c *Client) ListFirewallRules(ctx context.Context, project string) ([]*compute.Firewall, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	maxResultsPerPage, err := resultsPerPageOrDefault(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*compute.Firewall
+	req := c.firewallsService().List(project).MaxResults(maxResultsPerPage)
+	err = req.Pages(ctx, func(fl *compute.FirewallList) error {
+		rules = append(rules, fl.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// AllowsPort reports whether the ingress firewall rules permit traffic
+// over proto, e.g "tcp", to port on instances with the network tag.
+// Rules are evaluated like Google Cloud does: the matching rule with
+// the highest priority, that is the lowest Priority, takes effect and
+// a deny rule wins over an allow rule of the same priority.
+// Traffic that no rule matches is denied.
+func AllowsPort(rules []*compute.Firewall, tag string, port int64, proto string) bool {
+	var (
+		matched      bool
+		allowed      bool
+		bestPriority int64
+	)
+	for _, rule := range rules {
+		if rule == nil || rule.Disabled || !appliesToIngress(rule) || !appliesToTag(rule, tag) {
+			continue
+		}
+
+		var isAllow bool
+		switch {
+		case anyEntryMatches(deniedEntries(rule), port, proto):
+			isAllow = false
+		case anyEntryMatches(allowedEntries(rule), port, proto):
+			isAllow = true
+		default:
+			continue
+		}
+
+		priority := rule.Priority
+		switch {
+		case !matched || priority < bestPriority:
+			matched, bestPriority, allowed = true, priority, isAllow
+		case priority == bestPriority && !isAllow:
+			allowed = false
+		}
+	}
+	return matched && allowed
+}
+
+func appliesToIngress(rule *compute.Firewall) bool {
+	return rule.Direction == "" || strings.EqualFold(rule.Direction, "INGRESS")
+}
+
+// appliesToTag reports whether the rule targets instances with the tag.
+// A rule without target tags or service accounts applies to all instances.
+func appliesToTag(rule *compute.Firewall, tag string) bool {
+	if len(rule.TargetTags) == 0 {
+		return len(rule.TargetServiceAccounts) == 0
+	}
+	for _, targetTag := range rule.TargetTags {
+		if targetTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+type firewallEntry struct {
+	IPProtocol string
+	Ports      []string
+}
+
+func allowedEntries(rule *compute.Firewall) []*firewallEntry {
+	var entries []*firewallEntry
+	for _, allowed := range rule.Allowed {
+		entries = append(entries, &firewallEntry{IPProtocol: allowed.IPProtocol, Ports: allowed.Ports})
+	}
+	return entries
+}
+
+func deniedEntries(rule *compute.Firewall) []*firewallEntry {
+	var entries []*firewallEntry
+	for _, denied := range rule.Denied {
+		entries = append(entries, &firewallEntry{IPProtocol: denied.IPProtocol, Ports: denied.Ports})
+	}
+	return entries
+}
+
+func anyEntryMatches(entries []*firewallEntry, port int64, proto string) bool {
+	for _, entry := range entries {
+		if entry.matches(port, proto) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the entry covers the port and protocol.
+// An entry without ports covers all the ports of its protocol.
+func (fe *firewallEntry) matches(port int64, proto string) bool {
+	if fe.IPProtocol != "all" && !strings.EqualFold(fe.IPProtocol, proto) {
+		return false
+	}
+	if len(fe.Ports) == 0 {
+		return true
+	}
+	for _, portRange := range fe.Ports {
+		if portInRange(port, portRange) {
+			return true
+		}
+	}
+	return false
+}
+
+// portInRange reports whether port is portRange, that is
+// either a single port such as "80" or a range such as "8000-9000".
+func portInRange(port int64, portRange string) bool {
+	lo, hi := portRange, portRange
+	if i := strings.Index(portRange, "-"); i >= 0 {
+		lo, hi = portRange[:i], portRange[i+1:]
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(lo), 10, 64)
+	if err != nil {
+		return false
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(hi), 10, 64)
+	if err != nil {
+		return false
+	}
+	return start <= port && port <= end
+}
+
+// EffectiveFirewalls returns the VPC firewall rules that apply to the
+// primary network interface of the instance identified by ireq, which
+// helps to find out why the instance can't be reached.
+func (c *Client) EffectiveFirewalls(ctx context.Context, ireq *InstanceRequest) ([]*compute.Firewall, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+	nic, err := c.primaryNetworkInterface(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	req := c.instancesService().GetEffectiveFirewalls(ireq.Project, ireq.Zone, ireq.Name, nic.Name)
+	res, err := req.Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return res.Firewalls, nil
+}
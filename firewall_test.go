@@ -0,0 +1,164 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestListFirewallRules(t *testing.T) {
+	const firewallsPath = "/compute/v1/projects/sample/global/firewalls"
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "GET "+firewallsPath {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		switch req.URL.Query().Get("pageToken") {
+		case "":
+			writeJSON(w, &compute.FirewallList{
+				Items:         []*compute.Firewall{{Name: "allow-http"}, {Name: "allow-ssh"}},
+				NextPageToken: "page-2",
+			})
+		case "page-2":
+			writeJSON(w, &compute.FirewallList{Items: []*compute.Firewall{{Name: "deny-all"}}})
+		default:
+			http.Error(w, "unexpected page token", http.StatusBadRequest)
+		}
+	})
+
+	rules, err := client.ListFirewallRules(context.Background(), "sample")
+	if err != nil {
+		t.Fatalf("ListFirewallRules: %v", err)
+	}
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	if got, want := len(names), 3; got != want {
+		t.Fatalf("got %d rules %v want %d", got, names, want)
+	}
+	if names[2] != "deny-all" {
+		t.Errorf("last rule: got %q want %q", names[2], "deny-all")
+	}
+
+	if _, err := client.ListFirewallRules(context.Background(), ""); err != errEmptyProject {
+		t.Errorf("blank project: got err %v want %v", err, errEmptyProject)
+	}
+}
+
+func TestAllowsPort(t *testing.T) {
+	rules := []*compute.Firewall{
+		{
+			Name:       "allow-http",
+			Priority:   1000,
+			TargetTags: []string{"web"},
+			Allowed:    []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"80", "443"}}},
+		},
+		{
+			Name:     "allow-ssh-everywhere",
+			Priority: 1000,
+			Allowed:  []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"22"}}},
+		},
+		{
+			Name:       "allow-app-range",
+			Priority:   1000,
+			TargetTags: []string{"app"},
+			Allowed:    []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"8000-9000"}}},
+		},
+		{
+			Name:       "deny-debug-port",
+			Priority:   900,
+			TargetTags: []string{"app"},
+			Denied:     []*compute.FirewallDenied{{IPProtocol: "tcp", Ports: []string{"8080"}}},
+		},
+		{
+			Name:       "allow-dns",
+			Priority:   1000,
+			TargetTags: []string{"dns"},
+			Allowed:    []*compute.FirewallAllowed{{IPProtocol: "udp", Ports: []string{"53"}}},
+		},
+		{
+			Name:       "deny-dns-same-priority",
+			Priority:   1000,
+			TargetTags: []string{"dns"},
+			Denied:     []*compute.FirewallDenied{{IPProtocol: "udp", Ports: []string{"53"}}},
+		},
+		{
+			Name:       "disabled-allow-all",
+			Priority:   1,
+			Disabled:   true,
+			TargetTags: []string{"web"},
+			Allowed:    []*compute.FirewallAllowed{{IPProtocol: "all"}},
+		},
+		{
+			Name:       "egress-allow-all",
+			Priority:   1,
+			Direction:  "EGRESS",
+			TargetTags: []string{"web"},
+			Allowed:    []*compute.FirewallAllowed{{IPProtocol: "all"}},
+		},
+	}
+
+	tests := [...]struct {
+		tag   string
+		port  int64
+		proto string
+		want  bool
+	}{
+		0:  {tag: "web", port: 80, proto: "tcp", want: true},
+		1:  {tag: "web", port: 443, proto: "TCP", want: true},
+		2:  {tag: "web", port: 80, proto: "udp", want: false},
+		3:  {tag: "web", port: 8443, proto: "tcp", want: false},
+		4:  {tag: "web", port: 22, proto: "tcp", want: true},
+		5:  {tag: "untagged", port: 22, proto: "tcp", want: true},
+		6:  {tag: "app", port: 8500, proto: "tcp", want: true},
+		7:  {tag: "app", port: 9001, proto: "tcp", want: false},
+		8:  {tag: "app", port: 8080, proto: "tcp", want: false},
+		9:  {tag: "web", port: 8500, proto: "tcp", want: false},
+		10: {tag: "dns", port: 53, proto: "udp", want: false},
+	}
+
+	for i, tt := range tests {
+		if got := AllowsPort(rules, tt.tag, tt.port, tt.proto); got != tt.want {
+			t.Errorf("#%d: AllowsPort(%q, %d, %q): got %t want %t", i, tt.tag, tt.port, tt.proto, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveFirewalls(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/frontend"
+	var networkInterface string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + instancePath:
+			writeJSON(w, &compute.Instance{
+				Name:              "frontend",
+				NetworkInterfaces: []*compute.NetworkInterface{{Name: "nic0"}, {Name: "nic1"}},
+			})
+		case "GET " + instancePath + "/getEffectiveFirewalls":
+			networkInterface = req.URL.Query().Get("networkInterface")
+			writeJSON(w, &compute.InstancesGetEffectiveFirewallsResponse{
+				Firewalls: []*compute.Firewall{{Name: "allow-http"}, {Name: "default-allow-ssh"}},
+			})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	rules, err := client.EffectiveFirewalls(context.Background(), &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "frontend"})
+	if err != nil {
+		t.Fatalf("EffectiveFirewalls: %v", err)
+	}
+	if networkInterface != "nic0" {
+		t.Errorf("network interface: got %q want %q", networkInterface, "nic0")
+	}
+	if len(rules) != 2 || rules[0].Name != "allow-http" || rules[1].Name != "default-allow-ssh" {
+		t.Errorf("got rules %+v want allow-http and default-allow-ssh", rules)
+	}
+
+	if _, err := client.EffectiveFirewalls(context.Background(), &InstanceRequest{Zone: "us-central1-c", Name: "frontend"}); err != errEmptyProject {
+		t.Errorf("blank project: got err %v want %v", err, errEmptyProject)
+	}
+}
@@ -0,0 +1,81 @@
+package infra
+
+import (
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// InstanceRequestFromInstance converts an existing instance back into an
+// InstanceRequest, for example to create a similar instance. Its Project
+// and Zone are taken from the instance's self link and zone URLs.
+//
+// The conversion is lossy:
+//   - Metadata holds every metadata item, including those that were
+//     generated from fields such as StartupScript and SSHKeys, which are
+//     left unset.
+//   - Disks are the attached disks as they are, that is referencing the
+//     instance's existing disks rather than describing how to initialize
+//     new ones, so they must be replaced to create another instance.
+//   - Only the first network interface is kept, including any
+//     external IP that was assigned to it.
+//   - MachineType is parsed from the machine type's name, so machine
+//     types other than the custom and standard ones have only their Type.
+//   - ResourcePolicies are full URLs.
+//   - Fields such as the instance's status, labels and creation
+//     timestamp have no counterpart in InstanceRequest.
+func InstanceRequestFromInstance(inst *compute.Instance) *InstanceRequest {
+	if inst == nil {
+		return nil
+	}
+
+	ireq := &InstanceRequest{
+		Project:     projectFromURL(inst.SelfLink),
+		Zone:        lastURLSegment(inst.Zone),
+		Name:        inst.Name,
+		Description: inst.Description,
+
+		CanForwardIP: inst.CanIpForward,
+
+		Disks:    inst.Disks,
+		Metadata: inst.Metadata,
+
+		ServiceAccounts:     inst.ServiceAccounts,
+		ReservationAffinity: inst.ReservationAffinity,
+		ResourcePolicies:    inst.ResourcePolicies,
+
+		MinCPUPlatform: inst.MinCpuPlatform,
+	}
+	if inst.MachineType != "" {
+		ireq.MachineType = machineTypeFromURL(inst.MachineType)
+	}
+	if ireq.Zone == "" {
+		ireq.Zone = zoneFromURL(inst.MachineType)
+	}
+	if len(inst.NetworkInterfaces) > 0 {
+		ireq.NetworkInterface = inst.NetworkInterfaces[0]
+	}
+	if inst.Tags != nil {
+		ireq.Tags = inst.Tags.Items
+	}
+	return ireq
+}
+
+func lastURLSegment(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// segmentAfter returns the segment following the segment
+// called name in the URL e.g the project of "projects/sample".
+func segmentAfter(url, name string) string {
+	segments := strings.Split(url, "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] == name {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+func projectFromURL(url string) string { return segmentAfter(url, "projects") }
+func zoneFromURL(url string) string    { return segmentAfter(url, "zones") }
@@ -0,0 +1,71 @@
+package infra
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestInstanceRequestFromInstanceRoundTrip(t *testing.T) {
+	env := "production"
+	ireq := &InstanceRequest{
+		Project:     "sample",
+		Zone:        "us-central1-c",
+		Name:        "web",
+		Description: "frontend server",
+
+		MachineType:      &MachineType{CPUCount: 4, MemoryMBs: 32768, ExtendedMemory: true},
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		Metadata: &compute.Metadata{
+			Items: []*compute.MetadataItems{{Key: "env", Value: &env}},
+		},
+	}
+
+	got := InstanceRequestFromInstance(ireq.toInstance())
+	if got.Name != ireq.Name || got.Description != ireq.Description {
+		t.Errorf("got name %q and description %q want %q and %q", got.Name, got.Description, ireq.Name, ireq.Description)
+	}
+	if got.Zone != ireq.Zone {
+		t.Errorf("zone: got %q want %q", got.Zone, ireq.Zone)
+	}
+	if !reflect.DeepEqual(got.MachineType, ireq.MachineType) {
+		t.Errorf("machine type: got %+v want %+v", got.MachineType, ireq.MachineType)
+	}
+	if !reflect.DeepEqual(got.Metadata, ireq.Metadata) {
+		t.Errorf("metadata: got %+v want %+v", got.Metadata, ireq.Metadata)
+	}
+	if !reflect.DeepEqual(got.NetworkInterface, ireq.NetworkInterface) {
+		t.Errorf("network interface: got %+v want %+v", got.NetworkInterface, ireq.NetworkInterface)
+	}
+	if !reflect.DeepEqual(got.Disks, ireq.disks()) {
+		t.Errorf("disks: got %+v want %+v", got.Disks, ireq.disks())
+	}
+}
+
+func TestInstanceRequestFromInstance(t *testing.T) {
+	inst := &compute.Instance{
+		Name:        "api",
+		SelfLink:    "https://www.googleapis.com/compute/v1/projects/sample/zones/us-east1-b/instances/api",
+		Zone:        "https://www.googleapis.com/compute/v1/projects/sample/zones/us-east1-b",
+		MachineType: "https://www.googleapis.com/compute/v1/projects/sample/zones/us-east1-b/machineTypes/n1-standard-4",
+		NetworkInterfaces: []*compute.NetworkInterface{
+			{Name: "nic0"},
+			{Name: "nic1"},
+		},
+	}
+
+	got := InstanceRequestFromInstance(inst)
+	if got.Project != "sample" || got.Zone != "us-east1-b" {
+		t.Errorf("got project %q and zone %q want %q and %q", got.Project, got.Zone, "sample", "us-east1-b")
+	}
+	if want := (&MachineType{Type: N1Standard4}); !reflect.DeepEqual(got.MachineType, want) {
+		t.Errorf("machine type: got %+v want %+v", got.MachineType, want)
+	}
+	if got.NetworkInterface == nil || got.NetworkInterface.Name != "nic0" {
+		t.Errorf("network interface: got %+v want nic0", got.NetworkInterface)
+	}
+	if InstanceRequestFromInstance(nil) != nil {
+		t.Error("expected a nil request for a nil instance")
+	}
+}
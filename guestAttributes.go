@@ -0,0 +1,24 @@
+package infra
+
+import (
+	"context"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// GuestAttributes returns the guest attributes that agents running on
+// the instance have published under queryPath, e.g "hostkeys/" for the
+// instance's SSH host keys. An empty queryPath returns all of them.
+// Guest attributes must be enabled on the instance with the
+// "enable-guest-attributes" metadata key set to "TRUE".
+func (c *Client) GuestAttributes(ctx context.Context, ireq *InstanceRequest, queryPath string) (*compute.GuestAttributes, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+	req := c.instancesService().GetGuestAttributes(ireq.Project, ireq.Zone, ireq.Name)
+	if queryPath != "" {
+		req = req.QueryPath(queryPath)
+	}
+	return req.Context(ctx).Do()
+}
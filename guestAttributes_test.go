@@ -0,0 +1,48 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestGuestAttributes(t *testing.T) {
+	const guestAttributesPath = "/compute/v1/projects/sample/zones/us-central1-c/instances/frontend/getGuestAttributes"
+	var queryPath string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "GET "+guestAttributesPath {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		queryPath = req.URL.Query().Get("queryPath")
+		writeJSON(w, &compute.GuestAttributes{
+			QueryPath: queryPath,
+			QueryValue: &compute.GuestAttributesValue{
+				Items: []*compute.GuestAttributesEntry{
+					{Namespace: "hostkeys", Key: "ssh-ed25519", Value: "AAAAC3NzaC1lZDI1NTE5"},
+				},
+			},
+		})
+	})
+
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "frontend"}
+	attrs, err := client.GuestAttributes(context.Background(), ireq, "hostkeys/")
+	if err != nil {
+		t.Fatalf("GuestAttributes: %v", err)
+	}
+	if queryPath != "hostkeys/" {
+		t.Errorf("query path: got %q want %q", queryPath, "hostkeys/")
+	}
+	if attrs.QueryValue == nil || len(attrs.QueryValue.Items) != 1 {
+		t.Fatalf("got attributes %+v want the host key", attrs)
+	}
+	if item := attrs.QueryValue.Items[0]; item.Key != "ssh-ed25519" || item.Value != "AAAAC3NzaC1lZDI1NTE5" {
+		t.Errorf("got attribute %+v want the ssh-ed25519 host key", item)
+	}
+
+	if _, err := client.GuestAttributes(context.Background(), &InstanceRequest{Project: "sample", Zone: "us-central1-c"}, ""); err != errBlankName {
+		t.Errorf("blank name: got err %v want %v", err, errBlankName)
+	}
+}
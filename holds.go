@@ -0,0 +1,63 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/storage/v1"
+)
+
+// ErrObjectRetained is returned when deleting an object that is
+// under a hold or hasn't yet met its bucket's retention policy.
+var ErrObjectRetained = errors.New("object is retained")
+
+// SetObjectHold places or with on unset releases a hold on the object,
+// the temporary hold if temporary is set otherwise the event-based hold.
+// While held the object can't be deleted or replaced.
+func (c *Client) SetObjectHold(ctx context.Context, bucket, object string, temporary, on bool) (*storage.Object, error) {
+	if c.public {
+		return nil, errPublicClient
+	}
+	if bucket == "" {
+		return nil, errEmptyBucket
+	}
+	if object == "" {
+		return nil, errEmptyName
+	}
+
+	update := new(storage.Object)
+	if temporary {
+		update.TemporaryHold = on
+		update.ForceSendFields = []string{"TemporaryHold"}
+	} else {
+		update.EventBasedHold = on
+		update.ForceSendFields = []string{"EventBasedHold"}
+	}
+	return c.objectsService().Patch(bucket, object, update).Context(ctx).Do()
+}
+
+// isRetentionBlocked reports whether the error is the API refusing a
+// change because of a hold or of the bucket's retention policy.
+func isRetentionBlocked(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range gerr.Errors {
+		if item.Reason == "retentionPolicyNotMet" {
+			return true
+		}
+	}
+	return strings.Contains(gerr.Message, " hold")
+}
+
+func retentionBlockedError(object string, err error) error {
+	if isRetentionBlocked(err) {
+		return fmt.Errorf("%w: %q: %v", ErrObjectRetained, object, err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestObjectHolds(t *testing.T) {
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+
+	ctx := context.Background()
+	_, err := client.UploadWithParams(ctx, &UploadParams{
+		Project:       "sample",
+		Bucket:        "audit",
+		Name:          "2017-06.log",
+		TemporaryHold: true,
+		Reader:        func() io.Reader { return strings.NewReader("audit") },
+	})
+	if err != nil {
+		t.Fatalf("UploadWithParams: %v", err)
+	}
+	if obj := fs.objects["audit/2017-06.log"]; !obj.TemporaryHold || obj.EventBasedHold {
+		t.Fatalf("stored object: got temporary hold %v event-based hold %v", obj.TemporaryHold, obj.EventBasedHold)
+	}
+
+	err = client.DeleteObjectGeneration(ctx, "audit", "2017-06.log", 1)
+	if !errors.Is(err, ErrObjectRetained) {
+		t.Errorf("deleting held object: got err %v want %v", err, ErrObjectRetained)
+	}
+
+	obj, err := client.SetObjectHold(ctx, "audit", "2017-06.log", false, true)
+	if err != nil {
+		t.Fatalf("placing event-based hold: %v", err)
+	}
+	if !obj.TemporaryHold || !obj.EventBasedHold {
+		t.Errorf("after placing event-based hold: got temporary hold %v event-based hold %v", obj.TemporaryHold, obj.EventBasedHold)
+	}
+	for _, temporary := range []bool{true, false} {
+		if _, err := client.SetObjectHold(ctx, "audit", "2017-06.log", temporary, false); err != nil {
+			t.Fatalf("releasing hold (temporary=%v): %v", temporary, err)
+		}
+	}
+	if obj := fs.objects["audit/2017-06.log"]; obj.TemporaryHold || obj.EventBasedHold {
+		t.Errorf("after releasing: got temporary hold %v event-based hold %v", obj.TemporaryHold, obj.EventBasedHold)
+	}
+
+	if err := client.DeleteObjectGeneration(ctx, "audit", "2017-06.log", 1); err != nil {
+		t.Errorf("deleting released object: %v", err)
+	}
+	if _, ok := fs.objects["audit/2017-06.log"]; ok {
+		t.Errorf("expected the released object to be deleted")
+	}
+}
@@ -0,0 +1,62 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"google.golang.org/api/compute/v1"
+)
+
+var errBlankImageFamily = errors.New("expecting a non-blank image family")
+
+func (c *Client) imagesService() *compute.ImagesService {
+	return compute.NewImagesService(c.computeSrvc)
+}
+
+// ResolveImageFamily returns the latest image, that isn't deprecated,
+// of the image family in the project e.g the family "debian-11" of the
+// project "debian-cloud".
+func (c *Client) ResolveImageFamily(ctx context.Context, project, family string) (*compute.Image, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	if family == "" {
+		return nil, errBlankImageFamily
+	}
+	image, err := c.imagesService().GetFromFamily(project, family).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if image.Deprecated != nil && image.Deprecated.State != "" && image.Deprecated.State != "ACTIVE" {
+		return nil, fmt.Errorf("image family %q of project %q has no image that isn't deprecated, its latest %q is %s",
+			family, project, image.Name, image.Deprecated.State)
+	}
+	return image, nil
+}
+
+// imageFamilyRegexp matches image family URLs, capturing the project
+// and the family e.g "projects/debian-cloud/global/images/family/debian-11".
+var imageFamilyRegexp = regexp.MustCompile(`(?:^|/)projects/([^/]+)/global/images/family/([^/]+)$`)
+
+// resolveBootImage replaces the image family that the request's
+// BootImage refers to, if any, by the family's latest image in the
+// boot disk of the instance built from the request.
+func (c *Client) resolveBootImage(ctx context.Context, ireq *InstanceRequest, instance *compute.Instance) error {
+	matches := imageFamilyRegexp.FindStringSubmatch(ireq.BootImage)
+	if matches == nil {
+		return nil
+	}
+	image, err := c.ResolveImageFamily(ctx, matches[1], matches[2])
+	if err != nil {
+		return err
+	}
+	for _, disk := range instance.Disks {
+		if disk.Boot && disk.InitializeParams != nil {
+			disk.InitializeParams.SourceImage = image.SelfLink
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+const debian11SelfLink = "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/debian-11-bullseye-v20231010"
+
+func serveImageFamilies(w http.ResponseWriter, req *http.Request) bool {
+	switch req.URL.Path {
+	case "/compute/v1/projects/debian-cloud/global/images/family/debian-11":
+		writeJSON(w, &compute.Image{Name: "debian-11-bullseye-v20231010", Family: "debian-11", SelfLink: debian11SelfLink})
+	case "/compute/v1/projects/debian-cloud/global/images/family/debian-9":
+		writeJSON(w, &compute.Image{
+			Name:       "debian-9-stretch-v20220621",
+			Family:     "debian-9",
+			Deprecated: &compute.DeprecationStatus{State: "OBSOLETE"},
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+func TestResolveImageFamily(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if !serveImageFamilies(w, req) {
+			writeNotFound(w)
+		}
+	})
+
+	ctx := context.Background()
+	image, err := client.ResolveImageFamily(ctx, "debian-cloud", "debian-11")
+	if err != nil {
+		t.Fatalf("ResolveImageFamily: %v", err)
+	}
+	if image.SelfLink != debian11SelfLink {
+		t.Errorf("self link: got %q want %q", image.SelfLink, debian11SelfLink)
+	}
+
+	if _, err := client.ResolveImageFamily(ctx, "debian-cloud", "debian-9"); err == nil || !strings.Contains(err.Error(), "OBSOLETE") {
+		t.Errorf("deprecated family: got err %v, want one mentioning it is obsolete", err)
+	}
+	if _, err := client.ResolveImageFamily(ctx, "debian-cloud", ""); err != errBlankImageFamily {
+		t.Errorf("blank family: got err %v want %v", err, errBlankImageFamily)
+	}
+}
+
+func TestCreateInstancesResolvesBootImageFamily(t *testing.T) {
+	var bootImage string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if serveImageFamilies(w, req) {
+			return
+		}
+		if req.Method == "GET" && req.URL.Path == "/compute/v1/projects/sample/zones/us-central1-c" {
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+			return
+		}
+		if route := req.Method + " " + req.URL.Path; route != "POST /compute/v1/projects/sample/zones/us-central1-c/instances" {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		instance := new(compute.Instance)
+		readJSON(t, req, instance)
+		bootImage = instance.Disks[0].InitializeParams.SourceImage
+		writeJSON(w, &compute.Operation{Name: "op-" + instance.Name})
+	})
+
+	results, err := client.CreateInstances(context.Background(), []*InstanceRequest{{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		BootImage:        "projects/debian-cloud/global/images/family/debian-11",
+	}})
+	if err != nil {
+		t.Fatalf("CreateInstances: %v", err)
+	}
+	if err := results[0].Err; err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if bootImage != debian11SelfLink {
+		t.Errorf("boot image: got %q want %q", bootImage, debian11SelfLink)
+	}
+	if got := BasicAttachedDisk.InitializeParams.SourceImage; got != "projects/debian-cloud/global/images/family/debian-8" {
+		t.Errorf("BasicAttachedDisk was modified, its source image is now %q", got)
+	}
+}
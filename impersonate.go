@@ -0,0 +1,45 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"golang.org/x/oauth2"
+
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// serviceAccountRegexp matches service account emails such as
+// "deployer@project.iam.gserviceaccount.com".
+var serviceAccountRegexp = regexp.MustCompile(`^[a-z0-9][-a-z0-9]*@[a-z0-9][-a-z0-9.]*\.gserviceaccount\.com$`)
+
+// impersonatedTokenSource creates the token source of the impersonated
+// credentials and is only a variable so that tests can replace it.
+var impersonatedTokenSource = func(ctx context.Context, config impersonate.CredentialsConfig, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+	return impersonate.CredentialsTokenSource(ctx, config, opts...)
+}
+
+// NewWithImpersonation creates a client that acts as the service account
+// targetServiceAccount, using short-lived credentials that the Application
+// Default Credentials obtain by impersonating it. The Application Default
+// Credentials must be granted roles/iam.serviceAccountTokenCreator on it.
+func NewWithImpersonation(ctx context.Context, targetServiceAccount string, scopes ...string) (*Client, error) {
+	if !serviceAccountRegexp.MatchString(targetServiceAccount) {
+		return nil, fmt.Errorf("%q is not a service account email, expecting one such as %q",
+			targetServiceAccount, "deployer@project.iam.gserviceaccount.com")
+	}
+	if len(scopes) == 0 {
+		scopes = defaultGCEScopes[:]
+	}
+
+	ts, err := impersonatedTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: targetServiceAccount,
+		Scopes:          scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewWithHTTPClient(oauth2.NewClient(ctx, ts))
+}
@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+func TestNewWithImpersonation(t *testing.T) {
+	defer func(saved func(context.Context, impersonate.CredentialsConfig, ...option.ClientOption) (oauth2.TokenSource, error)) {
+		impersonatedTokenSource = saved
+	}(impersonatedTokenSource)
+
+	var config impersonate.CredentialsConfig
+	impersonatedTokenSource = func(ctx context.Context, cc impersonate.CredentialsConfig, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+		config = cc
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "impersonated-token"}), nil
+	}
+
+	var authorization string
+	base := &http.Client{Transport: &handlerTransport{h: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		writeJSON(w, &compute.Instance{Name: "frontend"})
+	})}}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, base)
+
+	const deployer = "deployer@sample.iam.gserviceaccount.com"
+	client, err := NewWithImpersonation(ctx, deployer)
+	if err != nil {
+		t.Fatalf("NewWithImpersonation: %v", err)
+	}
+	if config.TargetPrincipal != deployer {
+		t.Errorf("target principal: got %q want %q", config.TargetPrincipal, deployer)
+	}
+	if !reflect.DeepEqual(config.Scopes, defaultGCEScopes) {
+		t.Errorf("scopes: got %q want %q", config.Scopes, defaultGCEScopes)
+	}
+
+	_, err = client.FindInstance(context.Background(), &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "frontend"})
+	if err != nil {
+		t.Fatalf("FindInstance: %v", err)
+	}
+	if want := "Bearer impersonated-token"; authorization != want {
+		t.Errorf("authorization: got %q want %q", authorization, want)
+	}
+}
+
+func TestNewWithImpersonationValidatesServiceAccount(t *testing.T) {
+	for i, email := range []string{"", "deployer", "deployer@example.com", "@sample.iam.gserviceaccount.com"} {
+		if _, err := NewWithImpersonation(context.Background(), email); err == nil {
+			t.Errorf("#%d: %q: expected an error", i, email)
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,15 +18,87 @@ import (
 
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/storage/v1"
 )
 
 var defaultGCEScopes = []string{storage.CloudPlatformScope}
 
+// DefaultResultsPerPage is the number of results requested per page by
+// the listers when a request leaves ResultsPerPage unset. It must be
+// within 1 and 500, the range that the Google Cloud APIs accept.
+var DefaultResultsPerPage int64 = 40
+
 type Client struct {
 	computeSrvc *compute.Service
 	dnsSrvc     *dns.Service
 	storageSrvc *storage.Service
+
+	// OperationPollInterval is how long waiters such as
+	// WaitForGroupStable wait before their second poll, backing
+	// off to up to 4 times that between later polls.
+	// Operation waiters, such as WaitForZoneOperation, wait on
+	// the server at most that often, or if the server can't
+	// wait, poll likewise.
+	// If unset, defaultOperationPollInterval is used.
+	OperationPollInterval time.Duration
+
+	// OperationPollTimeout is how long waiters poll for before
+	// giving up, when the caller doesn't provide a timeout.
+	// If unset, defaultOperationPollTimeout is used.
+	OperationPollTimeout time.Duration
+
+	// RetryPolicy if set decides which errors of the calls that are
+	// retried, such as the listers' page fetches, are worth retrying.
+	// If unset, DefaultRetryPolicy is used.
+	RetryPolicy func(err error) bool
+
+	// MaxRetryAttempts is how many times the calls that are retried
+	// are attempted in all, backing off exponentially between
+	// attempts, before their error is returned or set on their page.
+	// If unset defaultMaxRetryAttempts is used, and 1 disables retries.
+	MaxRetryAttempts int
+
+	// DefaultProject if set is the project of the requests that don't
+	// set one, so that callers working in a single project can omit it.
+	// A request's own project always takes precedence.
+	DefaultProject string
+
+	// LookupCacheTTL if set is how long zones, accelerator types and
+	// machine types, which are looked up to validate instances before
+	// creating them, are cached for, cutting the API calls when creating
+	// many instances in the same zones. Zone statuses can then be that old.
+	LookupCacheTTL time.Duration
+
+	nameServersMu sync.Mutex
+	// nameServers caches the name servers of managed
+	// zones, keyed by their project and zone.
+	nameServers map[string][]string
+
+	lookups lookupCache
+
+	// public is set for clients without credentials,
+	// which can only read from public buckets.
+	public bool
+}
+
+const (
+	defaultOperationPollInterval = 2 * time.Second
+	defaultOperationPollTimeout  = 5 * time.Minute
+)
+
+func (c *Client) operationPollInterval() time.Duration {
+	if c.OperationPollInterval > 0 {
+		return c.OperationPollInterval
+	}
+	return defaultOperationPollInterval
+}
+
+func (c *Client) operationPollTimeout() time.Duration {
+	if c.OperationPollTimeout > 0 {
+		return c.OperationPollTimeout
+	}
+	return defaultOperationPollTimeout
 }
 
 func NewWithHTTPClient(hc *http.Client) (*Client, error) {
@@ -48,17 +123,36 @@ func NewWithHTTPClient(hc *http.Client) (*Client, error) {
 	return c, nil
 }
 
+// defaultHTTPClient creates the HTTP client of the Application Default
+// Credentials and is only a variable so that tests can replace it.
+var defaultHTTPClient = google.DefaultClient
+
+// NewDefaultClient creates a client from the Application Default Credentials,
+// with scopes replacing the default scopes if any are passed in.
+// Use NewDefaultClientWithAdditionalScopes to keep the default scopes.
 func NewDefaultClient(ctx context.Context, scopes ...string) (*Client, error) {
 	if len(scopes) == 0 {
 		scopes = defaultGCEScopes[:]
 	}
-	httpClient, err := google.DefaultClient(ctx, scopes...)
+	httpClient, err := defaultHTTPClient(ctx, scopes...)
 	if err != nil {
 		return nil, err
 	}
 	return NewWithHTTPClient(httpClient)
 }
 
+// NewDefaultClientWithAdditionalScopes is like NewDefaultClient
+// except that scopes are added to the default scopes, rather
+// than replacing them and losing access to the default APIs.
+func NewDefaultClientWithAdditionalScopes(ctx context.Context, scopes ...string) (*Client, error) {
+	return NewDefaultClient(ctx, withDefaultScopes(scopes...)...)
+}
+
+// withDefaultScopes returns the default scopes followed by the other scopes.
+func withDefaultScopes(scopes ...string) []string {
+	return dedup(append(defaultGCEScopes[:len(defaultGCEScopes):len(defaultGCEScopes)], scopes...)...)
+}
+
 func (c *Client) zonesService() *compute.ZonesService {
 	return compute.NewZonesService(c.computeSrvc)
 }
@@ -71,6 +165,10 @@ type ZonePage struct {
 	Err        error
 	PageNumber int64           `json:"page_number"`
 	Zones      []*compute.Zone `json:"zones,omitempty"`
+
+	// Warning if set is why the page is incomplete, such
+	// as with ZoneRequest.PartialSuccess set.
+	Warning *compute.ZoneListWarning `json:"warning,omitempty"`
 }
 
 type ZoneRequest struct {
@@ -79,8 +177,24 @@ type ZoneRequest struct {
 	OrderBy string `json:"order_by"`
 	Filter  string `json:"filter"`
 
+	// Region if set e.g "us-central1" restricts listing to the zones
+	// of that region. It is combined with Filter, so only the zones
+	// matching both are listed.
+	Region string `json:"region,omitempty"`
+
 	MaxPages       int64 `json:"max_pages"`
 	ResultsPerPage int64 `json:"results_per_page"`
+
+	// PartialSuccess when set returns the zones that could be listed
+	// rather than failing entirely, with a warning on the page.
+	PartialSuccess bool `json:"partial_success,omitempty"`
+
+	// MaxBufferedPages is the most pages fetched ahead of the consumer,
+	// if unset defaultMaxBufferedPages is used.
+	MaxBufferedPages int `json:"max_buffered_pages,omitempty"`
+
+	// ThrottleMillis is the wait between pages, 350ms if unset, none if negative.
+	ThrottleMillis int64 `json:"throttle_millis,omitempty"`
 }
 
 type ZonePagesResponse struct {
@@ -92,6 +206,14 @@ type InstancePage struct {
 	Err        error
 	PageNumber int64               `json:"page_number"`
 	Instances  []*compute.Instance `json:"instances,omitempty"`
+
+	// Zone is the zone of the instances, only set for
+	// the pages of ListInstancesInAllZones.
+	Zone string `json:"zone,omitempty"`
+
+	// Warning if set is why the page is incomplete, such
+	// as with InstancesRequest.PartialSuccess set.
+	Warning *compute.InstanceListWarning `json:"warning,omitempty"`
 }
 
 type InstancePagesResponse struct {
@@ -99,18 +221,92 @@ type InstancePagesResponse struct {
 	Cancel func() error
 }
 
-func makeCanceler() (<-chan bool, func() error) {
+// makeCanceler returns a context derived from ctx, along with a channel
+// that is closed and a cancel function that closes it, which also cancels
+// the context so that the listing's in-flight request is aborted.
+func makeCanceler(ctx context.Context) (context.Context, <-chan bool, func() error) {
 	var cancelOnce sync.Once
+	ctx, cancelCtx := context.WithCancel(ctx)
 	cancelChan := make(chan bool, 1)
 	cancel := func() error {
 		var err error
 		cancelOnce.Do(func() {
 			close(cancelChan)
+			cancelCtx()
 		})
 		return err
 	}
 
-	return cancelChan, cancel
+	return ctx, cancelChan, cancel
+}
+
+// fetchPages is the loop that listers fetch their pages in. It calls
+// fetch, retrying it, with the token of each page from the first on,
+// then send with the page's number, until there are no more pages or
+// more than maxPages were fetched, waiting throttle, jittered, between
+// pages. send sends the page that fetch last got, or the error that
+// fetch failed with, reporting whether the consumer is still there.
+// Errors are only sent when the listing wasn't cancelled, since those
+// are just the aborted requests.
+func (c *Client) fetchPages(ctx context.Context, cancelChan <-chan bool, maxPages int64, throttle time.Duration,
+	fetch func(pageToken string) (nextPageToken string, err error), send func(pageNumber int64, err error) bool) {
+
+	pageToken := ""
+	for pageNumber := int64(0); ; pageNumber++ {
+		var nextPageToken string
+		err := c.retry(ctx, func() (err error) {
+			nextPageToken, err = fetch(pageToken)
+			return err
+		})
+		if err != nil {
+			select {
+			case <-cancelChan:
+			default:
+				send(pageNumber, err)
+			}
+			return
+		}
+		if !send(pageNumber, nil) {
+			return
+		}
+		if maxPages > 0 && pageNumber+1 > maxPages {
+			return
+		}
+
+		select {
+		case <-cancelChan:
+			return
+		case <-time.After(jitter(throttle)):
+		}
+
+		if nextPageToken == "" {
+			// No more results left
+			return
+		}
+		pageToken = nextPageToken
+	}
+}
+
+// throttleJitter is the fraction, of 20%, by which throttling
+// durations are randomly shortened or lengthened so that concurrent
+// clients don't synchronize and then call the APIs all at once.
+const throttleJitter = 0.2
+
+var (
+	jitterMu sync.Mutex
+
+	// jitterRand is the source of the jitter,
+	// replaced by tests for deterministic durations.
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns d randomly adjusted by up to throttleJitter of it.
+func jitter(d time.Duration) time.Duration {
+	jitterMu.Lock()
+	f := jitterRand.Float64()
+	jitterMu.Unlock()
+
+	return time.Duration(float64(d) * (1 - throttleJitter + 2*throttleJitter*f))
 }
 
 var (
@@ -123,8 +319,73 @@ var (
 	errUnimplemented   = errors.New("unimplemented")
 
 	errEmptyNetworkInterface = errors.New("expecting a non-blank network interface")
+
+	errMultipleServiceAccounts = errors.New("expecting at most one service account per instance")
+
+	errDefaultResultsPerPageOutOfRange = errors.New("expecting DefaultResultsPerPage to be within 1 and 500")
 )
 
+var zoneRegexp = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+-[a-z]$`)
+
+// IsZone reports whether s looks like a zone e.g. "us-central1-c"
+// as opposed to a region like "us-central1".
+func IsZone(s string) bool {
+	return zoneRegexp.MatchString(s)
+}
+
+func validateZone(zone string) error {
+	if !IsZone(zone) {
+		return fmt.Errorf("%q is not a zone, expecting a zone such as %q", zone, "us-central1-c")
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
+
+// defaultMaxBufferedPages is how many pages listers fetch ahead of their
+// consumer by default, enough to overlap fetching with consuming while
+// holding a single page in memory. Buffering more pages lets a bursty
+// consumer go faster at the cost of memory, while once the buffer is
+// full the lister waits for a slow consumer to catch up.
+const defaultMaxBufferedPages = 1
+
+func maxBufferedPagesOrDefault(maxBufferedPages int) int {
+	if maxBufferedPages > 0 {
+		return maxBufferedPages
+	}
+	return defaultMaxBufferedPages
+}
+
+// defaultPageThrottle is how long listers wait between pages by default.
+const defaultPageThrottle = 350 * time.Millisecond
+
+// pageThrottleOrDefault returns how long a lister waits between pages to
+// stay within quota, jittered, for a request's ThrottleMillis: that many
+// milliseconds, 350ms if unset, or no wait at all if negative.
+func pageThrottleOrDefault(throttleMillis int64) time.Duration {
+	switch {
+	case throttleMillis < 0:
+		return 0
+	case throttleMillis == 0:
+		return defaultPageThrottle
+	default:
+		return time.Duration(throttleMillis) * time.Millisecond
+	}
+}
+
+func resultsPerPageOrDefault(resultsPerPage int64) (int64, error) {
+	if resultsPerPage > 0 {
+		return resultsPerPage, nil
+	}
+	if DefaultResultsPerPage < 1 || DefaultResultsPerPage > 500 {
+		return 0, errDefaultResultsPerPageOutOfRange
+	}
+	return DefaultResultsPerPage, nil
+}
+
 func (zreq *ZoneRequest) Validate() error {
 	if zreq == nil || zreq.Project == "" {
 		return errBlankProject
@@ -132,6 +393,34 @@ func (zreq *ZoneRequest) Validate() error {
 	return nil
 }
 
+// filter returns Filter along with the clause of the region.
+func (zreq *ZoneRequest) filter() string {
+	if zreq.Region == "" {
+		return zreq.Filter
+	}
+	regionClause := fmt.Sprintf("(region = %q)", SelfLink(zreq.Project, "regions", zreq.Region))
+	if zreq.Filter == "" {
+		return regionClause
+	}
+	return "(" + zreq.Filter + ") AND " + regionClause
+}
+
+// inRegion returns the zones that are in the region of the request,
+// in case any slipped through the filter, whose region URL could be
+// of a different host or API version than the zones'.
+func (zreq *ZoneRequest) inRegion(zones []*compute.Zone) []*compute.Zone {
+	if zreq.Region == "" {
+		return zones
+	}
+	var matches []*compute.Zone
+	for _, zone := range zones {
+		if lastURLSegment(zone.Region) == zreq.Region {
+			matches = append(matches, zone)
+		}
+	}
+	return matches
+}
+
 type InstancesRequest struct {
 	Project string `json:"project"`
 
@@ -142,6 +431,28 @@ type InstancesRequest struct {
 	ResultsPerPage int64 `json:"results_per_page"`
 
 	Zone string `json:"zone"`
+
+	// CreatedBefore and CreatedAfter if set restrict listing to the
+	// instances created before, or after, that time. They are combined
+	// with Filter, so only the instances matching all of them are listed.
+	CreatedBefore time.Time `json:"created_before,omitempty"`
+	CreatedAfter  time.Time `json:"created_after,omitempty"`
+
+	// Labels if set restricts listing to the instances that have all
+	// of these labels. Like the creation time window, they are
+	// combined with Filter.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// PartialSuccess when set returns the instances that could be listed
+	// rather than failing entirely, with a warning on the page.
+	PartialSuccess bool `json:"partial_success,omitempty"`
+
+	// MaxBufferedPages is the most pages fetched ahead of the consumer,
+	// if unset defaultMaxBufferedPages is used.
+	MaxBufferedPages int `json:"max_buffered_pages,omitempty"`
+
+	// ThrottleMillis is the wait between pages, 350ms if unset, none if negative.
+	ThrottleMillis int64 `json:"throttle_millis,omitempty"`
 }
 
 func (ireq *InstancesRequest) Validate() error {
@@ -151,79 +462,92 @@ func (ireq *InstancesRequest) Validate() error {
 	if ireq.Project == "" {
 		return errBlankProject
 	}
-	return nil
+	if err := validateLabels(ireq.Labels); err != nil {
+		return err
+	}
+	return validateZone(ireq.Zone)
+}
+
+// filter returns Filter along with the clauses of the creation
+// time window and of the labels.
+func (ireq *InstancesRequest) filter() string {
+	var clauses []string
+	if ireq.Filter != "" {
+		clauses = append(clauses, "("+ireq.Filter+")")
+	}
+	if !ireq.CreatedAfter.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("(creationTimestamp > %q)", ireq.CreatedAfter.UTC().Format(time.RFC3339)))
+	}
+	if !ireq.CreatedBefore.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("(creationTimestamp < %q)", ireq.CreatedBefore.UTC().Format(time.RFC3339)))
+	}
+	keys := make([]string, 0, len(ireq.Labels))
+	for key := range ireq.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		clauses = append(clauses, fmt.Sprintf("(labels.%s = %q)", key, ireq.Labels[key]))
+	}
+	if len(clauses) == 1 && ireq.Filter != "" {
+		return ireq.Filter
+	}
+	return strings.Join(clauses, " AND ")
 }
 
 func (c *Client) ListInstances(ctx context.Context, req *InstancesRequest) (*InstancePagesResponse, error) {
+	req = inProject(req, c.DefaultProject)
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
-	maxPageNumber := req.MaxPages
-	pageExceedsMax := func(pageNumber int64) bool {
-		if maxPageNumber <= 0 {
-			return false
-		}
-		return pageNumber > maxPageNumber
-	}
-
-	maxResultsPerPage := int64(40)
-	if req.ResultsPerPage > 0 {
-		maxResultsPerPage = req.ResultsPerPage
+	maxResultsPerPage, err := resultsPerPageOrDefault(req.ResultsPerPage)
+	if err != nil {
+		return nil, err
 	}
 
-	cancelChan, cancelFn := makeCanceler()
-	pagesChan := make(chan *InstancePage)
+	ctx, cancelChan, cancelFn := makeCanceler(ctx)
+	pagesChan := make(chan *InstancePage, maxBufferedPagesOrDefault(req.MaxBufferedPages))
 	go func() {
 		defer close(pagesChan)
+		defer cancelFn()
 
 		ilc := c.instancesService().List(req.Project, req.Zone).Context(ctx)
 		ilc.MaxResults(maxResultsPerPage)
-		if req.Filter != "" {
-			ilc.Filter(req.Filter)
+		if filter := req.filter(); filter != "" {
+			ilc.Filter(filter)
 		}
 
 		if req.OrderBy != "" {
 			ilc.OrderBy(req.OrderBy)
 		}
+		if req.PartialSuccess {
+			ilc.ReturnPartialSuccess(true)
+		}
 
-		pageToken := ""
-		pageNumber := int64(0)
-		throttleDuration := time.Duration(350 * time.Millisecond)
-
-		for {
-			ilc.PageToken(pageToken)
-			ipage := new(InstancePage)
-			ipage.PageNumber = pageNumber
-
-			ilr, err := ilc.Do()
+		var ilr *compute.InstanceList
+		fetch := func(pageToken string) (_ string, err error) {
+			ilr, err = ilc.PageToken(pageToken).Do()
 			if err != nil {
-				ipage.Err = err
-				pagesChan <- ipage
-				return
+				return "", err
 			}
-
-			ipage.Instances = ilr.Items
-			pagesChan <- ipage
-
-			pageNumber += 1
-			if pageExceedsMax(pageNumber) {
-				return
+			return ilr.NextPageToken, nil
+		}
+		send := func(pageNumber int64, err error) bool {
+			ipage := &InstancePage{PageNumber: pageNumber, Err: err}
+			if err == nil {
+				ipage.Instances = ilr.Items
+				ipage.Warning = ilr.Warning
 			}
-
-			pageToken := ilr.NextPageToken
-
 			select {
+			case pagesChan <- ipage:
+				return true
 			case <-cancelChan:
-				return
-			case <-time.After(throttleDuration):
-			}
-
-			if pageToken == "" {
-				// No more results left
-				break
+			case <-ctx.Done():
 			}
+			return false
 		}
+		c.fetchPages(ctx, cancelChan, req.MaxPages, pageThrottleOrDefault(req.ThrottleMillis), fetch, send)
 	}()
 
 	ires := &InstancePagesResponse{
@@ -235,75 +559,58 @@ func (c *Client) ListInstances(ctx context.Context, req *InstancesRequest) (*Ins
 }
 
 func (c *Client) ListZones(ctx context.Context, req *ZoneRequest) (*ZonePagesResponse, error) {
+	req = inProject(req, c.DefaultProject)
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
-	maxPageNumber := req.MaxPages
-	pageExceedsMax := func(pageNumber int64) bool {
-		if maxPageNumber <= 0 {
-			return false
-		}
-		return pageNumber > maxPageNumber
-	}
-
-	maxResultsPerPage := int64(40)
-	if req.ResultsPerPage > 0 {
-		maxResultsPerPage = req.ResultsPerPage
+	maxResultsPerPage, err := resultsPerPageOrDefault(req.ResultsPerPage)
+	if err != nil {
+		return nil, err
 	}
 
-	cancelChan, cancelFn := makeCanceler()
-	pagesChan := make(chan *ZonePage)
+	ctx, cancelChan, cancelFn := makeCanceler(ctx)
+	pagesChan := make(chan *ZonePage, maxBufferedPagesOrDefault(req.MaxBufferedPages))
 	go func() {
 		defer close(pagesChan)
+		defer cancelFn()
 
 		zlc := c.zonesService().List(req.Project).Context(ctx)
 		zlc.MaxResults(maxResultsPerPage)
-		if req.Filter != "" {
-			zlc.Filter(req.Filter)
+		if filter := req.filter(); filter != "" {
+			zlc.Filter(filter)
 		}
 
 		if req.OrderBy != "" {
 			zlc.OrderBy(req.OrderBy)
 		}
+		if req.PartialSuccess {
+			zlc.ReturnPartialSuccess(true)
+		}
 
-		pageToken := ""
-		pageNumber := int64(0)
-		throttleDuration := time.Duration(350 * time.Millisecond)
-
-		for {
-			zlc.PageToken(pageToken)
-			zpage := new(ZonePage)
-			zpage.PageNumber = pageNumber
-
-			zlr, err := zlc.Do()
+		var zlr *compute.ZoneList
+		fetch := func(pageToken string) (_ string, err error) {
+			zlr, err = zlc.PageToken(pageToken).Do()
 			if err != nil {
-				zpage.Err = err
-				pagesChan <- zpage
-				return
+				return "", err
 			}
-
-			zpage.Zones = zlr.Items
-			pagesChan <- zpage
-
-			pageNumber += 1
-			if pageExceedsMax(pageNumber) {
-				return
+			return zlr.NextPageToken, nil
+		}
+		send := func(pageNumber int64, err error) bool {
+			zpage := &ZonePage{PageNumber: pageNumber, Err: err}
+			if err == nil {
+				zpage.Zones = req.inRegion(zlr.Items)
+				zpage.Warning = zlr.Warning
 			}
-
-			pageToken := zlr.NextPageToken
-
 			select {
+			case pagesChan <- zpage:
+				return true
 			case <-cancelChan:
-				return
-			case <-time.After(throttleDuration):
-			}
-
-			if pageToken == "" {
-				// No more results left
-				break
+			case <-ctx.Done():
 			}
+			return false
 		}
+		c.fetchPages(ctx, cancelChan, req.MaxPages, pageThrottleOrDefault(req.ThrottleMillis), fetch, send)
 	}()
 
 	zres := &ZonePagesResponse{
@@ -330,6 +637,30 @@ type InstanceRequest struct {
 
 	Disks []*compute.AttachedDisk `json:"attached_disks,omitempty"`
 
+	// DataDisks are persistent disks to create along with the
+	// instance and attach to it after the boot disk.
+	DataDisks []*DataDiskSpec `json:"data_disks,omitempty"`
+
+	// ResourcePolicies are the resource policies, such as placement
+	// policies, applied to the instance. Short names are expanded
+	// to the policies of that name in the instance's region.
+	ResourcePolicies []string `json:"resource_policies,omitempty"`
+
+	// BootDiskResourcePolicies are the resource policies, such as
+	// snapshot schedules, applied to the boot disk. Short names are
+	// expanded like those of ResourcePolicies.
+	BootDiskResourcePolicies []string `json:"boot_disk_resource_policies,omitempty"`
+
+	// BootImage if set is the image that the boot disk is created
+	// from e.g "projects/debian-cloud/global/images/debian-11-bullseye-v20231010".
+	// An image family such as "projects/debian-cloud/global/images/family/debian-11"
+	// is resolved to its latest image when the instance is created.
+	BootImage string `json:"boot_image,omitempty"`
+
+	// BootDiskKMSKeyName if set is the Cloud KMS key that the boot
+	// disk is encrypted with, rather than a Google-managed key.
+	BootDiskKMSKeyName string `json:"boot_disk_kms_key_name,omitempty"`
+
 	// NetworkInterface specifies how this interface is configured to interact with
 	// other network services, such as connecting to the internet.
 	// Description obtained from:
@@ -342,6 +673,31 @@ type InstanceRequest struct {
 	// https://godoc.org/google.golang.org/api/compute/v1#Instance.Metadata
 	Metadata *compute.Metadata `json:"metadata"`
 
+	// StartupScript if set is the script that the instance runs on
+	// every boot. It is passed as the "startup-script" metadata key.
+	StartupScript string `json:"startup_script,omitempty"`
+
+	// SSHKeys if set are the public keys, each in the form
+	// "USERNAME:KEY", that can SSH into the instance. They are
+	// passed as the "ssh-keys" metadata key.
+	SSHKeys []string `json:"ssh_keys,omitempty"`
+
+	// EnableOSLogin when set makes SSH access to the instance go
+	// through OS Login rather than metadata keys, by setting the
+	// "enable-oslogin" metadata key, so it can't be used with SSHKeys.
+	EnableOSLogin bool `json:"enable_oslogin,omitempty"`
+
+	// PreferCallerMetadata controls what happens when Metadata
+	// explicitly sets a key that is also generated from fields
+	// such as StartupScript: by default it is an error but if set,
+	// the explicitly set value is kept.
+	PreferCallerMetadata bool `json:"prefer_caller_metadata,omitempty"`
+
+	// MetadataFromFiles maps metadata keys to the local files
+	// that their values are read from when creating the instance,
+	// like gcloud's --metadata-from-file.
+	MetadataFromFiles map[string]string `json:"metadata_from_files,omitempty"`
+
 	// ServiceAccounts: A list of service accounts, with their specified
 	// scopes, authorized for this instance. Only one service account per VM
 	// instance is supported.
@@ -353,6 +709,11 @@ type InstanceRequest struct {
 	// https://godoc.org/google.golang.org/api/compute/v1#Instance.ServiceAccounts
 	ServiceAccounts []*compute.ServiceAccount `json:"service_accounts,omitempty"`
 
+	// UseDefaultServiceAccount when set and no ServiceAccounts are
+	// provided, attaches the project's default compute service account
+	// with the same scopes that gcloud grants it by default.
+	UseDefaultServiceAccount bool `json:"use_default_service_account,omitempty"`
+
 	// NullFields is a list of field names (e.g. "CanIpForward") to include
 	// in API requests with the JSON null value. By default, fields with
 	// empty values are omitted from API requests. However, any field with
@@ -363,23 +724,167 @@ type InstanceRequest struct {
 	// https://godoc.org/google.golang.org/api/compute/v1#Instance.NullFields
 	NullFields []string `json:"null_fields"`
 
+	// ReservationAffinity specifies the reservations that this instance
+	// can consume from. Use SpecificReservationAffinity to target a
+	// reservation made with CreateReservation.
+	// Description obtained from:
+	// https://godoc.org/google.golang.org/api/compute/v1#Instance.ReservationAffinity
+	ReservationAffinity *compute.ReservationAffinity `json:"reservation_affinity,omitempty"`
+
 	// BlockUntilCompletion when set signifies that the instance request
 	// should wait until full completion of creation of an instance.
 	BlockUntilCompletion bool `json:"block_until_completion"`
+
+	// Labels are the labels that the instance is created with.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Tags are the network tags of the instance e.g "http-server",
+	// which firewall rules and routes target. Unlike Labels, they
+	// aren't for organizing instances. Duplicates are dropped.
+	Tags []string `json:"tags,omitempty"`
+
+	// MinCPUPlatform if set is the oldest CPU platform that the instance
+	// can be scheduled on e.g "Intel Ice Lake". It must be one of the
+	// zone's AvailableCPUPlatforms.
+	MinCPUPlatform string `json:"min_cpu_platform,omitempty"`
+
+	// NetworkPerformanceTier if set is the egress bandwidth tier of the
+	// instance, either "DEFAULT" or "TIER_1" for higher bandwidth which
+	// only some machine families, such as N2 and C3, support.
+	NetworkPerformanceTier string `json:"network_performance_tier,omitempty"`
+
+	// AdvancedFeatures if set configures the CPUs of the instance,
+	// such as to disable simultaneous multithreading.
+	AdvancedFeatures *AdvancedFeatures `json:"advanced_features,omitempty"`
+
+	// SkipZoneStatusCheck when set creates the instance without first
+	// checking that its zone is UP, saving a lookup of the zone.
+	SkipZoneStatusCheck bool `json:"skip_zone_status_check,omitempty"`
+
+	// Accelerators if set are the accelerator cards, such as GPUs, to
+	// attach. Before creating the instance, its zone is checked to offer
+	// them unless SkipAcceleratorCheck is set. Such instances are
+	// terminated, rather than live migrated, for host maintenance.
+	Accelerators         []*Accelerator `json:"accelerators,omitempty"`
+	SkipAcceleratorCheck bool           `json:"skip_accelerator_check,omitempty"`
+
+	// ApplyProjectDefaultLabels when set creates the instance with the
+	// project's default labels, see ProjectDefaultLabels, along with
+	// Labels whose values win over the defaults of the same keys.
+	ApplyProjectDefaultLabels bool `json:"apply_project_default_labels,omitempty"`
+
+	// MaxRunDuration or TerminationTime, but not both, if set bound
+	// how long the instance runs before it is terminated, such as
+	// for ephemeral CI instances. TerminationAction is what is done
+	// to the instance then, "STOP" if unset or "DELETE".
+	MaxRunDuration    time.Duration `json:"max_run_duration,omitempty"`
+	TerminationTime   time.Time     `json:"termination_time,omitempty"`
+	TerminationAction string        `json:"termination_action,omitempty"`
+}
+
+// buildInstance validates the request and builds the instance
+// to create, reading the metadata values of MetadataFromFiles.
+func (ireq *InstanceRequest) buildInstance() (*compute.Instance, error) {
+	if err := ireq.validateForCreate(); err != nil {
+		return nil, err
+	}
+	fromFiles, err := ireq.readMetadataFromFiles()
+	if err != nil {
+		return nil, err
+	}
+	instance := ireq.toInstance()
+	if len(fromFiles) > 0 {
+		instance.Metadata = ireq.metadata(fromFiles...)
+	}
+	return instance, nil
 }
 
 func (ireq *InstanceRequest) toInstance() *compute.Instance {
+	return ireq.instance(true)
+}
+
+// instance returns the instance that the request describes. Without
+// selfLinks its machine, disk and accelerator types are bare names, as
+// instance templates and bulk inserts expect them.
+func (ireq *InstanceRequest) instance(selfLinks bool) *compute.Instance {
+	machineType := ireq.machineTypeOrDefault().name()
+	if selfLinks {
+		machineType = ireq.machineTypeOrDefault().selfLinkByZone(ireq.Project, ireq.Zone)
+	}
 	return &compute.Instance{
 		Name:  ireq.Name,
-		Disks: ireq.disksOrDefault(),
+		Disks: append(ireq.disks(), ireq.dataDisks(selfLinks)...),
 
-		Metadata:    ireq.Metadata,
+		Metadata:    ireq.metadata(),
 		Description: ireq.Description,
-		MachineType: ireq.machineTypeOrDefault().partialURLByZone(ireq.Zone),
+		MachineType: machineType,
 
-		ServiceAccounts: ireq.ServiceAccounts[:],
+		ServiceAccounts: ireq.serviceAccounts(),
 
 		NetworkInterfaces: []*compute.NetworkInterface{ireq.NetworkInterface},
+
+		ReservationAffinity: ireq.ReservationAffinity,
+		ResourcePolicies:    ireq.resourcePolicyURLs(ireq.ResourcePolicies),
+
+		Labels:         ireq.Labels,
+		Tags:           ireq.tags(),
+		MinCpuPlatform: ireq.MinCPUPlatform,
+
+		NetworkPerformanceConfig: ireq.networkPerformanceConfig(),
+		AdvancedMachineFeatures:  ireq.advancedMachineFeatures(),
+
+		GuestAccelerators: ireq.guestAccelerators(selfLinks),
+		Scheduling:        ireq.scheduling(),
+	}
+}
+
+// disks returns the disks to attach with the boot disk's initialization
+// customized per the request. The boot disk is copied so that shared
+// disks such as BasicAttachedDisk are never modified.
+func (ireq *InstanceRequest) disks() []*compute.AttachedDisk {
+	var disks []*compute.AttachedDisk
+	for _, disk := range ireq.disksOrDefault() {
+		if disk.Boot && disk.InitializeParams != nil {
+			bootDisk := *disk
+			params := *disk.InitializeParams
+			ireq.customizeBootDisk(&params)
+			bootDisk.InitializeParams = &params
+			if ireq.BootDiskKMSKeyName != "" {
+				bootDisk.DiskEncryptionKey = &compute.CustomerEncryptionKey{KmsKeyName: ireq.BootDiskKMSKeyName}
+			}
+			disk = &bootDisk
+		}
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+func (ireq *InstanceRequest) customizeBootDisk(params *compute.AttachedDiskInitializeParams) {
+	if ireq.BootImage != "" {
+		params.SourceImage = ireq.BootImage
+	}
+	if len(ireq.BootDiskResourcePolicies) > 0 {
+		params.ResourcePolicies = ireq.resourcePolicyURLs(ireq.BootDiskResourcePolicies)
+	}
+}
+
+// defaultServiceAccountScopes are the scopes that gcloud
+// grants to the default compute service account.
+var defaultServiceAccountScopes = []string{
+	"https://www.googleapis.com/auth/devstorage.read_only",
+	"https://www.googleapis.com/auth/logging.write",
+	"https://www.googleapis.com/auth/monitoring.write",
+	"https://www.googleapis.com/auth/servicecontrol",
+	"https://www.googleapis.com/auth/service.management.readonly",
+	"https://www.googleapis.com/auth/trace.append",
+}
+
+func (ireq *InstanceRequest) serviceAccounts() []*compute.ServiceAccount {
+	if len(ireq.ServiceAccounts) > 0 || !ireq.UseDefaultServiceAccount {
+		return ireq.ServiceAccounts[:]
+	}
+	return []*compute.ServiceAccount{
+		{Email: "default", Scopes: defaultServiceAccountScopes[:]},
 	}
 }
 
@@ -402,16 +907,54 @@ func (ireq *InstanceRequest) validateBasic() error {
 	if ireq.Name == "" {
 		return errBlankName
 	}
-	return nil
+	return validateZone(ireq.Zone)
 }
 
 func (ireq *InstanceRequest) validateForCreate() error {
 	if err := ireq.validateBasic(); err != nil {
 		return err
 	}
+	return ireq.validateProperties()
+}
+
+// validateProperties validates the configuration of the instance,
+// which unlike its identity is shared with instance templates.
+func (ireq *InstanceRequest) validateProperties() error {
 	if ireq.NetworkInterface == nil {
 		return errEmptyNetworkInterface
 	}
+	if len(ireq.ServiceAccounts) > 1 {
+		return errMultipleServiceAccounts
+	}
+	if err := ireq.validateMetadata(); err != nil {
+		return err
+	}
+	if err := validateLabels(ireq.Labels); err != nil {
+		return err
+	}
+	if err := validateNetworkTags(ireq.Tags); err != nil {
+		return err
+	}
+	if ireq.BootDiskKMSKeyName != "" {
+		if err := validateKMSKeyName(ireq.BootDiskKMSKeyName); err != nil {
+			return err
+		}
+	}
+	if err := ireq.validateNetworkPerformanceTier(); err != nil {
+		return err
+	}
+	if err := ireq.validateAdvancedFeatures(); err != nil {
+		return err
+	}
+	if err := ireq.validateAccelerators(); err != nil {
+		return err
+	}
+	if err := ireq.validateDataDisks(); err != nil {
+		return err
+	}
+	if err := ireq.validateRunDuration(); err != nil {
+		return err
+	}
 	return ireq.machineTypeOrDefault().Validate()
 }
 
@@ -427,24 +970,48 @@ func (ireq *InstanceRequest) validateForByName() error {
 }
 
 func (c *Client) FindInstance(ctx context.Context, ireq *InstanceRequest) (*compute.Instance, error) {
+	ireq = inProject(ireq, c.DefaultProject)
 	if err := ireq.validateForByName(); err != nil {
 		return nil, err
 	}
-	req := c.instancesService().Get(ireq.Project, ireq.Zone, ireq.Name)
-	return req.Context(ctx).Do()
+	var instance *compute.Instance
+	err := c.retry(ctx, func() (err error) {
+		req := c.instancesService().Get(ireq.Project, ireq.Zone, ireq.Name)
+		instance, err = req.Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
 }
 
 func (c *Client) CreateInstance(ctx context.Context, ireq *InstanceRequest) (*compute.Instance, error) {
-	if err := ireq.validateForCreate(); err != nil {
+	ireq = inProject(ireq, c.DefaultProject)
+	toCreate, err := ireq.buildInstance()
+	if err != nil {
 		return nil, err
 	}
-	req := c.instancesService().Insert(ireq.Project, ireq.Zone, ireq.toInstance())
-	operation, err := req.Context(ctx).Do()
+	if err := c.prepareInstance(ctx, ireq, toCreate); err != nil {
+		return nil, err
+	}
+	var operation *compute.Operation
+	err = c.retryMutation(ctx, func() (err error) {
+		req := c.instancesService().Insert(ireq.Project, ireq.Zone, toCreate)
+		operation, err = req.Context(ctx).Do()
+		return err
+	})
 	log.Printf("op: %+v err: %v\n", operation, err)
 	if err != nil {
 		return nil, err
 	}
 
+	if ireq.BlockUntilCompletion {
+		if _, err := c.waitForZoneOperation(ctx, ireq.Project, ireq.Zone, operation); err != nil {
+			return nil, err
+		}
+	}
+
 	// Now check for any errors returned in operations.
 	if err := operation.Error; err != nil {
 		if anErr, ok := interface{}(err).(error); ok {
@@ -0,0 +1,569 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// handlerTransport serves requests from an in-process handler
+// so that tests never have to reach out to the real Google APIs.
+type handlerTransport struct {
+	h http.Handler
+}
+
+var _ http.RoundTripper = (*handlerTransport)(nil)
+
+func (ht *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	ht.h.ServeHTTP(rec, req)
+	// Like real transports, fail requests whose context is done.
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	res := rec.Result()
+	res.Request = req
+	return res, nil
+}
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	client, err := NewWithHTTPClient(&http.Client{Transport: &handlerTransport{h: h}})
+	if err != nil {
+		t.Fatalf("NewWithHTTPClient: %v", err)
+	}
+	return client
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func readJSON(t *testing.T, req *http.Request, save interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(req.Body).Decode(save); err != nil {
+		t.Fatalf("decoding request body: %v", err)
+	}
+}
+
+func TestListZonesUsesDefaultResultsPerPage(t *testing.T) {
+	defer func(saved int64) { DefaultResultsPerPage = saved }(DefaultResultsPerPage)
+	DefaultResultsPerPage = 7
+
+	var maxResults []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		maxResults = append(maxResults, req.URL.Query().Get("maxResults"))
+		writeJSON(w, &compute.ZoneList{Items: []*compute.Zone{{Name: "us-central1-c"}}})
+	})
+
+	for _, perPage := range []int64{0, 25} {
+		zres, err := client.ListZones(context.Background(), &ZoneRequest{
+			Project:        "sample",
+			ResultsPerPage: perPage,
+		})
+		if err != nil {
+			t.Fatalf("ListZones: %v", err)
+		}
+		for page := range zres.Pages {
+			if page.Err != nil {
+				t.Fatalf("page #%d: %v", page.PageNumber, page.Err)
+			}
+		}
+	}
+
+	want := []string{"7", "25"}
+	if !reflect.DeepEqual(maxResults, want) {
+		t.Errorf("maxResults: got %q want %q", maxResults, want)
+	}
+}
+
+func TestListZonesThrottle(t *testing.T) {
+	tests := []struct {
+		millis int64
+		want   time.Duration
+	}{
+		{millis: 0, want: defaultPageThrottle},
+		{millis: 50, want: 50 * time.Millisecond},
+		{millis: -1, want: 0},
+	}
+	for _, tt := range tests {
+		if got := pageThrottleOrDefault(tt.millis); got != tt.want {
+			t.Errorf("%d: got %s want %s", tt.millis, got, tt.want)
+		}
+	}
+
+	const pages = 5
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		zl := &compute.ZoneList{Items: []*compute.Zone{{Name: "us-central1-c"}}}
+		if requests < pages {
+			zl.NextPageToken = fmt.Sprintf("page-%d", requests+1)
+		}
+		writeJSON(w, zl)
+	})
+
+	start := time.Now()
+	zres, err := client.ListZones(context.Background(), &ZoneRequest{Project: "sample", ThrottleMillis: -1})
+	if err != nil {
+		t.Fatalf("ListZones: %v", err)
+	}
+	for page := range zres.Pages {
+		if page.Err != nil {
+			t.Fatalf("page #%d: %v", page.PageNumber, page.Err)
+		}
+	}
+	if requests != pages {
+		t.Errorf("got %d requests want %d", requests, pages)
+	}
+	// Throttled by default, the pages would take over a second.
+	if elapsed := time.Since(start); elapsed >= defaultPageThrottle {
+		t.Errorf("unthrottled listing of %d pages took %s", pages, elapsed)
+	}
+}
+
+func TestListZonesInRegion(t *testing.T) {
+	regionURL := func(region string) string { return SelfLink("sample", "regions", region) }
+	var filter string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		filter = req.URL.Query().Get("filter")
+		writeJSON(w, &compute.ZoneList{Items: []*compute.Zone{
+			{Name: "us-central1-a", Region: regionURL("us-central1")},
+			{Name: "us-east1-b", Region: regionURL("us-east1")},
+			{Name: "us-central1-c", Region: regionURL("us-central1")},
+			{Name: "europe-west1-b", Region: regionURL("europe-west1")},
+		}})
+	})
+
+	zres, err := client.ListZones(context.Background(), &ZoneRequest{
+		Project: "sample",
+		Filter:  `status = "UP"`,
+		Region:  "us-central1",
+	})
+	if err != nil {
+		t.Fatalf("ListZones: %v", err)
+	}
+	var names []string
+	for page := range zres.Pages {
+		if page.Err != nil {
+			t.Fatalf("page #%d: %v", page.PageNumber, page.Err)
+		}
+		for _, zone := range page.Zones {
+			names = append(names, zone.Name)
+		}
+	}
+
+	if want := []string{"us-central1-a", "us-central1-c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("zones: got %q want %q", names, want)
+	}
+	if want := `(status = "UP") AND (region = "https://www.googleapis.com/compute/v1/projects/sample/regions/us-central1")`; filter != want {
+		t.Errorf("filter:\ngot  %s\nwant %s", filter, want)
+	}
+}
+
+func TestDefaultResultsPerPageOutOfRange(t *testing.T) {
+	defer func(saved int64) { DefaultResultsPerPage = saved }(DefaultResultsPerPage)
+
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+	})
+	for _, perPage := range []int64{0, -1, 501} {
+		DefaultResultsPerPage = perPage
+		_, err := client.ListInstances(context.Background(), &InstancesRequest{
+			Project: "sample",
+			Zone:    "us-central1-c",
+		})
+		if err != errDefaultResultsPerPageOutOfRange {
+			t.Errorf("DefaultResultsPerPage=%d: got err %v want %v", perPage, err, errDefaultResultsPerPageOutOfRange)
+		}
+	}
+}
+
+func TestIsZone(t *testing.T) {
+	tests := [...]struct {
+		s    string
+		want bool
+	}{
+		0: {s: "us-central1-c", want: true},
+		1: {s: "europe-west4-a", want: true},
+		2: {s: "northamerica-northeast1-b", want: true},
+		3: {s: "us-central1", want: false},
+		4: {s: "europe-west4", want: false},
+		5: {s: "", want: false},
+		6: {s: "US-CENTRAL1-C", want: false},
+		7: {s: "us-central1-c-", want: false},
+	}
+
+	for i, tt := range tests {
+		if got := IsZone(tt.s); got != tt.want {
+			t.Errorf("#%d: IsZone(%q): got %v want %v", i, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRejectsRegionsAsZones(t *testing.T) {
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1", Name: "web"}
+	if err := ireq.validateBasic(); err == nil || !strings.Contains(err.Error(), "is not a zone") {
+		t.Errorf("InstanceRequest: got err %v, want a not a zone error", err)
+	}
+	ireq.Zone = "us-central1-c"
+	if err := ireq.validateBasic(); err != nil {
+		t.Errorf("InstanceRequest: unexpected err: %v", err)
+	}
+
+	ireqs := &InstancesRequest{Project: "sample", Zone: "us-central1"}
+	if err := ireqs.Validate(); err == nil || !strings.Contains(err.Error(), "is not a zone") {
+		t.Errorf("InstancesRequest: got err %v, want a not a zone error", err)
+	}
+}
+
+func TestInstanceRequestServiceAccounts(t *testing.T) {
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "web",
+
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		ServiceAccounts: []*compute.ServiceAccount{
+			{Email: "web@sample.iam.gserviceaccount.com"},
+			{Email: "db@sample.iam.gserviceaccount.com"},
+		},
+	}
+	if err := ireq.validateForCreate(); err != errMultipleServiceAccounts {
+		t.Errorf("two service accounts: got err %v want %v", err, errMultipleServiceAccounts)
+	}
+
+	ireq.ServiceAccounts = ireq.ServiceAccounts[:1]
+	ireq.UseDefaultServiceAccount = true
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("one service account: unexpected err: %v", err)
+	}
+	if got := ireq.toInstance().ServiceAccounts; len(got) != 1 || got[0].Email != "web@sample.iam.gserviceaccount.com" {
+		t.Errorf("explicit service account must win over the default, got %+v", got)
+	}
+
+	ireq.ServiceAccounts = nil
+	got := ireq.toInstance().ServiceAccounts
+	if len(got) != 1 || got[0].Email != "default" || !reflect.DeepEqual(got[0].Scopes, defaultServiceAccountScopes) {
+		t.Errorf("expected the default service account, got %+v", got)
+	}
+
+	ireq.UseDefaultServiceAccount = false
+	if got := ireq.toInstance().ServiceAccounts; len(got) != 0 {
+		t.Errorf("expected no service accounts, got %+v", got)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	defer func(saved *rand.Rand) { jitterRand = saved }(jitterRand)
+
+	const d = 350 * time.Millisecond
+	lo, hi := time.Duration(float64(d)*0.8), time.Duration(float64(d)*1.2)
+
+	sample := func(seed int64) []time.Duration {
+		jitterRand = rand.New(rand.NewSource(seed))
+		var sleeps []time.Duration
+		for i := 0; i < 100; i++ {
+			sleeps = append(sleeps, jitter(d))
+		}
+		return sleeps
+	}
+
+	sleeps := sample(1)
+	distinct := make(map[time.Duration]bool)
+	for i, sleep := range sleeps {
+		if sleep < lo || sleep > hi {
+			t.Errorf("#%d: sleep %s is outside of [%s, %s]", i, sleep, lo, hi)
+		}
+		distinct[sleep] = true
+	}
+	if len(distinct) < 50 {
+		t.Errorf("got only %d distinct sleeps out of %d", len(distinct), len(sleeps))
+	}
+
+	if again := sample(1); !reflect.DeepEqual(again, sleeps) {
+		t.Error("expected the same sleeps from the same seed")
+	}
+}
+
+func TestListZonesMaxBufferedPages(t *testing.T) {
+	for _, cancelBy := range []string{"Cancel", "context"} {
+		fetched := make(chan bool, 10)
+		client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+			fetched <- true
+			// There are always more pages.
+			writeJSON(w, &compute.ZoneList{Items: []*compute.Zone{{Name: "us-central1-c"}}, NextPageToken: "next"})
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		zres, err := client.ListZones(ctx, &ZoneRequest{Project: "sample", MaxBufferedPages: 1})
+		if err != nil {
+			t.Fatalf("ListZones: %v", err)
+		}
+
+		// With nothing consumed, the first page fills the buffer and the
+		// second one is fetched but then can't be sent, so no third page
+		// gets fetched for well past the throttling between pages.
+		for i := 0; i < 2; i++ {
+			select {
+			case <-fetched:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: page #%d wasn't fetched", cancelBy, i)
+			}
+		}
+		select {
+		case <-fetched:
+			t.Fatalf("%s: expected the lister to block on the full buffer", cancelBy)
+		case <-time.After(time.Second):
+		}
+
+		if cancelBy == "Cancel" {
+			_ = zres.Cancel()
+		} else {
+			cancel()
+		}
+
+		// The lister must stop and close the pages, leaving only the buffered page.
+		deadline := time.After(5 * time.Second)
+		n := 0
+	drain:
+		for {
+			select {
+			case _, ok := <-zres.Pages:
+				if !ok {
+					break drain
+				}
+				n++
+			case <-deadline:
+				t.Fatalf("%s: the lister didn't stop", cancelBy)
+			}
+		}
+		if n != 1 {
+			t.Errorf("%s: got %d buffered pages want 1", cancelBy, n)
+		}
+		cancel()
+	}
+}
+
+func TestListInstancesCreationTimeWindow(t *testing.T) {
+	var filter string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		filter = req.URL.Query().Get("filter")
+		writeJSON(w, &compute.InstanceList{})
+	})
+
+	after := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2023, time.October, 2, 3, 0, 0, 0, time.FixedZone("EAT", 3*60*60))
+	ires, err := client.ListInstances(context.Background(), &InstancesRequest{
+		Project:       "sample",
+		Zone:          "us-central1-c",
+		Filter:        `labels.env = "test"`,
+		CreatedAfter:  after,
+		CreatedBefore: before,
+	})
+	if err != nil {
+		t.Fatalf("ListInstances: %v", err)
+	}
+	for range ires.Pages {
+	}
+
+	want := `(labels.env = "test") AND (creationTimestamp > "2023-10-01T00:00:00Z") AND (creationTimestamp < "2023-10-02T00:00:00Z")`
+	if filter != want {
+		t.Errorf("filter:\ngot  %s\nwant %s", filter, want)
+	}
+
+	tests := []struct {
+		ireq *InstancesRequest
+		want string
+	}{
+		{ireq: &InstancesRequest{}, want: ""},
+		{ireq: &InstancesRequest{Filter: "status = RUNNING"}, want: "status = RUNNING"},
+		{ireq: &InstancesRequest{CreatedBefore: before}, want: `(creationTimestamp < "2023-10-02T00:00:00Z")`},
+		{
+			ireq: &InstancesRequest{Labels: map[string]string{"team": "web", "env": "prod"}},
+			want: `(labels.env = "prod") AND (labels.team = "web")`,
+		},
+		{
+			ireq: &InstancesRequest{Filter: "status = RUNNING", Labels: map[string]string{"env": "prod"}},
+			want: `(status = RUNNING) AND (labels.env = "prod")`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.ireq.filter(); got != tt.want {
+			t.Errorf("got filter %q want %q", got, tt.want)
+		}
+	}
+
+	_, err = client.ListInstances(context.Background(), &InstancesRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Labels:  map[string]string{"Env": "prod"},
+	})
+	if err == nil {
+		t.Error("expected the invalid label key to be rejected")
+	}
+}
+
+func TestNewDefaultClientWithAdditionalScopes(t *testing.T) {
+	defer func(saved func(context.Context, ...string) (*http.Client, error)) {
+		defaultHTTPClient = saved
+	}(defaultHTTPClient)
+
+	var scopes []string
+	defaultHTTPClient = func(ctx context.Context, s ...string) (*http.Client, error) {
+		scopes = s
+		return new(http.Client), nil
+	}
+
+	const dnsReadOnly = "https://www.googleapis.com/auth/ndev.clouddns.readonly"
+	ctx := context.Background()
+	if _, err := NewDefaultClientWithAdditionalScopes(ctx, dnsReadOnly, defaultGCEScopes[0]); err != nil {
+		t.Fatalf("NewDefaultClientWithAdditionalScopes: %v", err)
+	}
+	if want := append(defaultGCEScopes[:len(defaultGCEScopes):len(defaultGCEScopes)], dnsReadOnly); !reflect.DeepEqual(scopes, want) {
+		t.Errorf("merged scopes: got %q want %q", scopes, want)
+	}
+
+	// NewDefaultClient still replaces the default scopes.
+	if _, err := NewDefaultClient(ctx, dnsReadOnly); err != nil {
+		t.Fatalf("NewDefaultClient: %v", err)
+	}
+	if want := []string{dnsReadOnly}; !reflect.DeepEqual(scopes, want) {
+		t.Errorf("replaced scopes: got %q want %q", scopes, want)
+	}
+	if len(defaultGCEScopes) != 1 {
+		t.Errorf("the default scopes were modified: %q", defaultGCEScopes)
+	}
+}
+
+func TestListInstancesPartialSuccess(t *testing.T) {
+	var partialSuccess string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		partialSuccess = req.URL.Query().Get("returnPartialSuccess")
+		writeJSON(w, &compute.InstanceList{
+			Items: []*compute.Instance{{Name: "web"}},
+			Warning: &compute.InstanceListWarning{
+				Code:    "UNREACHABLE",
+				Message: "some instances could not be listed",
+			},
+		})
+	})
+
+	ires, err := client.ListInstances(context.Background(), &InstancesRequest{
+		Project:        "sample",
+		Zone:           "us-central1-c",
+		PartialSuccess: true,
+	})
+	if err != nil {
+		t.Fatalf("ListInstances: %v", err)
+	}
+	var pages []*InstancePage
+	for page := range ires.Pages {
+		pages = append(pages, page)
+	}
+
+	if partialSuccess != "true" {
+		t.Errorf("returnPartialSuccess: got %q want %q", partialSuccess, "true")
+	}
+	if len(pages) != 1 || pages[0].Err != nil {
+		t.Fatalf("expected a single page, got %+v", pages)
+	}
+	if len(pages[0].Instances) != 1 || pages[0].Instances[0].Name != "web" {
+		t.Errorf("expected the partial results, got %+v", pages[0].Instances)
+	}
+	if w := pages[0].Warning; w == nil || w.Code != "UNREACHABLE" {
+		t.Errorf("expected the warning on the page, got %+v", w)
+	}
+}
+
+func TestListZonesCancelAbortsInFlightRequest(t *testing.T) {
+	inFlight := make(chan bool)
+	aborted := make(chan bool, 1)
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		close(inFlight)
+		// A slow page that only returns once the request is aborted.
+		select {
+		case <-req.Context().Done():
+			aborted <- true
+		case <-time.After(10 * time.Second):
+		}
+		writeJSON(w, &compute.ZoneList{})
+	})
+
+	zres, err := client.ListZones(context.Background(), &ZoneRequest{Project: "sample"})
+	if err != nil {
+		t.Fatalf("ListZones: %v", err)
+	}
+	<-inFlight
+	start := time.Now()
+	_ = zres.Cancel()
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the in-flight request wasn't aborted")
+	}
+	for page := range zres.Pages {
+		t.Errorf("unexpected page after cancelling: %+v", page)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s to stop after cancelling", elapsed)
+	}
+}
+
+func TestClientDefaultProject(t *testing.T) {
+	var paths []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+		writeJSON(w, &compute.Instance{Name: "web"})
+	})
+
+	ctx := context.Background()
+	ireq := &InstanceRequest{Zone: "us-central1-c", Name: "web"}
+	if _, err := client.FindInstance(ctx, ireq); err != errEmptyProject {
+		t.Errorf("no default project: got err %v want %v", err, errEmptyProject)
+	}
+
+	client.DefaultProject = "sample"
+	if _, err := client.FindInstance(ctx, ireq); err != nil {
+		t.Fatalf("default project: unexpected err: %v", err)
+	}
+	if ireq.Project != "" {
+		t.Errorf("expected the caller's request to not be modified, got project %q", ireq.Project)
+	}
+	ireq.Project = "other"
+	if _, err := client.FindInstance(ctx, ireq); err != nil {
+		t.Fatalf("request project: unexpected err: %v", err)
+	}
+	want := []string{
+		"/compute/v1/projects/sample/zones/us-central1-c/instances/web",
+		"/compute/v1/projects/other/zones/us-central1-c/instances/web",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths: got %q want %q", paths, want)
+	}
+
+	fz := new(fakeZone)
+	client = newTestClient(t, fz.ServeHTTP)
+	client.DefaultProject = "sample"
+	_, err := client.AddRecordSets(ctx, &UpdateRequest{
+		Zone:    "zone",
+		Records: []*Record{{Type: AName, DNSName: "www.orijtech.com", TTL: 300, IPV4Addresses: []string{"10.0.0.1"}}},
+	})
+	if err != nil {
+		t.Fatalf("AddRecordSets: %v", err)
+	}
+	if len(fz.rrsets) != 1 {
+		t.Errorf("expected the record to be added to the default project's zone, got %+v", fz.rrsets)
+	}
+}
@@ -0,0 +1,81 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+var errNegativeSize = errors.New("expecting a non-negative size")
+
+func (c *Client) instanceGroupManagersService() *compute.InstanceGroupManagersService {
+	return compute.NewInstanceGroupManagersService(c.computeSrvc)
+}
+
+func validateGroupIdentity(project, zone, groupName string) error {
+	if project == "" {
+		return errEmptyProject
+	}
+	if zone == "" {
+		return errEmptyZone
+	}
+	if groupName == "" {
+		return errBlankName
+	}
+	return nil
+}
+
+// ResizeInstanceGroup sets the target number of instances of the managed
+// instance group called groupName. The returned operation completes once
+// the resize has been requested, use WaitForGroupStable to wait until
+// all the instances are up and running.
+func (c *Client) ResizeInstanceGroup(ctx context.Context, project, zone, groupName string, size int64) (*compute.Operation, error) {
+	project = c.projectOrDefault(project)
+	if err := validateGroupIdentity(project, zone, groupName); err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, errNegativeSize
+	}
+	req := c.instanceGroupManagersService().Resize(project, zone, groupName, size)
+	return req.Context(ctx).Do()
+}
+
+// WaitForGroupStable polls the managed instance group called groupName
+// until it reports itself as stable, that is all its instances are running
+// and none of them are being created, restarted or deleted.
+// A non-positive timeout means that Client.OperationPollTimeout is used.
+func (c *Client) WaitForGroupStable(ctx context.Context, project, zone, groupName string, timeout time.Duration) error {
+	project = c.projectOrDefault(project)
+	if err := validateGroupIdentity(project, zone, groupName); err != nil {
+		return err
+	}
+
+	if timeout <= 0 {
+		timeout = c.operationPollTimeout()
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	backoff := c.pollBackoff()
+
+	for {
+		igm, err := c.instanceGroupManagersService().Get(project, zone, groupName).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		if igm.Status != nil && igm.Status.IsStable {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("instance group %q did not become stable within %s", groupName, timeout)
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
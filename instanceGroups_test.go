@@ -0,0 +1,88 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestResizeAndWaitForGroupStable(t *testing.T) {
+	const groupPath = "/compute/v1/projects/sample/zones/us-central1-c/instanceGroupManagers/web"
+	var resizedTo string
+	gets := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "POST " + groupPath + "/resize":
+			resizedTo = req.URL.Query().Get("size")
+			writeJSON(w, &compute.Operation{Name: "op-resize"})
+		case "GET " + groupPath:
+			gets++
+			// Stable only from the third check onwards.
+			writeJSON(w, &compute.InstanceGroupManager{
+				Name:   "web",
+				Status: &compute.InstanceGroupManagerStatus{IsStable: gets >= 3},
+			})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	client.OperationPollInterval = time.Millisecond
+
+	ctx := context.Background()
+	if _, err := client.ResizeInstanceGroup(ctx, "sample", "us-central1-c", "web", 4); err != nil {
+		t.Fatalf("ResizeInstanceGroup: %v", err)
+	}
+	if resizedTo != "4" {
+		t.Errorf("resize size: got %q want %q", resizedTo, "4")
+	}
+	if err := client.WaitForGroupStable(ctx, "sample", "us-central1-c", "web", time.Minute); err != nil {
+		t.Fatalf("WaitForGroupStable: %v", err)
+	}
+	if gets != 3 {
+		t.Errorf("status checks: got %d want %d", gets, 3)
+	}
+}
+
+func TestWaitForGroupStableTimeout(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, &compute.InstanceGroupManager{Status: &compute.InstanceGroupManagerStatus{}})
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	err := client.WaitForGroupStable(context.Background(), "sample", "us-central1-c", "web", 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not become stable") {
+		t.Errorf("got err %v, want a timeout error", err)
+	}
+}
+
+func TestOperationPollInterval(t *testing.T) {
+	pollsWithInterval := func(interval time.Duration) int {
+		polls := 0
+		client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+			polls++
+			writeJSON(w, &compute.InstanceGroupManager{Status: &compute.InstanceGroupManagerStatus{}})
+		})
+		client.OperationPollInterval = interval
+		client.OperationPollTimeout = 60 * time.Millisecond
+
+		// A zero timeout falls back to the client's OperationPollTimeout.
+		if err := client.WaitForGroupStable(context.Background(), "sample", "us-central1-c", "web", 0); err == nil {
+			t.Fatal("expected a timeout error")
+		}
+		return polls
+	}
+
+	shortPolls := pollsWithInterval(time.Millisecond)
+	longPolls := pollsWithInterval(25 * time.Millisecond)
+	if longPolls > 3 {
+		t.Errorf("25ms interval within 60ms: got %d polls want at most 3", longPolls)
+	}
+	if shortPolls <= longPolls {
+		t.Errorf("1ms interval polled %d times, expected more than the %d polls of the 25ms interval", shortPolls, longPolls)
+	}
+}
@@ -0,0 +1,18 @@
+package infra
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var kmsKeyNameRegexp = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+// validateKMSKeyName checks that name is the resource name of
+// a Cloud KMS key, for encrypting data with a customer-managed key.
+func validateKMSKeyName(name string) error {
+	if !kmsKeyNameRegexp.MatchString(name) {
+		return fmt.Errorf("%q is not a KMS key name, expecting one such as %q",
+			name, "projects/sample/locations/us-central1/keyRings/ring/cryptoKeys/key")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package infra
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testKMSKeyName = "projects/sample/locations/us-central1/keyRings/ring/cryptoKeys/key"
+
+func TestUploadKMSKeyName(t *testing.T) {
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+
+	ctx := context.Background()
+	_, err := client.UploadWithParams(ctx, &UploadParams{
+		Project:    "sample",
+		Bucket:     "backups",
+		Name:       "2017-06-01.tar.gz",
+		KMSKeyName: testKMSKeyName,
+		Reader:     func() io.Reader { return strings.NewReader("backup") },
+	})
+	if err != nil {
+		t.Fatalf("UploadWithParams: %v", err)
+	}
+	if got := fs.objects["backups/2017-06-01.tar.gz"].KmsKeyName; got != testKMSKeyName {
+		t.Errorf("stored object: got KMS key %q want %q", got, testKMSKeyName)
+	}
+
+	_, err = client.UploadWithParams(ctx, &UploadParams{
+		Bucket:     "backups",
+		Name:       "bad.tar.gz",
+		KMSKeyName: "ring/key",
+		Reader:     func() io.Reader { return strings.NewReader("backup") },
+	})
+	if err == nil || !strings.Contains(err.Error(), "not a KMS key name") {
+		t.Errorf("upload: got err %v, want an invalid KMS key name error", err)
+	}
+}
+
+func TestBootDiskKMSKeyName(t *testing.T) {
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "web",
+
+		NetworkInterface:   BasicExternalNATNetworkInterface,
+		BootDiskKMSKeyName: testKMSKeyName,
+	}
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	bootDisk := ireq.toInstance().Disks[0]
+	if bootDisk.DiskEncryptionKey == nil || bootDisk.DiskEncryptionKey.KmsKeyName != testKMSKeyName {
+		t.Errorf("boot disk: got encryption key %+v want %q", bootDisk.DiskEncryptionKey, testKMSKeyName)
+	}
+	if BasicAttachedDisk.DiskEncryptionKey != nil {
+		t.Error("BasicAttachedDisk was modified")
+	}
+
+	ireq.BootDiskKMSKeyName = testKMSKeyName + "/cryptoKeyVersions/1/extra"
+	if err := ireq.validateForCreate(); err == nil || !strings.Contains(err.Error(), "not a KMS key name") {
+		t.Errorf("got err %v, want an invalid KMS key name error", err)
+	}
+}
@@ -0,0 +1,88 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// maxLabelFingerprintRetries is the number of times that SetInstanceLabels
+// retries its read-modify-write after losing a race to a concurrent writer.
+const maxLabelFingerprintRetries = 3
+
+// maxLabels is the most labels that a resource can have.
+const maxLabels = 64
+
+var (
+	labelKeyRegexp   = regexp.MustCompile(`^[a-z][-_a-z0-9]{0,62}$`)
+	labelValueRegexp = regexp.MustCompile(`^[-_a-z0-9]{0,63}$`)
+)
+
+// validateLabels checks that the labels follow the syntax that Google
+// Cloud requires of labels: keys start with a lowercase letter and,
+// like values, are at most 63 lowercase letters, digits, '_' and '-'.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("got %d labels, expecting at most %d", len(labels), maxLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyRegexp.MatchString(key) {
+			return fmt.Errorf("%q is not a valid label key, expecting one such as %q", key, "cost-center")
+		}
+		if !labelValueRegexp.MatchString(value) {
+			return fmt.Errorf("label %q: %q is not a valid label value, expecting one such as %q", key, value, "web_team-1")
+		}
+	}
+	return nil
+}
+
+// mergeLabels returns a new map with the labels of base
+// overridden and extended by those of overrides.
+func mergeLabels(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// SetInstanceLabels merges labels into the instance's existing labels,
+// overriding the values of any keys that already exist. It is safe to use
+// concurrently with other label writers: the update is conditioned on the
+// instance's label fingerprint and is retried if the labels changed between
+// reading and writing them.
+func (c *Client) SetInstanceLabels(ctx context.Context, ireq *InstanceRequest, labels map[string]string) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; ; i++ {
+		instance, err := c.FindInstance(ctx, ireq)
+		if err != nil {
+			return nil, err
+		}
+
+		req := c.instancesService().SetLabels(ireq.Project, ireq.Zone, ireq.Name, &compute.InstancesSetLabelsRequest{
+			Labels:           mergeLabels(instance.Labels, labels),
+			LabelFingerprint: instance.LabelFingerprint,
+		})
+		op, err := req.Context(ctx).Do()
+		if err == nil || i >= maxLabelFingerprintRetries || !isFingerprintMismatch(err) {
+			return op, err
+		}
+	}
+}
+
+func isFingerprintMismatch(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusPreconditionFailed
+}
@@ -0,0 +1,71 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestSetupLabelsMergedOntoReusedInstance(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/frontend"
+	var setLabelsReqs []*compute.InstancesSetLabelsRequest
+	fingerprint := "fp-1"
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + instancePath:
+			writeJSON(w, &compute.Instance{
+				Name:              "frontend",
+				Labels:            map[string]string{"team": "web", "managed-by": "terraform"},
+				LabelFingerprint:  fingerprint,
+				NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.128.0.5"}},
+			})
+		case "POST " + instancePath + "/setLabels":
+			slr := new(compute.InstancesSetLabelsRequest)
+			readJSON(t, req, slr)
+			setLabelsReqs = append(setLabelsReqs, slr)
+			if len(setLabelsReqs) == 1 {
+				// Simulate a concurrent writer having changed the labels.
+				fingerprint = "fp-2"
+				w.WriteHeader(http.StatusPreconditionFailed)
+				writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": http.StatusPreconditionFailed}})
+				return
+			}
+			writeJSON(w, &compute.Operation{Name: "op-labels"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ipv4Addresses, created, err := client.generateMachineAndIPV4Addresses(context.Background(), &Setup{
+		Project:     "sample",
+		Zone:        "us-central1-c",
+		DomainName:  "edison.orijtech.com",
+		MachineName: "frontend",
+
+		SetupLabels: map[string]string{"managed-by": "infra"},
+	})
+	if err != nil {
+		t.Fatalf("generateMachineAndIPV4Addresses: %v", err)
+	}
+	if created != nil {
+		t.Errorf("expected the existing instance to be reused, got created instance %q", created.Name)
+	}
+	if want := []string{"10.128.0.5"}; !reflect.DeepEqual(ipv4Addresses, want) {
+		t.Errorf("ipv4Addresses: got %q want %q", ipv4Addresses, want)
+	}
+
+	if len(setLabelsReqs) != 2 {
+		t.Fatalf("setLabels calls: got %d want 2", len(setLabelsReqs))
+	}
+	last := setLabelsReqs[1]
+	if last.LabelFingerprint != "fp-2" {
+		t.Errorf("fingerprint: got %q want %q", last.LabelFingerprint, "fp-2")
+	}
+	want := map[string]string{"team": "web", "managed-by": "infra"}
+	if !reflect.DeepEqual(last.Labels, want) {
+		t.Errorf("labels: got %v want %v", last.Labels, want)
+	}
+}
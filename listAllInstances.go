@@ -0,0 +1,74 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// InstanceSortKey is the field that ListAllInstances sorts instances by.
+type InstanceSortKey string
+
+const (
+	SortByName              InstanceSortKey = "name"
+	SortByCreationTimestamp InstanceSortKey = "creationTimestamp"
+	SortByMachineType       InstanceSortKey = "machineType"
+	SortByStatus            InstanceSortKey = "status"
+)
+
+var instanceLessFuncs = map[InstanceSortKey]func(a, b *compute.Instance) bool{
+	SortByName: func(a, b *compute.Instance) bool { return a.Name < b.Name },
+	SortByCreationTimestamp: func(a, b *compute.Instance) bool {
+		// Timestamps can have different offsets so compare them as times.
+		ta, errA := time.Parse(time.RFC3339, a.CreationTimestamp)
+		tb, errB := time.Parse(time.RFC3339, b.CreationTimestamp)
+		if errA != nil || errB != nil {
+			return a.CreationTimestamp < b.CreationTimestamp
+		}
+		return ta.Before(tb)
+	},
+	SortByMachineType: func(a, b *compute.Instance) bool { return a.MachineType < b.MachineType },
+	SortByStatus:      func(a, b *compute.Instance) bool { return a.Status < b.Status },
+}
+
+// ListAllInstances fetches every page of the instances that ireq lists and
+// returns them in a single slice, sorted by sortBy if set rather than in the
+// order of the API. Unlike OrderBy, sorting happens once all instances are
+// fetched, so any of the InstanceSortKey fields can be used. If ireq.Zone
+// is blank, the instances of every zone are listed, like with
+// ListInstancesInAllZones, so that they can be sorted across zones.
+func (c *Client) ListAllInstances(ctx context.Context, ireq *InstancesRequest, sortBy InstanceSortKey) ([]*compute.Instance, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	var less func(a, b *compute.Instance) bool
+	if sortBy != "" {
+		var ok bool
+		if less, ok = instanceLessFuncs[sortBy]; !ok {
+			return nil, fmt.Errorf("unknown instance sort key %q", sortBy)
+		}
+	}
+
+	list := c.ListInstances
+	if ireq != nil && ireq.Zone == "" {
+		list = c.ListInstancesInAllZones
+	}
+	ires, err := list(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	defer ires.Cancel()
+
+	var instances []*compute.Instance
+	for page := range ires.Pages {
+		if page.Err != nil {
+			return nil, page.Err
+		}
+		instances = append(instances, page.Instances...)
+	}
+	if less != nil {
+		sort.SliceStable(instances, func(i, j int) bool { return less(instances[i], instances[j]) })
+	}
+	return instances, nil
+}
@@ -0,0 +1,92 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestListAllInstancesSorted(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("pageToken") == "" {
+			writeJSON(w, &compute.InstanceList{
+				Items: []*compute.Instance{
+					{Name: "web", CreationTimestamp: "2017-06-03T10:00:00.000-07:00"},
+					{Name: "api", CreationTimestamp: "2017-06-01T10:00:00.000-07:00"},
+				},
+				NextPageToken: "2",
+			})
+			return
+		}
+		writeJSON(w, &compute.InstanceList{
+			Items: []*compute.Instance{
+				// Earlier than "web" despite sorting after it as a string.
+				{Name: "db", CreationTimestamp: "2017-06-03T12:00:00.000Z"},
+			},
+		})
+	})
+
+	ireq := &InstancesRequest{Project: "sample", Zone: "us-central1-c"}
+	ctx := context.Background()
+	tests := []struct {
+		sortBy InstanceSortKey
+		want   []string
+	}{
+		{sortBy: "", want: []string{"web", "api", "db"}},
+		{sortBy: SortByName, want: []string{"api", "db", "web"}},
+		{sortBy: SortByCreationTimestamp, want: []string{"api", "db", "web"}},
+	}
+	for _, tt := range tests {
+		instances, err := client.ListAllInstances(ctx, ireq, tt.sortBy)
+		if err != nil {
+			t.Fatalf("sortBy %q: unexpected err: %v", tt.sortBy, err)
+		}
+		var names []string
+		for _, instance := range instances {
+			names = append(names, instance.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("sortBy %q: got %q want %q", tt.sortBy, names, tt.want)
+		}
+	}
+
+	if _, err := client.ListAllInstances(ctx, ireq, "cpu"); err == nil {
+		t.Errorf("expected an unknown sort key to be rejected")
+	}
+}
+
+func TestListAllInstancesAcrossZones(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/compute/v1/projects/sample/aggregated/instances" {
+			http.Error(w, "unexpected route "+req.URL.Path, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, &compute.InstanceAggregatedList{
+			Items: map[string]compute.InstancesScopedList{
+				"zones/us-central1-c": {Instances: []*compute.Instance{
+					{Name: "web", CreationTimestamp: "2017-06-03T10:00:00.000-07:00"},
+				}},
+				"zones/europe-west1-b": {Instances: []*compute.Instance{
+					{Name: "api", CreationTimestamp: "2017-06-01T10:00:00.000-07:00"},
+					{Name: "db", CreationTimestamp: "2017-06-03T12:00:00.000Z"},
+				}},
+			},
+		})
+	})
+
+	ireq := &InstancesRequest{Project: "sample", ThrottleMillis: -1}
+	instances, err := client.ListAllInstances(context.Background(), ireq, SortByCreationTimestamp)
+	if err != nil {
+		t.Fatalf("ListAllInstances: %v", err)
+	}
+	var names []string
+	for _, instance := range instances {
+		names = append(names, instance.Name)
+	}
+	if want := []string{"api", "db", "web"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q want %q", names, want)
+	}
+}
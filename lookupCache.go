@@ -0,0 +1,101 @@
+package infra
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// lookupCache caches the results of read-mostly lookups,
+// such as of zones, keyed by what was looked up.
+type lookupCache struct {
+	mu      sync.Mutex
+	entries map[string]*lookupEntry
+}
+
+type lookupEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func (lc *lookupCache) get(key string, now time.Time) (interface{}, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry, ok := lc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(entry.expires) {
+		delete(lc.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (lc *lookupCache) set(key string, value interface{}, expires time.Time) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.entries == nil {
+		lc.entries = make(map[string]*lookupEntry)
+	}
+	lc.entries[key] = &lookupEntry{value: value, expires: expires}
+}
+
+// cachedLookup returns the value cached for key if Client.LookupCacheTTL
+// is set and it hasn't expired, otherwise the value that lookup returns,
+// caching it if it succeeded. Failed lookups aren't cached.
+func (c *Client) cachedLookup(key string, lookup func() (interface{}, error)) (interface{}, error) {
+	if c.LookupCacheTTL <= 0 {
+		return lookup()
+	}
+	now := time.Now()
+	if value, ok := c.lookups.get(key, now); ok {
+		return value, nil
+	}
+	value, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	c.lookups.set(key, value, now.Add(c.LookupCacheTTL))
+	return value, nil
+}
+
+// zone returns the zone, cached for Client.LookupCacheTTL if set.
+func (c *Client) zone(ctx context.Context, project, zone string) (*compute.Zone, error) {
+	value, err := c.cachedLookup(path.Join("zones", project, zone), func() (interface{}, error) {
+		return c.zonesService().Get(project, zone).Context(ctx).Do()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*compute.Zone), nil
+}
+
+// acceleratorType returns the accelerator type of the zone,
+// cached for Client.LookupCacheTTL if set.
+func (c *Client) acceleratorType(ctx context.Context, project, zone, acceleratorType string) (*compute.AcceleratorType, error) {
+	value, err := c.cachedLookup(path.Join("acceleratorTypes", project, zone, acceleratorType), func() (interface{}, error) {
+		return c.acceleratorTypesService().Get(project, zone, acceleratorType).Context(ctx).Do()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*compute.AcceleratorType), nil
+}
+
+// machineType returns the machine type of the zone,
+// cached for Client.LookupCacheTTL if set.
+func (c *Client) machineType(ctx context.Context, project, zone, machineType string) (*compute.MachineType, error) {
+	value, err := c.cachedLookup(path.Join("machineTypes", project, zone, machineType), func() (interface{}, error) {
+		return c.machineTypesService().Get(project, zone, machineType).Context(ctx).Do()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*compute.MachineType), nil
+}
@@ -0,0 +1,124 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestLookupCache(t *testing.T) {
+	var mu sync.Mutex
+	zoneGets := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/compute/v1/projects/sample/zones/us-central1-c" {
+			http.Error(w, "unexpected route "+req.URL.Path, http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		zoneGets++
+		mu.Unlock()
+		writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP", AvailableCpuPlatforms: []string{"Intel Ice Lake"}})
+	})
+	ctx := context.Background()
+	lookupTwice := func() {
+		t.Helper()
+		if _, err := client.ZoneStatus(ctx, "sample", "us-central1-c"); err != nil {
+			t.Fatalf("ZoneStatus: %v", err)
+		}
+		if _, err := client.AvailableCPUPlatforms(ctx, "sample", "us-central1-c"); err != nil {
+			t.Fatalf("AvailableCPUPlatforms: %v", err)
+		}
+	}
+
+	// Without a TTL, nothing is cached.
+	lookupTwice()
+	if zoneGets != 2 {
+		t.Errorf("uncached: got %d zone lookups want 2", zoneGets)
+	}
+
+	zoneGets = 0
+	client.LookupCacheTTL = time.Hour
+	lookupTwice()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", MinCPUPlatform: "Intel Ice Lake"}
+			if err := client.validateZoneForCreate(ctx, ireq); err != nil {
+				t.Errorf("validateZoneForCreate: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if zoneGets != 1 {
+		t.Errorf("within the TTL: got %d zone lookups want 1", zoneGets)
+	}
+
+	// Entries expire after the TTL that they were cached with.
+	zoneGets = 0
+	client.lookups = lookupCache{}
+	client.LookupCacheTTL = 10 * time.Millisecond
+	lookupTwice()
+	before := zoneGets
+	time.Sleep(2 * client.LookupCacheTTL)
+	lookupTwice()
+	if zoneGets == before {
+		t.Errorf("after the TTL: expected the zone to be looked up again")
+	}
+}
+
+func TestMachineTypeAvailableCached(t *testing.T) {
+	const machineTypesPath = "/compute/v1/projects/sample/zones/us-central1-c/machineTypes/"
+	gets := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		gets++
+		if req.URL.Path != machineTypesPath+"n1-standard-4" {
+			writeNotFound(w)
+			return
+		}
+		writeJSON(w, &compute.MachineType{Name: "n1-standard-4", GuestCpus: 4})
+	})
+	client.LookupCacheTTL = time.Hour
+	ctx := context.Background()
+
+	mt, err := ChooseMachineType(4, 15360)
+	if err != nil {
+		t.Fatalf("ChooseMachineType: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		ok, err := client.MachineTypeAvailable(ctx, "sample", "us-central1-c", mt)
+		if err != nil || !ok {
+			t.Fatalf("#%d: got %v, %v want available", i, ok, err)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("within the TTL: got %d machine type lookups want 1", gets)
+	}
+
+	custom := &MachineType{CPUCount: 2, MemoryMBs: 4096}
+	if ok, err := client.MachineTypeAvailable(ctx, "sample", "us-central1-c", custom); err != nil || ok {
+		t.Errorf("custom type: got %v, %v want unavailable", ok, err)
+	}
+}
+
+func TestAvailableCPUPlatformsCopiesCachedZone(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP", AvailableCpuPlatforms: []string{"Intel Ice Lake"}})
+	})
+	client.LookupCacheTTL = time.Hour
+	ctx := context.Background()
+
+	platforms, err := client.AvailableCPUPlatforms(ctx, "sample", "us-central1-c")
+	if err != nil {
+		t.Fatalf("AvailableCPUPlatforms: %v", err)
+	}
+	platforms[0] = "modified"
+	if platforms, _ = client.AvailableCPUPlatforms(ctx, "sample", "us-central1-c"); platforms[0] != "Intel Ice Lake" {
+		t.Errorf("cached zone modified through the returned platforms: got %q", platforms)
+	}
+}
@@ -1,8 +1,14 @@
 package infra
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
 )
 
 type StandardType string
@@ -14,28 +20,32 @@ type MachineType struct {
 	MemoryMBs int `json:"memory_mbs"`
 
 	Type StandardType `json:"type"`
+
+	// ExtendedMemory allows a custom machine to have more memory
+	// than the 6.5GB per CPU that custom machines are limited to.
+	// Extended memory is billed at a different rate.
+	ExtendedMemory bool `json:"extended_memory"`
 }
 
 var (
 	errInvalidZeroCount    = errors.New("expecting 1 or an even CPU count upto 32")
 	errMemoryMultipleOf256 = errors.New("memory must be a multiple of 256")
 	errEmptyType           = errors.New("expecting a non-empty type")
+
+	errMemoryExceedsPerCPULimit = errors.New("memory exceeds 6.5GB per CPU, set ExtendedMemory for more")
 )
 
-func (mt *MachineType) Validate() error {
-	validators := []func() error{
-		mt.validateAsCustomMachine,
-		mt.validateAsStandardMachine,
-	}
+// maxMemoryMBsPerCPU is the most memory, 6.5GB, that each CPU
+// of a custom machine can have without extended memory.
+const maxMemoryMBsPerCPU = 6656
 
-	var err error
-	for _, fn := range validators {
-		err = fn()
-		if err == nil {
-			return nil
-		}
+// Validate checks the machine type as a custom machine if it sets
+// CPUCount or MemoryMBs, otherwise as a standard machine.
+func (mt *MachineType) Validate() error {
+	if mt.CPUCount != 0 || mt.MemoryMBs != 0 {
+		return mt.validateAsCustomMachine()
 	}
-	return err
+	return mt.validateAsStandardMachine()
 }
 
 func (mt *MachineType) validateAsStandardMachine() error {
@@ -60,6 +70,10 @@ func (mt *MachineType) validateAsCustomMachine() error {
 		return errMemoryMultipleOf256
 	}
 
+	if !mt.ExtendedMemory && mt.MemoryMBs > mt.CPUCount*maxMemoryMBsPerCPU {
+		return errMemoryExceedsPerCPULimit
+	}
+
 	return nil
 }
 
@@ -68,12 +82,50 @@ func (mt *MachineType) standardRoute() string {
 }
 
 func (mt *MachineType) customRoute() string {
-	// /machineTypes/custom-CPUS-MEMORY
-	return fmt.Sprintf("/machineTypes/custom-%d-%d", mt.CPUCount, mt.MemoryMBs)
+	return fmt.Sprintf("/machineTypes/%s", mt.customName())
+}
+
+func (mt *MachineType) customName() string {
+	// custom-CPUS-MEMORY[-ext]
+	name := fmt.Sprintf("custom-%d-%d", mt.CPUCount, mt.MemoryMBs)
+	if mt.ExtendedMemory {
+		name += "-ext"
+	}
+	return name
 }
 
-func (mt *MachineType) partialURLByZone(zone string) string {
-	return fmt.Sprintf("/zones/%s%s", zone, mt.route())
+// name returns the bare machine type name e.g "n1-standard-1"
+// or "custom-2-4096", as expected by APIs that don't take URLs.
+func (mt *MachineType) name() string {
+	if mt.canMakeCustomMachine() {
+		return mt.customName()
+	}
+	return string(mt.Type)
+}
+
+var customNameRegexp = regexp.MustCompile(`^custom-(\d+)-(\d+)(-ext)?$`)
+
+// machineTypeFromURL parses a machine type's name or URL, such as
+// "zones/us-central1-c/machineTypes/custom-2-4096", into a MachineType.
+func machineTypeFromURL(machineTypeURL string) *MachineType {
+	name := machineTypeURL[strings.LastIndex(machineTypeURL, "/")+1:]
+	if name == "" {
+		return nil
+	}
+	if matches := customNameRegexp.FindStringSubmatch(name); matches != nil {
+		cpuCount, _ := strconv.Atoi(matches[1])
+		memoryMBs, _ := strconv.Atoi(matches[2])
+		return &MachineType{
+			CPUCount:       cpuCount,
+			MemoryMBs:      memoryMBs,
+			ExtendedMemory: matches[3] != "",
+		}
+	}
+	return &MachineType{Type: StandardType(name)}
+}
+
+func (mt *MachineType) selfLinkByZone(project, zone string) string {
+	return SelfLink(project, "zones", zone, strings.TrimPrefix(mt.route(), "/"))
 }
 
 func (mt *MachineType) canMakeCustomMachine() bool {
@@ -137,3 +189,88 @@ const (
 	// Maximum total of persistent disk size: 64 TB.
 	N1Standard64 StandardType = "n1-standard-64"
 )
+
+// standardMachineTypes are the CPU counts and memory of the standard
+// machine types that ChooseMachineType picks from.
+var standardMachineTypes = []struct {
+	typ       StandardType
+	cpuCount  int
+	memoryMBs int
+}{
+	{N1Standard1, 1, 3840},
+	{N1Standard2, 2, 7680},
+	{N1Standard4, 4, 15360},
+	{N1Standard8, 8, 30720},
+	{N1Standard16, 16, 61440},
+	{N1Standard32, 32, 122880},
+	{N1Standard64, 64, 245760},
+}
+
+var (
+	errNonPositiveMemory = errors.New("expecting a positive amount of memory")
+	errNilMachineType    = errors.New("expecting a non-nil machine type")
+)
+
+// ChooseMachineType returns the smallest machine type with at least cpus
+// CPUs and memoryMBs of memory: the standard type that has exactly that
+// many CPUs and that much memory if there is one, otherwise a custom type.
+// For a custom type the CPU count is rounded up to 1 or an even number,
+// and the memory up to a multiple of 256MB, with extended memory if it
+// exceeds 6.5GB per CPU.
+func ChooseMachineType(cpus, memoryMBs int) (*MachineType, error) {
+	for _, st := range standardMachineTypes {
+		if st.cpuCount == cpus && st.memoryMBs == memoryMBs {
+			return &MachineType{Type: st.typ}, nil
+		}
+	}
+
+	if cpus <= 0 || cpus > 32 {
+		return nil, errInvalidZeroCount
+	}
+	if memoryMBs <= 0 {
+		return nil, errNonPositiveMemory
+	}
+	if cpus > 1 && cpus%2 != 0 {
+		cpus += 1
+	}
+	if remainder := memoryMBs % 256; remainder != 0 {
+		memoryMBs += 256 - remainder
+	}
+	mt := &MachineType{
+		CPUCount:       cpus,
+		MemoryMBs:      memoryMBs,
+		ExtendedMemory: memoryMBs > cpus*maxMemoryMBsPerCPU,
+	}
+	if err := mt.validateAsCustomMachine(); err != nil {
+		return nil, err
+	}
+	return mt, nil
+}
+
+func (c *Client) machineTypesService() *compute.MachineTypesService {
+	return compute.NewMachineTypesService(c.computeSrvc)
+}
+
+// MachineTypeAvailable reports whether the zone offers the machine type,
+// such as one returned by ChooseMachineType. Its lookup is cached for
+// Client.LookupCacheTTL if set, so that provisioning a fleet of the same
+// machine type checks it once.
+func (c *Client) MachineTypeAvailable(ctx context.Context, project, zone string, mt *MachineType) (bool, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return false, errEmptyProject
+	}
+	if err := validateZone(zone); err != nil {
+		return false, err
+	}
+	if mt == nil {
+		return false, errNilMachineType
+	}
+	if _, err := c.machineType(ctx, project, zone, mt.name()); err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
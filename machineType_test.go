@@ -0,0 +1,92 @@
+package infra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMachineTypeExtendedMemory(t *testing.T) {
+	tests := [...]struct {
+		mt        *MachineType
+		wantErr   error
+		wantRoute string
+	}{
+		0: {
+			mt:        &MachineType{CPUCount: 2, MemoryMBs: 4096},
+			wantRoute: "/machineTypes/custom-2-4096",
+		},
+		1: {
+			// 16GB is over the 13GB allowed for 2 CPUs.
+			mt:      &MachineType{CPUCount: 2, MemoryMBs: 16384},
+			wantErr: errMemoryExceedsPerCPULimit,
+		},
+		2: {
+			mt:        &MachineType{CPUCount: 2, MemoryMBs: 16384, ExtendedMemory: true},
+			wantRoute: "/machineTypes/custom-2-16384-ext",
+		},
+		3: {
+			mt:      &MachineType{CPUCount: 2, MemoryMBs: 16300, ExtendedMemory: true},
+			wantErr: errMemoryMultipleOf256,
+		},
+		4: {
+			// A type doesn't make up for invalid custom settings.
+			mt:      &MachineType{Type: N1Standard2, CPUCount: 2, MemoryMBs: 16384},
+			wantErr: errMemoryExceedsPerCPULimit,
+		},
+		5: {
+			mt:        &MachineType{Type: N1Standard2},
+			wantRoute: "/machineTypes/n1-standard-2",
+		},
+		6: {
+			mt:      &MachineType{},
+			wantErr: errEmptyType,
+		},
+	}
+
+	for i, tt := range tests {
+		err := tt.mt.Validate()
+		if err != tt.wantErr {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := tt.mt.route(); got != tt.wantRoute {
+			t.Errorf("#%d: route: got %q want %q", i, got, tt.wantRoute)
+		}
+	}
+}
+
+func TestChooseMachineType(t *testing.T) {
+	tests := []struct {
+		cpus, memoryMBs int
+		want            *MachineType
+		wantErr         error
+	}{
+		{cpus: 4, memoryMBs: 8192, want: &MachineType{CPUCount: 4, MemoryMBs: 8192}},
+		{cpus: 4, memoryMBs: 15360, want: &MachineType{Type: N1Standard4}},
+		{cpus: 1, memoryMBs: 3840, want: &MachineType{Type: N1Standard1}},
+		{cpus: 3, memoryMBs: 8000, want: &MachineType{CPUCount: 4, MemoryMBs: 8192}},
+		{cpus: 2, memoryMBs: 16384, want: &MachineType{CPUCount: 2, MemoryMBs: 16384, ExtendedMemory: true}},
+		{cpus: 0, memoryMBs: 4096, wantErr: errInvalidZeroCount},
+		{cpus: 33, memoryMBs: 4096, wantErr: errInvalidZeroCount},
+		{cpus: 2, memoryMBs: 0, wantErr: errNonPositiveMemory},
+	}
+
+	for i, tt := range tests {
+		got, err := ChooseMachineType(tt.cpus, tt.memoryMBs)
+		if err != tt.wantErr {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: got %+v want %+v", i, got, tt.want)
+		}
+		if got != nil {
+			if err := got.Validate(); err != nil {
+				t.Errorf("#%d: chose an invalid machine type: %v", i, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	startupScriptMetadataKey = "startup-script"
+	sshKeysMetadataKey       = "ssh-keys"
+	osLoginMetadataKey       = "enable-oslogin"
+)
+
+var errOSLoginWithSSHKeys = errors.New("expecting either EnableOSLogin or SSHKeys, OS Login ignores SSH keys in metadata")
+
+type generatedMetadata struct {
+	key   string
+	value string
+
+	// field is the InstanceRequest field that the key is generated from.
+	field string
+}
+
+func (ireq *InstanceRequest) generatedMetadata() []*generatedMetadata {
+	var generated []*generatedMetadata
+	if ireq.StartupScript != "" {
+		generated = append(generated, &generatedMetadata{
+			key:   startupScriptMetadataKey,
+			value: ireq.StartupScript,
+			field: "StartupScript",
+		})
+	}
+	if len(ireq.SSHKeys) > 0 {
+		generated = append(generated, &generatedMetadata{
+			key:   sshKeysMetadataKey,
+			value: strings.Join(ireq.SSHKeys, "\n"),
+			field: "SSHKeys",
+		})
+	}
+	if ireq.EnableOSLogin {
+		generated = append(generated, &generatedMetadata{
+			key:   osLoginMetadataKey,
+			value: "TRUE",
+			field: "EnableOSLogin",
+		})
+	}
+	return generated
+}
+
+func (ireq *InstanceRequest) explicitMetadataKeys() map[string]bool {
+	keys := make(map[string]bool)
+	if ireq.Metadata == nil {
+		return keys
+	}
+	for _, item := range ireq.Metadata.Items {
+		keys[item.Key] = true
+	}
+	return keys
+}
+
+// metadataFromFilesKeys returns the keys of MetadataFromFiles in order.
+func (ireq *InstanceRequest) metadataFromFilesKeys() []string {
+	var keys []string
+	for key := range ireq.MetadataFromFiles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readMetadataFromFiles reads the metadata values of MetadataFromFiles.
+func (ireq *InstanceRequest) readMetadataFromFiles() ([]*generatedMetadata, error) {
+	var fromFiles []*generatedMetadata
+	for _, key := range ireq.metadataFromFilesKeys() {
+		path := ireq.MetadataFromFiles[key]
+		blob, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("metadata key %q: %w", key, err)
+		}
+		fromFiles = append(fromFiles, &generatedMetadata{
+			key:   key,
+			value: string(blob),
+			field: "MetadataFromFiles",
+		})
+	}
+	return fromFiles, nil
+}
+
+func (ireq *InstanceRequest) validateMetadata() error {
+	if ireq.EnableOSLogin && len(ireq.SSHKeys) > 0 {
+		return errOSLoginWithSSHKeys
+	}
+	generatedBy := make(map[string]string)
+	for _, gen := range ireq.generatedMetadata() {
+		generatedBy[gen.key] = gen.field
+	}
+	for _, key := range ireq.metadataFromFilesKeys() {
+		if field, ok := generatedBy[key]; ok {
+			return fmt.Errorf("metadata key %q is set both by %s and by MetadataFromFiles", key, field)
+		}
+		generatedBy[key] = "MetadataFromFiles"
+	}
+
+	if ireq.PreferCallerMetadata {
+		return nil
+	}
+	if ireq.Metadata == nil {
+		return nil
+	}
+	for _, item := range ireq.Metadata.Items {
+		if field, ok := generatedBy[item.Key]; ok {
+			return fmt.Errorf("metadata key %q is set both in Metadata and by %s, set PreferCallerMetadata to keep the explicit value", item.Key, field)
+		}
+	}
+	return nil
+}
+
+// metadata returns the caller's metadata merged with the metadata
+// generated from fields such as StartupScript, and with fromFiles,
+// the metadata read from MetadataFromFiles. The caller's metadata
+// is never modified.
+func (ireq *InstanceRequest) metadata(fromFiles ...*generatedMetadata) *compute.Metadata {
+	generated := append(ireq.generatedMetadata(), fromFiles...)
+	if len(generated) == 0 {
+		return ireq.Metadata
+	}
+
+	merged := new(compute.Metadata)
+	if ireq.Metadata != nil {
+		merged.Fingerprint = ireq.Metadata.Fingerprint
+		merged.Items = append(merged.Items, ireq.Metadata.Items...)
+	}
+	explicitKeys := ireq.explicitMetadataKeys()
+	for _, gen := range generated {
+		if explicitKeys[gen.key] {
+			continue
+		}
+		value := gen.value
+		merged.Items = append(merged.Items, &compute.MetadataItems{Key: gen.key, Value: &value})
+	}
+	return merged
+}
+
+// InstanceMetadata returns the metadata of the instance identified by
+// ireq as a map of keys to values, such as to read back a value that
+// the instance's startup script generated. Keys without a value map
+// to the empty string.
+func (c *Client) InstanceMetadata(ctx context.Context, ireq *InstanceRequest) (map[string]string, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	instance, err := c.FindInstance(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if instance.Metadata == nil {
+		return values, nil
+	}
+	for _, item := range instance.Metadata.Items {
+		value := ""
+		if item.Value != nil {
+			value = *item.Value
+		}
+		values[item.Key] = value
+	}
+	return values, nil
+}
+
+// maxMetadataFingerprintRetries is the number of times that
+// RemoveInstanceMetadataKeys retries its read-modify-write after
+// losing a race to a concurrent writer.
+const maxMetadataFingerprintRetries = 3
+
+var errNoMetadataKeys = errors.New("expecting at least one metadata key")
+
+// RemoveInstanceMetadataKeys removes the metadata keys from the instance
+// identified by ireq, such as to rotate out a startup script, keeping the
+// instance's other metadata. Like SetInstanceLabels the update is conditioned
+// on the metadata's fingerprint and retried if the metadata changed between
+// reading and writing it. If the instance has none of the keys, its metadata
+// isn't written and a nil operation is returned.
+func (c *Client) RemoveInstanceMetadataKeys(ctx context.Context, ireq *InstanceRequest, keys ...string) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errNoMetadataKeys
+	}
+	toRemove := make(map[string]bool)
+	for _, key := range keys {
+		toRemove[key] = true
+	}
+
+	for i := 0; ; i++ {
+		instance, err := c.FindInstance(ctx, ireq)
+		if err != nil {
+			return nil, err
+		}
+		if instance.Metadata == nil {
+			return nil, nil
+		}
+
+		kept := &compute.Metadata{Fingerprint: instance.Metadata.Fingerprint}
+		for _, item := range instance.Metadata.Items {
+			if !toRemove[item.Key] {
+				kept.Items = append(kept.Items, item)
+			}
+		}
+		if len(kept.Items) == len(instance.Metadata.Items) {
+			return nil, nil
+		}
+
+		req := c.instancesService().SetMetadata(ireq.Project, ireq.Zone, ireq.Name, kept)
+		op, err := req.Context(ctx).Do()
+		if err == nil || i >= maxMetadataFingerprintRetries || !isFingerprintMismatch(err) {
+			return op, err
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func metadataValues(md *compute.Metadata) map[string]string {
+	values := make(map[string]string)
+	for _, item := range md.Items {
+		if item.Value != nil {
+			values[item.Key] = *item.Value
+		}
+	}
+	return values
+}
+
+func TestGeneratedMetadata(t *testing.T) {
+	env := "production"
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "web",
+
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		Metadata: &compute.Metadata{
+			Items: []*compute.MetadataItems{{Key: "env", Value: &env}},
+		},
+		StartupScript: "#!/bin/sh\necho hello",
+		SSHKeys:       []string{"alice:ssh-ed25519 AAAA alice", "bob:ssh-ed25519 BBBB bob"},
+	}
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	got := metadataValues(ireq.toInstance().Metadata)
+	want := map[string]string{
+		"env":            "production",
+		"startup-script": "#!/bin/sh\necho hello",
+		"ssh-keys":       "alice:ssh-ed25519 AAAA alice\nbob:ssh-ed25519 BBBB bob",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%q: got %q want %q", key, got[key], value)
+		}
+	}
+	if n := len(ireq.Metadata.Items); n != 1 {
+		t.Errorf("the caller's metadata was modified, it now has %d items", n)
+	}
+}
+
+func TestGeneratedMetadataCollision(t *testing.T) {
+	explicitScript := "#!/bin/sh\necho explicit"
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "web",
+
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		Metadata: &compute.Metadata{
+			Items: []*compute.MetadataItems{{Key: "startup-script", Value: &explicitScript}},
+		},
+		StartupScript: "#!/bin/sh\necho generated",
+	}
+
+	err := ireq.validateForCreate()
+	if err == nil || !strings.Contains(err.Error(), `"startup-script"`) || !strings.Contains(err.Error(), "StartupScript") {
+		t.Fatalf("got err %v, want a startup-script collision error", err)
+	}
+
+	ireq.PreferCallerMetadata = true
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("PreferCallerMetadata: unexpected err: %v", err)
+	}
+	md := ireq.toInstance().Metadata
+	if len(md.Items) != 1 || metadataValues(md)["startup-script"] != explicitScript {
+		t.Errorf("expected only the explicit startup-script, got %+v", md.Items)
+	}
+}
+
+func TestEnableOSLogin(t *testing.T) {
+	env := "production"
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "web",
+
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		Metadata: &compute.Metadata{
+			Items: []*compute.MetadataItems{{Key: "env", Value: &env}},
+		},
+		EnableOSLogin: true,
+	}
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	got := metadataValues(ireq.toInstance().Metadata)
+	if got["enable-oslogin"] != "TRUE" || got["env"] != "production" {
+		t.Errorf("got metadata %q, want enable-oslogin set along with env", got)
+	}
+
+	ireq.SSHKeys = []string{"alice:ssh-ed25519 AAAA alice"}
+	if err := ireq.validateForCreate(); err != errOSLoginWithSSHKeys {
+		t.Errorf("with SSHKeys: got err %v want %v", err, errOSLoginWithSSHKeys)
+	}
+}
+
+func TestMetadataFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "startup.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "web",
+
+		NetworkInterface:  BasicExternalNATNetworkInterface,
+		SSHKeys:           []string{"alice:ssh-ed25519 AAAA alice"},
+		MetadataFromFiles: map[string]string{"startup-script": scriptPath},
+	}
+	instance, err := ireq.buildInstance()
+	if err != nil {
+		t.Fatalf("buildInstance: %v", err)
+	}
+	got := metadataValues(instance.Metadata)
+	if want := "#!/bin/sh\necho from file"; got["startup-script"] != want {
+		t.Errorf("startup-script: got %q want %q", got["startup-script"], want)
+	}
+	if want := "alice:ssh-ed25519 AAAA alice"; got["ssh-keys"] != want {
+		t.Errorf("ssh-keys: got %q want %q", got["ssh-keys"], want)
+	}
+
+	// An unreadable file fails the build with the underlying error.
+	ireq.MetadataFromFiles["config"] = filepath.Join(dir, "missing.conf")
+	_, err = ireq.buildInstance()
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), `"config"`) {
+		t.Errorf("missing file: got err %v, want a wrapped not-exist error naming the key", err)
+	}
+
+	// Keys read from files collide with generated keys.
+	delete(ireq.MetadataFromFiles, "config")
+	ireq.StartupScript = "#!/bin/sh\necho inline"
+	if _, err := ireq.buildInstance(); err == nil || !strings.Contains(err.Error(), "MetadataFromFiles") {
+		t.Errorf("collision: got err %v, want one mentioning MetadataFromFiles", err)
+	}
+}
+
+func TestInstanceMetadata(t *testing.T) {
+	token := "s3cr3t"
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "GET /compute/v1/projects/sample/zones/us-central1-c/instances/frontend" {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, &compute.Instance{
+			Name: "frontend",
+			Metadata: &compute.Metadata{
+				Items: []*compute.MetadataItems{
+					{Key: "generated-token", Value: &token},
+					{Key: "enable-guest-attributes"},
+				},
+			},
+		})
+	})
+
+	ctx := context.Background()
+	got, err := client.InstanceMetadata(ctx, &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "frontend"})
+	if err != nil {
+		t.Fatalf("InstanceMetadata: %v", err)
+	}
+	want := map[string]string{"generated-token": "s3cr3t", "enable-guest-attributes": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+
+	if _, err := client.InstanceMetadata(ctx, &InstanceRequest{Project: "sample", Zone: "us-central1-c"}); err != errBlankName {
+		t.Errorf("blank name: got err %v want %v", err, errBlankName)
+	}
+}
+
+func TestRemoveInstanceMetadataKeys(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/frontend"
+	oldScript, token, fingerprint := "echo old", "s3cr3t", "fp-1"
+	var set *compute.Metadata
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + instancePath:
+			writeJSON(w, &compute.Instance{
+				Name: "frontend",
+				Metadata: &compute.Metadata{
+					Fingerprint: fingerprint,
+					Items: []*compute.MetadataItems{
+						{Key: startupScriptMetadataKey, Value: &oldScript},
+						{Key: "generated-token", Value: &token},
+						{Key: "enable-guest-attributes"},
+					},
+				},
+			})
+		case "POST " + instancePath + "/setMetadata":
+			md := new(compute.Metadata)
+			readJSON(t, req, md)
+			if md.Fingerprint != fingerprint {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": http.StatusPreconditionFailed, "message": "fingerprint mismatch"}})
+				return
+			}
+			set = md
+			writeJSON(w, &compute.Operation{Name: "op-metadata"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "frontend"}
+	ctx := context.Background()
+	op, err := client.RemoveInstanceMetadataKeys(ctx, ireq, startupScriptMetadataKey, "enable-guest-attributes", "missing")
+	if err != nil {
+		t.Fatalf("RemoveInstanceMetadataKeys: %v", err)
+	}
+	if op == nil || set == nil {
+		t.Fatalf("expected the metadata to be set")
+	}
+	if set.Fingerprint != "fp-1" {
+		t.Errorf("fingerprint: got %q want %q", set.Fingerprint, "fp-1")
+	}
+	if len(set.Items) != 1 || set.Items[0].Key != "generated-token" || *set.Items[0].Value != token {
+		t.Errorf("expected only the token to remain, got %+v", set.Items)
+	}
+
+	set = nil
+	if op, err := client.RemoveInstanceMetadataKeys(ctx, ireq, "missing"); op != nil || err != nil || set != nil {
+		t.Errorf("missing key: got op %v err %v, want no write", op, err)
+	}
+	if _, err := client.RemoveInstanceMetadataKeys(ctx, ireq); err != errNoMetadataKeys {
+		t.Errorf("no keys: got err %v want %v", err, errNoMetadataKeys)
+	}
+}
@@ -0,0 +1,41 @@
+package infra
+
+import (
+	"context"
+	"path"
+)
+
+// ZoneNameServers returns the name servers that Cloud DNS assigned to the
+// managed zone, which are what to configure at the domain's registrar.
+// They don't change over the lifetime of the zone so they are fetched
+// once and then cached by the client.
+func (c *Client) ZoneNameServers(ctx context.Context, project, zone string) ([]string, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	if zone == "" {
+		return nil, errEmptyZone
+	}
+
+	key := path.Join(project, zone)
+	c.nameServersMu.Lock()
+	nameServers, ok := c.nameServers[key]
+	c.nameServersMu.Unlock()
+	if ok {
+		return nameServers[:len(nameServers):len(nameServers)], nil
+	}
+
+	mz, err := c.managedZonesService().Get(project, zone).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	c.nameServersMu.Lock()
+	if c.nameServers == nil {
+		c.nameServers = make(map[string][]string)
+	}
+	c.nameServers[key] = mz.NameServers
+	c.nameServersMu.Unlock()
+	return mz.NameServers[:len(mz.NameServers):len(mz.NameServers)], nil
+}
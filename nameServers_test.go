@@ -0,0 +1,41 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/dns/v1"
+)
+
+func TestZoneNameServers(t *testing.T) {
+	nameServers := []string{"ns-cloud-a1.googledomains.com.", "ns-cloud-a2.googledomains.com."}
+	gets := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if route := req.Method + " " + req.URL.Path; route != "GET "+fakeZonePath {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		gets++
+		writeJSON(w, &dns.ManagedZone{Name: "zone", DnsName: "orijtech.com.", NameServers: nameServers})
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		got, err := client.ZoneNameServers(ctx, "sample", "zone")
+		if err != nil {
+			t.Fatalf("#%d: ZoneNameServers: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, nameServers) {
+			t.Errorf("#%d: got %q want %q", i, got, nameServers)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("zone fetches: got %d want 1, the name servers should be cached", gets)
+	}
+
+	if _, err := client.ZoneNameServers(ctx, "sample", ""); err != errEmptyZone {
+		t.Errorf("got err %v want %v", err, errEmptyZone)
+	}
+}
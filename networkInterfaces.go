@@ -1,6 +1,9 @@
 package infra
 
 import (
+	"context"
+	"errors"
+
 	"google.golang.org/api/compute/v1"
 )
 
@@ -14,3 +17,146 @@ var (
 		},
 	}
 )
+
+// ExternalNATInterface returns a network interface like
+// BasicExternalNATNetworkInterface but whose external IP is natIP,
+// for example a reserved static address. If natIP is empty,
+// an ephemeral external IP is assigned instead.
+func ExternalNATInterface(natIP string) *compute.NetworkInterface {
+	return &compute.NetworkInterface{
+		AccessConfigs: []*compute.AccessConfig{
+			{
+				Name:  "External NAT",
+				Type:  "ONE_TO_ONE_NAT",
+				NatIP: natIP,
+			},
+		},
+	}
+}
+
+// NetworkConfig describes the network and subnetwork that an instance's
+// network interface attaches to. With HostProject set, they belong to
+// that shared VPC host project rather than to the instance's project.
+type NetworkConfig struct {
+	// Project is the project that the instance is created in.
+	Project string `json:"project"`
+
+	// HostProject if set is the shared VPC host project that owns
+	// Network and Subnetwork. Project must then be one of its service
+	// projects. If unset, Network and Subnetwork belong to Project.
+	HostProject string `json:"host_project,omitempty"`
+
+	Region     string `json:"region"`
+	Network    string `json:"network,omitempty"`
+	Subnetwork string `json:"subnetwork"`
+
+	// External when set gives the interface an external IP,
+	// NatIP if set otherwise an ephemeral one.
+	External bool   `json:"external,omitempty"`
+	NatIP    string `json:"nat_ip,omitempty"`
+}
+
+var (
+	errBlankRegion     = errors.New("expecting a non-blank region")
+	errBlankSubnetwork = errors.New("expecting a non-blank subnetwork")
+)
+
+func (nc *NetworkConfig) Validate() error {
+	if nc == nil || nc.Project == "" {
+		return errEmptyProject
+	}
+	if nc.Region == "" {
+		return errBlankRegion
+	}
+	if nc.Subnetwork == "" {
+		return errBlankSubnetwork
+	}
+	return nil
+}
+
+// networkProject returns the project that owns the network and subnetwork.
+func (nc *NetworkConfig) networkProject() string {
+	if nc.HostProject != "" {
+		return nc.HostProject
+	}
+	return nc.Project
+}
+
+// NetworkInterface returns the network interface to set as
+// InstanceRequest.NetworkInterface to attach to the configured subnetwork.
+func (nc *NetworkConfig) NetworkInterface() (*compute.NetworkInterface, error) {
+	if err := nc.Validate(); err != nil {
+		return nil, err
+	}
+
+	nic := new(compute.NetworkInterface)
+	if nc.External {
+		nic = ExternalNATInterface(nc.NatIP)
+	}
+
+	project := nc.networkProject()
+	if nc.Network != "" {
+		nic.Network = SelfLink(project, "global", "networks", nc.Network)
+	}
+	nic.Subnetwork = SelfLink(project, "regions", nc.Region, "subnetworks", nc.Subnetwork)
+	return nic, nil
+}
+
+var (
+	errNoNetworkInterfaces = errors.New("instance has no network interfaces")
+	errNoExternalIP        = errors.New("instance's primary network interface has no external IP")
+	errHasExternalIP       = errors.New("instance's primary network interface already has an external IP")
+)
+
+// primaryNetworkInterface returns the first network interface, "nic0",
+// of the instance identified by ireq.
+func (c *Client) primaryNetworkInterface(ctx context.Context, ireq *InstanceRequest) (*compute.NetworkInterface, error) {
+	instance, err := c.FindInstance(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	if len(instance.NetworkInterfaces) == 0 {
+		return nil, errNoNetworkInterfaces
+	}
+	return instance.NetworkInterfaces[0], nil
+}
+
+// RemoveExternalIP removes the external IP of the instance's
+// primary network interface, leaving it reachable only internally.
+func (c *Client) RemoveExternalIP(ctx context.Context, ireq *InstanceRequest) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+	nic, err := c.primaryNetworkInterface(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	if len(nic.AccessConfigs) == 0 {
+		return nil, errNoExternalIP
+	}
+
+	req := c.instancesService().DeleteAccessConfig(ireq.Project, ireq.Zone, ireq.Name, nic.AccessConfigs[0].Name, nic.Name)
+	return req.Context(ctx).Do()
+}
+
+// AddExternalIP attaches an external IP to the primary network interface
+// of an instance that doesn't have one. natIP can be a reserved static
+// address or empty for an ephemeral one.
+func (c *Client) AddExternalIP(ctx context.Context, ireq *InstanceRequest, natIP string) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+	nic, err := c.primaryNetworkInterface(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	if len(nic.AccessConfigs) > 0 {
+		return nil, errHasExternalIP
+	}
+
+	accessConfig := ExternalNATInterface(natIP).AccessConfigs[0]
+	req := c.instancesService().AddAccessConfig(ireq.Project, ireq.Zone, ireq.Name, nic.Name, accessConfig)
+	return req.Context(ctx).Do()
+}
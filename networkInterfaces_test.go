@@ -0,0 +1,194 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestExternalNATInterface(t *testing.T) {
+	tests := [...]struct {
+		natIP string
+	}{
+		0: {natIP: "35.192.0.10"},
+		1: {natIP: ""},
+	}
+
+	for i, tt := range tests {
+		nic := ExternalNATInterface(tt.natIP)
+		if len(nic.AccessConfigs) != 1 {
+			t.Errorf("#%d: got %d access configs want 1", i, len(nic.AccessConfigs))
+			continue
+		}
+		ac := nic.AccessConfigs[0]
+		if ac.Type != "ONE_TO_ONE_NAT" {
+			t.Errorf("#%d: type: got %q want %q", i, ac.Type, "ONE_TO_ONE_NAT")
+		}
+		if ac.NatIP != tt.natIP {
+			t.Errorf("#%d: natIP: got %q want %q", i, ac.NatIP, tt.natIP)
+		}
+	}
+
+	// The shared basic interface must never be mutated.
+	ExternalNATInterface("35.192.0.11")
+	if got := BasicExternalNATNetworkInterface.AccessConfigs[0].NatIP; got != "" {
+		t.Errorf("BasicExternalNATNetworkInterface was modified, natIP: %q", got)
+	}
+}
+
+func TestRemoveExternalIP(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/frontend"
+	var accessConfig, networkInterface string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + instancePath:
+			writeJSON(w, &compute.Instance{
+				Name: "frontend",
+				NetworkInterfaces: []*compute.NetworkInterface{
+					{
+						Name: "nic0",
+						AccessConfigs: []*compute.AccessConfig{
+							{Name: "external-nat", Type: "ONE_TO_ONE_NAT", NatIP: "35.192.0.10"},
+						},
+					},
+					{Name: "nic1"},
+				},
+			})
+		case "POST " + instancePath + "/deleteAccessConfig":
+			accessConfig = req.URL.Query().Get("accessConfig")
+			networkInterface = req.URL.Query().Get("networkInterface")
+			writeJSON(w, &compute.Operation{Name: "op-delete-access-config"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	_, err := client.RemoveExternalIP(context.Background(), &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "frontend",
+	})
+	if err != nil {
+		t.Fatalf("RemoveExternalIP: %v", err)
+	}
+	if accessConfig != "external-nat" {
+		t.Errorf("accessConfig: got %q want %q", accessConfig, "external-nat")
+	}
+	if networkInterface != "nic0" {
+		t.Errorf("networkInterface: got %q want %q", networkInterface, "nic0")
+	}
+}
+
+func TestRemoveExternalIPWithoutOne(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		writeJSON(w, &compute.Instance{
+			Name:              "backend",
+			NetworkInterfaces: []*compute.NetworkInterface{{Name: "nic0"}},
+		})
+	})
+
+	_, err := client.RemoveExternalIP(context.Background(), &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "backend",
+	})
+	if err != errNoExternalIP {
+		t.Errorf("got err %v want %v", err, errNoExternalIP)
+	}
+}
+
+func TestAddExternalIP(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/backend"
+	var networkInterface string
+	var accessConfig *compute.AccessConfig
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + instancePath:
+			writeJSON(w, &compute.Instance{
+				Name:              "backend",
+				NetworkInterfaces: []*compute.NetworkInterface{{Name: "nic0"}},
+			})
+		case "POST " + instancePath + "/addAccessConfig":
+			networkInterface = req.URL.Query().Get("networkInterface")
+			accessConfig = new(compute.AccessConfig)
+			readJSON(t, req, accessConfig)
+			writeJSON(w, &compute.Operation{Name: "op-add-access-config"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "backend"}
+	for _, natIP := range []string{"35.192.0.10", ""} {
+		accessConfig = nil
+		if _, err := client.AddExternalIP(context.Background(), ireq, natIP); err != nil {
+			t.Fatalf("natIP=%q: AddExternalIP: %v", natIP, err)
+		}
+		if networkInterface != "nic0" {
+			t.Errorf("natIP=%q: networkInterface: got %q want %q", natIP, networkInterface, "nic0")
+		}
+		if accessConfig == nil || accessConfig.Type != "ONE_TO_ONE_NAT" || accessConfig.NatIP != natIP {
+			t.Errorf("natIP=%q: unexpected access config: %+v", natIP, accessConfig)
+		}
+	}
+}
+
+func TestNetworkConfigSharedVPC(t *testing.T) {
+	nc := &NetworkConfig{
+		Project:     "service-project",
+		HostProject: "host-project",
+		Region:      "us-central1",
+		Network:     "shared-vpc",
+		Subnetwork:  "apps",
+	}
+	nic, err := nc.NetworkInterface()
+	if err != nil {
+		t.Fatalf("NetworkInterface: %v", err)
+	}
+	if want := "https://www.googleapis.com/compute/v1/projects/host-project/regions/us-central1/subnetworks/apps"; nic.Subnetwork != want {
+		t.Errorf("subnetwork:\ngot  %q\nwant %q", nic.Subnetwork, want)
+	}
+	if want := "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/shared-vpc"; nic.Network != want {
+		t.Errorf("network:\ngot  %q\nwant %q", nic.Network, want)
+	}
+	if len(nic.AccessConfigs) != 0 {
+		t.Errorf("expected an internal-only interface, got access configs %+v", nic.AccessConfigs)
+	}
+
+	// Without a host project, the service project's own network is used.
+	nc.HostProject = ""
+	nc.External = true
+	nic, err = nc.NetworkInterface()
+	if err != nil {
+		t.Fatalf("NetworkInterface: %v", err)
+	}
+	if want := "https://www.googleapis.com/compute/v1/projects/service-project/regions/us-central1/subnetworks/apps"; nic.Subnetwork != want {
+		t.Errorf("subnetwork:\ngot  %q\nwant %q", nic.Subnetwork, want)
+	}
+	if len(nic.AccessConfigs) != 1 {
+		t.Errorf("expected an external access config, got %+v", nic.AccessConfigs)
+	}
+}
+
+func TestNetworkConfigValidate(t *testing.T) {
+	tests := [...]struct {
+		nc   *NetworkConfig
+		want error
+	}{
+		0: {nc: nil, want: errEmptyProject},
+		1: {nc: &NetworkConfig{HostProject: "host-project", Region: "us-central1", Subnetwork: "apps"}, want: errEmptyProject},
+		2: {nc: &NetworkConfig{Project: "service-project", Subnetwork: "apps"}, want: errBlankRegion},
+		3: {nc: &NetworkConfig{Project: "service-project", Region: "us-central1"}, want: errBlankSubnetwork},
+	}
+
+	for i, tt := range tests {
+		if _, err := tt.nc.NetworkInterface(); err != tt.want {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.want)
+		}
+	}
+}
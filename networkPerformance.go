@@ -0,0 +1,62 @@
+package infra
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	defaultNetworkPerformanceTier = "DEFAULT"
+	tier1NetworkPerformanceTier   = "TIER_1"
+)
+
+// tier1MachineFamilies are the machine families
+// that support TIER_1 networking performance.
+var tier1MachineFamilies = map[string]bool{
+	"n2":  true,
+	"n2d": true,
+	"c2":  true,
+	"c2d": true,
+	"c3":  true,
+	"c3d": true,
+	"m3":  true,
+}
+
+// family returns the machine family e.g "n2" for "n2-standard-32". Custom
+// machines without a family prefix, such as "custom-2-4096", are N1 machines.
+func (mt *MachineType) family() string {
+	name := mt.name()
+	if strings.HasPrefix(name, "custom-") {
+		return "n1"
+	}
+	if i := strings.Index(name, "-"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func (ireq *InstanceRequest) validateNetworkPerformanceTier() error {
+	switch ireq.NetworkPerformanceTier {
+	case "", defaultNetworkPerformanceTier:
+		return nil
+	case tier1NetworkPerformanceTier:
+		mt := ireq.machineTypeOrDefault()
+		if !tier1MachineFamilies[mt.family()] {
+			return fmt.Errorf("machine type %q doesn't support %s networking, expecting one of the N2, N2D, C2, C2D, C3, C3D or M3 families",
+				mt.name(), tier1NetworkPerformanceTier)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown network performance tier %q, expecting %q or %q",
+			ireq.NetworkPerformanceTier, defaultNetworkPerformanceTier, tier1NetworkPerformanceTier)
+	}
+}
+
+func (ireq *InstanceRequest) networkPerformanceConfig() *compute.NetworkPerformanceConfig {
+	if ireq.NetworkPerformanceTier == "" {
+		return nil
+	}
+	return &compute.NetworkPerformanceConfig{TotalEgressBandwidthTier: ireq.NetworkPerformanceTier}
+}
@@ -0,0 +1,55 @@
+package infra
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNetworkPerformanceTier(t *testing.T) {
+	newRequest := func(tier string, mt *MachineType) *InstanceRequest {
+		return &InstanceRequest{
+			Project: "sample",
+			Zone:    "us-central1-c",
+			Name:    "web",
+
+			NetworkInterface:       BasicExternalNATNetworkInterface,
+			MachineType:            mt,
+			NetworkPerformanceTier: tier,
+		}
+	}
+
+	ireq := newRequest("TIER_1", &MachineType{Type: "n2-standard-32"})
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	npc := ireq.toInstance().NetworkPerformanceConfig
+	if npc == nil || npc.TotalEgressBandwidthTier != "TIER_1" {
+		t.Errorf("got network performance config %+v want the TIER_1 tier", npc)
+	}
+	if npc := newRequest("", nil).toInstance().NetworkPerformanceConfig; npc != nil {
+		t.Errorf("expected no network performance config by default, got %+v", npc)
+	}
+
+	tests := []struct {
+		ireq    *InstanceRequest
+		wantErr string
+	}{
+		{ireq: newRequest("DEFAULT", nil)},
+		{ireq: newRequest("TIER_1", &MachineType{Type: "c3-highcpu-44"})},
+		{ireq: newRequest("TIER_1", nil), wantErr: `"n1-standard-1" doesn't support TIER_1`},
+		{ireq: newRequest("TIER_1", &MachineType{CPUCount: 32, MemoryMBs: 32768}), wantErr: "doesn't support TIER_1"},
+		{ireq: newRequest("TIER_2", nil), wantErr: "unknown network performance tier"},
+	}
+	for i, tt := range tests {
+		err := tt.ireq.validateForCreate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected err: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("#%d: got err %v, want it to contain %q", i, err, tt.wantErr)
+		}
+	}
+}
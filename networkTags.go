@@ -0,0 +1,38 @@
+package infra
+
+import (
+	"fmt"
+	"regexp"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// maxNetworkTags is the most network tags that an instance can have.
+const maxNetworkTags = 64
+
+// networkTagRegexp matches the valid network tags, which are RFC 1035
+// labels: lowercase letters, digits and dashes, starting with a letter
+// and not ending with a dash, up to 63 characters long.
+var networkTagRegexp = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+func validateNetworkTags(tags []string) error {
+	uniqs := dedup(tags...)
+	if len(uniqs) > maxNetworkTags {
+		return fmt.Errorf("got %d network tags, expecting at most %d", len(uniqs), maxNetworkTags)
+	}
+	for _, tag := range uniqs {
+		if !networkTagRegexp.MatchString(tag) {
+			return fmt.Errorf("%q is not a valid network tag, expecting one such as %q", tag, "http-server")
+		}
+	}
+	return nil
+}
+
+// tags returns the instance's network tags, deduplicated, if any.
+func (ireq *InstanceRequest) tags() *compute.Tags {
+	uniqs := dedup(ireq.Tags...)
+	if len(uniqs) == 0 {
+		return nil
+	}
+	return &compute.Tags{Items: uniqs}
+}
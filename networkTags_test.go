@@ -0,0 +1,63 @@
+package infra
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInstanceNetworkTags(t *testing.T) {
+	ireq := &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		Tags:             []string{"http-server", " https-server ", "http-server", ""},
+	}
+	if err := ireq.validateForCreate(); err != nil {
+		t.Fatalf("validateForCreate: %v", err)
+	}
+
+	want := []string{"http-server", "https-server"}
+	if got := ireq.toInstance().Tags; got == nil || !reflect.DeepEqual(got.Items, want) {
+		t.Errorf("toInstance tags: got %+v want %q", got, want)
+	}
+	if got := ireq.toInstanceProperties(nil).Tags; got == nil || !reflect.DeepEqual(got.Items, want) {
+		t.Errorf("toInstanceProperties tags: got %+v want %q", got, want)
+	}
+
+	ireq.Tags = nil
+	if got := ireq.toInstance().Tags; got != nil {
+		t.Errorf("without tags: got %+v want nil", got)
+	}
+}
+
+func TestValidateNetworkTags(t *testing.T) {
+	tooMany := make([]string, maxNetworkTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+
+	tests := []struct {
+		name    string
+		tags    []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "valid", tags: []string{"http-server", "a", "db1"}},
+		{name: "uppercase", tags: []string{"HTTP-server"}, wantErr: true},
+		{name: "leading digit", tags: []string{"1server"}, wantErr: true},
+		{name: "trailing dash", tags: []string{"server-"}, wantErr: true},
+		{name: "underscore", tags: []string{"http_server"}, wantErr: true},
+		{name: "64 characters", tags: []string{"a" + fmt.Sprintf("%063d", 0)}, wantErr: true},
+		{name: "too many", tags: tooMany, wantErr: true},
+		{name: "duplicates within the limit", tags: append(tooMany[:maxNetworkTags:maxNetworkTags], tooMany[0])},
+	}
+
+	for _, tt := range tests {
+		err := validateNetworkTags(tt.tags)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("%s: got err %v wantErr %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
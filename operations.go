@@ -0,0 +1,229 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var errNilOperation = errors.New("expecting a non-nil operation")
+
+func (c *Client) zoneOperationsService() *compute.ZoneOperationsService {
+	return compute.NewZoneOperationsService(c.computeSrvc)
+}
+
+// waitForZoneOperation waits for the zonal operation until it is done,
+// giving up after Client.OperationPollTimeout. It returns the done
+// operation, or the operation's errors if it failed.
+func (c *Client) waitForZoneOperation(ctx context.Context, project, zone string, op *compute.Operation) (*compute.Operation, error) {
+	return c.WaitForZoneOperation(ctx, project, zone, op, nil)
+}
+
+// WaitForZoneOperation waits for the zonal operation, such as one returned
+// by CreateInstance, until it is done like WaitForOperation, giving up
+// after Client.OperationPollTimeout. If onProgress is set, it is invoked
+// with the operation's Progress, from 0 to 100, whenever it advances. It
+// returns the done operation, or the operation's errors if it failed.
+func (c *Client) WaitForZoneOperation(ctx context.Context, project, zone string, op *compute.Operation, onProgress func(pct int64)) (*compute.Operation, error) {
+	project = c.projectOrDefault(project)
+	return c.pollOperation(ctx, op, onProgress, operationCalls{
+		wait: func(ctx context.Context, name string) (*compute.Operation, error) {
+			return c.zoneOperationsService().Wait(project, zone, name).Context(ctx).Do()
+		},
+		get: func(ctx context.Context, name string) (*compute.Operation, error) {
+			return c.zoneOperationsService().Get(project, zone, name).Context(ctx).Do()
+		},
+	})
+}
+
+func (c *Client) regionOperationsService() *compute.RegionOperationsService {
+	return compute.NewRegionOperationsService(c.computeSrvc)
+}
+
+// waitForRegionOperation is like waitForZoneOperation but for regional
+// operations, such as those that reserve addresses.
+func (c *Client) waitForRegionOperation(ctx context.Context, project, region string, op *compute.Operation) (*compute.Operation, error) {
+	return c.pollOperation(ctx, op, nil, operationCalls{
+		wait: func(ctx context.Context, name string) (*compute.Operation, error) {
+			return c.regionOperationsService().Wait(project, region, name).Context(ctx).Do()
+		},
+		get: func(ctx context.Context, name string) (*compute.Operation, error) {
+			return c.regionOperationsService().Get(project, region, name).Context(ctx).Do()
+		},
+	})
+}
+
+func (c *Client) globalOperationsService() *compute.GlobalOperationsService {
+	return compute.NewGlobalOperationsService(c.computeSrvc)
+}
+
+// waitForGlobalOperation is like waitForZoneOperation but for global
+// operations, such as those that create firewall rules and images.
+func (c *Client) waitForGlobalOperation(ctx context.Context, project string, op *compute.Operation) (*compute.Operation, error) {
+	return c.pollOperation(ctx, op, nil, operationCalls{
+		wait: func(ctx context.Context, name string) (*compute.Operation, error) {
+			return c.globalOperationsService().Wait(project, name).Context(ctx).Do()
+		},
+		get: func(ctx context.Context, name string) (*compute.Operation, error) {
+			return c.globalOperationsService().Get(project, name).Context(ctx).Do()
+		},
+	})
+}
+
+// WaitForOperation waits for the operation until it is done on the
+// operations' Wait endpoint, or by polling it where that isn't
+// supported, giving up after Client.OperationPollTimeout. Zonal and
+// regional operations are waited on in the zone or region that they
+// name, operations that name neither in zone if set, otherwise as
+// global operations. It returns
+// the done operation, or the operation's errors if it failed.
+func (c *Client) WaitForOperation(ctx context.Context, project, zone string, op *compute.Operation) (*compute.Operation, error) {
+	if op == nil {
+		return nil, errNilOperation
+	}
+	project = c.projectOrDefault(project)
+	switch {
+	case op.Zone != "":
+		return c.waitForZoneOperation(ctx, project, lastURLSegment(op.Zone), op)
+	case op.Region != "":
+		return c.waitForRegionOperation(ctx, project, lastURLSegment(op.Region), op)
+	case zone != "":
+		return c.waitForZoneOperation(ctx, project, zone, op)
+	default:
+		return c.waitForGlobalOperation(ctx, project, op)
+	}
+}
+
+// maxOperationPollBackoff is how many times Client.OperationPollInterval
+// the wait between polls of a long running operation grows to.
+const maxOperationPollBackoff = 4
+
+// pollBackoff returns the backoff between the polls of waiters, from
+// Client.OperationPollInterval up to maxOperationPollBackoff times that.
+func (c *Client) pollBackoff() *Backoff {
+	interval := c.operationPollInterval()
+	return &Backoff{Initial: interval, Max: maxOperationPollBackoff * interval, Factor: 1.5}
+}
+
+// operationCalls are the calls that fetch an operation by name in its
+// scope: wait blocks server-side until the operation is done, or for
+// about 2 minutes, while get returns it right away.
+type operationCalls struct {
+	wait func(ctx context.Context, name string) (*compute.Operation, error)
+	get  func(ctx context.Context, name string) (*compute.Operation, error)
+}
+
+// pollOperation waits for the operation until it is done, re-invoking
+// the Wait endpoint for as long as the operation takes, but at most
+// every Client.OperationPollInterval in case it returns early. If the
+// Wait endpoint isn't supported, it polls with get instead, waiting
+// Client.OperationPollInterval before the first poll and increasingly
+// longer, up to maxOperationPollBackoff times that, before later ones.
+func (c *Client) pollOperation(ctx context.Context, op *compute.Operation, onProgress func(pct int64), calls operationCalls) (*compute.Operation, error) {
+	if op == nil {
+		return nil, errNilOperation
+	}
+	timeout := c.operationPollTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	// waitCtx bounds the server-side waits by the timeout too.
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	timedOut := func() error {
+		return fmt.Errorf("operation %q did not complete within %s", op.Name, timeout)
+	}
+
+	interval := c.operationPollInterval()
+	backoff := c.pollBackoff()
+	wait := calls.wait
+	lastProgress := int64(-1)
+	for {
+		if onProgress != nil && op.Progress > lastProgress {
+			lastProgress = op.Progress
+			onProgress(op.Progress)
+		}
+		if err := operationError(op); err != nil {
+			return op, err
+		}
+		if op.Status == "DONE" {
+			return op, nil
+		}
+
+		if wait != nil {
+			started := time.Now()
+			waited, err := wait(waitCtx, op.Name)
+			switch {
+			case err == nil:
+				op = waited
+				if op.Status != "DONE" {
+					// Don't spin if the operation isn't waited on for long.
+					select {
+					case <-ctx.Done():
+						return op, ctx.Err()
+					case <-timer.C:
+						return op, timedOut()
+					case <-time.After(interval - time.Since(started)):
+					}
+				}
+				continue
+			case ctx.Err() != nil:
+				return op, ctx.Err()
+			case waitCtx.Err() != nil:
+				return op, timedOut()
+			case !isWaitUnsupported(err):
+				return nil, err
+			}
+			wait = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return op, ctx.Err()
+		case <-timer.C:
+			return op, timedOut()
+		case <-time.After(backoff.Next()):
+		}
+
+		var err error
+		op, err = calls.get(ctx, op.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isWaitUnsupported reports whether err is the response of a server,
+// such as an emulator, that doesn't implement the operations' Wait
+// endpoint, in which case operations are polled instead. Not found
+// isn't one of them since it means that the operation doesn't exist,
+// which polling would only report again.
+func isWaitUnsupported(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	switch gerr.Code {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	default:
+		return false
+	}
+}
+
+// operationError returns the errors that the operation failed with if any.
+func operationError(op *compute.Operation) error {
+	if op == nil || op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+	var msgs []string
+	for _, oe := range op.Error.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", oe.Code, oe.Message))
+	}
+	return fmt.Errorf("operation %q failed: %s", op.Name, strings.Join(msgs, "; "))
+}
@@ -0,0 +1,259 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestWaitForZoneOperationProgress(t *testing.T) {
+	const opPath = "/compute/v1/projects/sample/zones/us-central1-c/operations/op-insert"
+
+	// Each poll reports the operation further along, with a repeat
+	// of 50 that mustn't be reported again.
+	polled := []*compute.Operation{
+		{Name: "op-insert", Status: "RUNNING", Progress: 50},
+		{Name: "op-insert", Status: "RUNNING", Progress: 50},
+		{Name: "op-insert", Status: "DONE", Progress: 100},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if isWaitRoute(req) {
+			writeWaitUnsupported(w)
+			return
+		}
+		if route := req.Method + " " + req.URL.Path; route != "GET "+opPath || len(polled) == 0 {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, polled[0])
+		polled = polled[1:]
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	var seen []int64
+	op := &compute.Operation{Name: "op-insert", Status: "PENDING"}
+	done, err := client.WaitForZoneOperation(context.Background(), "sample", "us-central1-c", op, func(pct int64) {
+		seen = append(seen, pct)
+	})
+	if err != nil {
+		t.Fatalf("WaitForZoneOperation: %v", err)
+	}
+	if done.Status != "DONE" {
+		t.Errorf("status: got %q want %q", done.Status, "DONE")
+	}
+	if want := []int64{0, 50, 100}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("progress: got %v want %v", seen, want)
+	}
+
+	// A nil callback is fine.
+	if _, err := client.WaitForZoneOperation(context.Background(), "sample", "us-central1-c", done, nil); err != nil {
+		t.Errorf("nil callback: %v", err)
+	}
+}
+
+func TestWaitForOperationScopes(t *testing.T) {
+	const base = "/compute/v1/projects/sample"
+	var polled []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if isWaitRoute(req) {
+			writeWaitUnsupported(w)
+			return
+		}
+		if req.Method != "GET" {
+			writeNotFound(w)
+			return
+		}
+		polled = append(polled, strings.TrimPrefix(req.URL.Path, base))
+		writeJSON(w, &compute.Operation{Name: lastURLSegment(req.URL.Path), Status: "DONE"})
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	tests := []struct {
+		zone string
+		op   *compute.Operation
+		want string
+	}{
+		{
+			op:   &compute.Operation{Name: "op-zonal", Zone: "https://www.googleapis.com" + base + "/zones/us-east1-b"},
+			want: "/zones/us-east1-b/operations/op-zonal",
+		},
+		{
+			zone: "us-central1-c",
+			op:   &compute.Operation{Name: "op-regional", Region: "https://www.googleapis.com" + base + "/regions/us-east1"},
+			want: "/regions/us-east1/operations/op-regional",
+		},
+		{
+			zone: "us-central1-c",
+			op:   &compute.Operation{Name: "op-unscoped"},
+			want: "/zones/us-central1-c/operations/op-unscoped",
+		},
+		{
+			op:   &compute.Operation{Name: "op-global"},
+			want: "/global/operations/op-global",
+		},
+	}
+	for _, tt := range tests {
+		polled = nil
+		done, err := client.WaitForOperation(context.Background(), "sample", tt.zone, tt.op)
+		if err != nil {
+			t.Errorf("%s: WaitForOperation: %v", tt.op.Name, err)
+			continue
+		}
+		if done.Status != "DONE" {
+			t.Errorf("%s: status: got %q want %q", tt.op.Name, done.Status, "DONE")
+		}
+		if want := []string{tt.want}; !reflect.DeepEqual(polled, want) {
+			t.Errorf("%s: polled %q want %q", tt.op.Name, polled, want)
+		}
+	}
+
+	if _, err := client.WaitForOperation(context.Background(), "sample", "", nil); err != errNilOperation {
+		t.Errorf("nil operation: got err %v want %v", err, errNilOperation)
+	}
+}
+
+func TestCreateInstanceBlocksUntilCompletion(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	var routes []string
+	polls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if isWaitRoute(req) {
+			writeWaitUnsupported(w)
+			return
+		}
+		route := req.Method + " " + req.URL.Path
+		routes = append(routes, route)
+		switch route {
+		case "GET " + zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+		case "POST " + zonePath + "/instances":
+			writeJSON(w, &compute.Operation{Name: "op-insert", Status: "PENDING"})
+		case "GET " + zonePath + "/operations/op-insert":
+			polls++
+			status := "RUNNING"
+			if polls > 1 {
+				status = "DONE"
+			}
+			writeJSON(w, &compute.Operation{Name: "op-insert", Status: status})
+		case "GET " + zonePath + "/instances/web":
+			writeJSON(w, &compute.Instance{
+				Name:              "web",
+				NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.128.0.2"}},
+			})
+		default:
+			writeNotFound(w)
+		}
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	instance, err := client.CreateInstance(context.Background(), &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+
+		BlockUntilCompletion: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+	if instance.Name != "web" {
+		t.Errorf("instance: got %q want %q", instance.Name, "web")
+	}
+	if polls != 2 {
+		t.Errorf("polled the insert operation %d times, want 2", polls)
+	}
+	// The instance must only be looked up once its insertion is done.
+	if last := routes[len(routes)-1]; last != "GET "+zonePath+"/instances/web" {
+		t.Errorf("last route: got %q want the instance lookup", last)
+	}
+	for i, route := range routes[:len(routes)-1] {
+		if strings.HasSuffix(route, "/instances/web") {
+			t.Errorf("#%d: instance looked up before the operation was done", i)
+		}
+	}
+}
+
+func TestWaitForOperationUsesWaitEndpoint(t *testing.T) {
+	const opPath = "/compute/v1/projects/sample/global/operations/op-firewall"
+	var routes []string
+	waits := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		route := req.Method + " " + req.URL.Path
+		routes = append(routes, route)
+		if route != "POST "+opPath+"/wait" {
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			return
+		}
+		// The server-side wait gives up twice before the operation is done.
+		waits++
+		status := "RUNNING"
+		if waits == 3 {
+			status = "DONE"
+		}
+		writeJSON(w, &compute.Operation{Name: "op-firewall", Status: status})
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	done, err := client.WaitForOperation(context.Background(), "sample", "", &compute.Operation{Name: "op-firewall", Status: "PENDING"})
+	if err != nil {
+		t.Fatalf("WaitForOperation: %v", err)
+	}
+	if done.Status != "DONE" {
+		t.Errorf("status: got %q want %q", done.Status, "DONE")
+	}
+	want := []string{"POST " + opPath + "/wait", "POST " + opPath + "/wait", "POST " + opPath + "/wait"}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes: got %q want %q", routes, want)
+	}
+}
+
+func TestWaitForOperationTimesOutWhileWaiting(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		// Block like the Wait endpoint does until the client gives up.
+		<-req.Context().Done()
+	})
+	client.OperationPollInterval = time.Millisecond
+	client.OperationPollTimeout = 20 * time.Millisecond
+
+	_, err := client.WaitForOperation(context.Background(), "sample", "us-central1-c", &compute.Operation{Name: "op-slow", Status: "RUNNING"})
+	if err == nil || !strings.Contains(err.Error(), "did not complete within") {
+		t.Errorf("got err %v, want a timeout error", err)
+	}
+}
+
+func TestWaitForOperationReturnsMissingOperation(t *testing.T) {
+	var routes []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		routes = append(routes, req.Method+" "+req.URL.Path)
+		writeNotFound(w)
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	_, err := client.WaitForOperation(context.Background(), "sample", "", &compute.Operation{Name: "op-gone", Status: "RUNNING"})
+	if err == nil {
+		t.Fatal("expected an error for the missing operation")
+	}
+	// Not found is about the operation, not the Wait endpoint, so it
+	// mustn't be looked up again with a poll.
+	if want := []string{"POST /compute/v1/projects/sample/global/operations/op-gone/wait"}; !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes: got %q want %q", routes, want)
+	}
+}
+
+// isWaitRoute reports whether req invokes an operation's Wait endpoint.
+func isWaitRoute(req *http.Request) bool {
+	return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/wait")
+}
+
+// writeWaitUnsupported responds like a server, such as an emulator,
+// that doesn't implement the operations' Wait endpoint.
+func writeWaitUnsupported(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": http.StatusMethodNotAllowed, "message": "method not allowed"}})
+}
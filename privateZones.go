@@ -0,0 +1,162 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+)
+
+// PrivateZoneRequest describes a private managed zone, whose records
+// only resolve from within the VPC networks that it is bound to, such
+// as for the internal hostnames of instances.
+type PrivateZoneRequest struct {
+	Project string `json:"project"`
+
+	// Name is the name of the managed zone e.g "internal".
+	Name string `json:"name"`
+
+	// DNSName is the domain that the zone serves e.g "internal.orijtech.com".
+	DNSName     string `json:"dns_name"`
+	Description string `json:"description,omitempty"`
+
+	// Networks are the VPC networks that can resolve the zone's records,
+	// either short names such as "default" for the networks of Project,
+	// or network URLs.
+	Networks []string `json:"networks"`
+}
+
+var (
+	errBlankPrivateZoneName = errors.New("expecting a non-blank private zone name")
+	errNoPrivateZoneNetwork = errors.New("expecting at least one network for the private zone")
+	errBlankNetwork         = errors.New("expecting non-blank networks")
+)
+
+func (preq *PrivateZoneRequest) Validate() error {
+	if preq == nil || preq.Project == "" {
+		return errEmptyProject
+	}
+	if preq.Name == "" {
+		return errBlankPrivateZoneName
+	}
+	if preq.DNSName == "" {
+		return errEmptyDomainName
+	}
+	if len(preq.Networks) == 0 {
+		return errNoPrivateZoneNetwork
+	}
+	for _, network := range preq.Networks {
+		if strings.TrimSpace(network) == "" {
+			return errBlankNetwork
+		}
+	}
+	return nil
+}
+
+// networkURLs returns the URLs of the zone's networks.
+func (preq *PrivateZoneRequest) networkURLs() []string {
+	var urls []string
+	for _, network := range preq.Networks {
+		if !strings.Contains(network, "/") {
+			network = SelfLink(preq.Project, "global", "networks", network)
+		}
+		urls = append(urls, network)
+	}
+	return urls
+}
+
+// networkPath trims a network URL to its path from "projects/",
+// since the API returns network URLs of different hosts and versions.
+func networkPath(url string) string {
+	if i := strings.Index(url, "projects/"); i >= 0 {
+		return url[i:]
+	}
+	return url
+}
+
+// EnsurePrivateZone returns the private managed zone described by preq,
+// creating it if it doesn't exist yet. An existing zone must be private
+// and be bound to at least the networks of preq.
+func (c *Client) EnsurePrivateZone(ctx context.Context, preq *PrivateZoneRequest) (*dns.ManagedZone, error) {
+	preq = inProject(preq, c.DefaultProject)
+	if err := preq.Validate(); err != nil {
+		return nil, err
+	}
+
+	mz, err := c.managedZonesService().Get(preq.Project, preq.Name).Context(ctx).Do()
+	if err == nil {
+		return mz, preq.checkZone(mz)
+	}
+	if !isNotFound(err) {
+		return nil, err
+	}
+
+	visibility := new(dns.ManagedZonePrivateVisibilityConfig)
+	for _, url := range preq.networkURLs() {
+		visibility.Networks = append(visibility.Networks, &dns.ManagedZonePrivateVisibilityConfigNetwork{NetworkUrl: url})
+	}
+	mz = &dns.ManagedZone{
+		Name:        preq.Name,
+		DnsName:     ensureHasTrailingDot(preq.DNSName),
+		Description: preq.Description,
+		Visibility:  "private",
+
+		PrivateVisibilityConfig: visibility,
+	}
+	return c.managedZonesService().Create(preq.Project, mz).Context(ctx).Do()
+}
+
+// checkZone checks that the existing managed zone mz
+// is private and bound to each of the networks of preq.
+func (preq *PrivateZoneRequest) checkZone(mz *dns.ManagedZone) error {
+	if mz.Visibility != "private" {
+		return fmt.Errorf("managed zone %q is %s rather than private", mz.Name, mz.Visibility)
+	}
+	bound := make(map[string]bool)
+	if mz.PrivateVisibilityConfig != nil {
+		for _, network := range mz.PrivateVisibilityConfig.Networks {
+			bound[networkPath(network.NetworkUrl)] = true
+		}
+	}
+	for _, url := range preq.networkURLs() {
+		if !bound[networkPath(url)] {
+			return fmt.Errorf("private managed zone %q isn't bound to network %q", mz.Name, url)
+		}
+	}
+	return nil
+}
+
+// RegisterInstancesInPrivateZone adds an A record for each instance to the
+// private managed zone, so that it resolves as "<instance>.<zone DNS name>"
+// to its internal IPs from within the zone's networks.
+func (c *Client) RegisterInstancesInPrivateZone(ctx context.Context, project, zone string, instances ...*compute.Instance) (*dns.Change, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	if zone == "" {
+		return nil, errEmptyZone
+	}
+
+	mz, err := c.managedZonesService().Get(project, zone).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, instance := range instances {
+		records = append(records, internalRecord(mz, instance.Name, ipv4AddressesFromInstance(instance)...))
+	}
+	return c.AddRecordSets(ctx, &UpdateRequest{Project: project, Zone: zone, Records: records})
+}
+
+// internalRecord returns the A record of the host name in the private zone.
+func internalRecord(mz *dns.ManagedZone, name string, ipv4Addresses ...string) *Record {
+	return &Record{
+		Type:          AName,
+		DNSName:       name + "." + ensureHasTrailingDot(mz.DnsName),
+		IPV4Addresses: ipv4Addresses,
+	}
+}
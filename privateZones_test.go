@@ -0,0 +1,146 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+)
+
+// fakePrivateZone serves the private managed zone "internal" of the
+// project "sample", keeping its records in a fakeZone.
+type fakePrivateZone struct {
+	t       *testing.T
+	zone    *dns.ManagedZone
+	records fakeZone
+}
+
+const fakePrivateZonePath = "/dns/v1/projects/sample/managedZones/internal"
+
+func (fp *fakePrivateZone) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch route := req.Method + " " + req.URL.Path; {
+	case route == "GET "+fakePrivateZonePath:
+		if fp.zone == nil {
+			writeNotFound(w)
+			return
+		}
+		writeJSON(w, fp.zone)
+	case route == "POST /dns/v1/projects/sample/managedZones":
+		fp.zone = new(dns.ManagedZone)
+		readJSON(fp.t, req, fp.zone)
+		writeJSON(w, fp.zone)
+	case strings.HasPrefix(req.URL.Path, fakePrivateZonePath+"/"):
+		req.URL.Path = fakeZonePath + strings.TrimPrefix(req.URL.Path, fakePrivateZonePath)
+		fp.records.ServeHTTP(w, req)
+	default:
+		http.Error(w, "unexpected route "+route, http.StatusNotFound)
+	}
+}
+
+func TestFullSetupPrivateZone(t *testing.T) {
+	// The setup's zone is both the machine's and the public managed zone.
+	const publicZonePath = "/dns/v1/projects/sample/managedZones/us-central1-c"
+	public := new(fakeZone)
+	private := &fakePrivateZone{t: t}
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/compute/v1/projects/sample/zones/us-central1-c/instances/web":
+			writeJSON(w, &compute.Instance{
+				Name: "web",
+				NetworkInterfaces: []*compute.NetworkInterface{{
+					NetworkIP:     "10.128.0.5",
+					AccessConfigs: []*compute.AccessConfig{{Type: "ONE_TO_ONE_NAT", NatIP: "35.1.2.3"}},
+				}},
+			})
+		case strings.HasPrefix(req.URL.Path, publicZonePath):
+			req.URL.Path = fakeZonePath + strings.TrimPrefix(req.URL.Path, publicZonePath)
+			public.ServeHTTP(w, req)
+		default:
+			private.ServeHTTP(w, req)
+		}
+	})
+
+	_, err := client.FullSetup(context.Background(), &Setup{
+		Project:       "sample",
+		Zone:          "us-central1-c",
+		MachineName:   "web",
+		DomainName:    "www.orijtech.com",
+		IPV4Addresses: []string{"35.1.2.3"},
+		SkipBinary:    true,
+		PrivateZone: &PrivateZoneRequest{
+			Name:     "internal",
+			DNSName:  "internal.orijtech.com",
+			Networks: []string{"default"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FullSetup: %v", err)
+	}
+
+	mz := private.zone
+	if mz == nil {
+		t.Fatal("expected the private zone to be created")
+	}
+	if mz.Visibility != "private" || mz.DnsName != "internal.orijtech.com." {
+		t.Errorf("got a %s zone for %q, want a private zone for %q", mz.Visibility, mz.DnsName, "internal.orijtech.com.")
+	}
+	wantNetworks := []*dns.ManagedZonePrivateVisibilityConfigNetwork{
+		{NetworkUrl: "https://www.googleapis.com/compute/v1/projects/sample/global/networks/default"},
+	}
+	if mz.PrivateVisibilityConfig == nil || !reflect.DeepEqual(mz.PrivateVisibilityConfig.Networks, wantNetworks) {
+		t.Errorf("networks: got %+v want %+v", mz.PrivateVisibilityConfig, wantNetworks)
+	}
+
+	want := []*dns.ResourceRecordSet{
+		{Name: "web.internal.orijtech.com.", Type: "A", Rrdatas: []string{"10.128.0.5"}},
+	}
+	if got := private.records.rrsets; len(got) != 1 || got[0].Name != want[0].Name || got[0].Type != want[0].Type || !reflect.DeepEqual(got[0].Rrdatas, want[0].Rrdatas) {
+		t.Errorf("private records: got %+v want %+v", got, want)
+	}
+	// The private zone gets the machine's internal IP rather than the public one.
+	if len(public.rrsets) != 1 || public.rrsets[0].Name != "www.orijtech.com." || !reflect.DeepEqual(public.rrsets[0].Rrdatas, []string{"35.1.2.3"}) {
+		t.Errorf("public records: got %+v", public.rrsets)
+	}
+}
+
+func TestEnsurePrivateZoneChecksNetworks(t *testing.T) {
+	private := &fakePrivateZone{
+		t: t,
+		zone: &dns.ManagedZone{
+			Name:       "internal",
+			DnsName:    "internal.orijtech.com.",
+			Visibility: "private",
+			PrivateVisibilityConfig: &dns.ManagedZonePrivateVisibilityConfig{
+				Networks: []*dns.ManagedZonePrivateVisibilityConfigNetwork{
+					{NetworkUrl: "https://compute.googleapis.com/compute/v1/projects/sample/global/networks/default"},
+				},
+			},
+		},
+	}
+	client := newTestClient(t, private.ServeHTTP)
+	ctx := context.Background()
+
+	preq := &PrivateZoneRequest{Project: "sample", Name: "internal", DNSName: "internal.orijtech.com", Networks: []string{"default"}}
+	if _, err := client.EnsurePrivateZone(ctx, preq); err != nil {
+		t.Errorf("EnsurePrivateZone with a bound network: %v", err)
+	}
+
+	preq.Networks = []string{"default", "staging"}
+	if _, err := client.EnsurePrivateZone(ctx, preq); err == nil || !strings.Contains(err.Error(), "staging") {
+		t.Errorf("expected an error about the unbound staging network, got %v", err)
+	}
+
+	private.zone.Visibility = "public"
+	preq.Networks = []string{"default"}
+	if _, err := client.EnsurePrivateZone(ctx, preq); err == nil {
+		t.Error("expected an error for a public zone")
+	}
+
+	if err := (&PrivateZoneRequest{Project: "sample", Name: "internal", DNSName: "internal.orijtech.com"}).Validate(); err != errNoPrivateZoneNetwork {
+		t.Errorf("got err %v want %v", err, errNoPrivateZoneNetwork)
+	}
+}
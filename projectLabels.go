@@ -0,0 +1,89 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// ProjectDefaultLabelsKey is the key of the project metadata whose value
+// lists the labels that the project's resources default to, as comma
+// separated key=value pairs e.g "cost-center=web,team=frontend".
+const ProjectDefaultLabelsKey = "default-labels"
+
+func (c *Client) projectsService() *compute.ProjectsService {
+	return compute.NewProjectsService(c.computeSrvc)
+}
+
+// ProjectDefaultLabels returns the labels that the resources of the
+// project default to, as set in the project's common instance metadata
+// under ProjectDefaultLabelsKey, or none if that isn't set.
+func (c *Client) ProjectDefaultLabels(ctx context.Context, project string) (map[string]string, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	p, err := c.projectsService().Get(project).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if p.CommonInstanceMetadata == nil {
+		return nil, nil
+	}
+	for _, item := range p.CommonInstanceMetadata.Items {
+		if item.Key == ProjectDefaultLabelsKey && item.Value != nil {
+			return parseLabels(*item.Value)
+		}
+	}
+	return nil, nil
+}
+
+// parseLabels parses labels of the form "key=value,key=value".
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a label, expecting one such as %q", pair, "team=frontend")
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// withProjectDefaultLabels returns the project's default labels
+// overridden and extended by labels.
+func (c *Client) withProjectDefaultLabels(ctx context.Context, project string, labels map[string]string) (map[string]string, error) {
+	defaults, err := c.ProjectDefaultLabels(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeLabels(defaults, labels)
+	if err := validateLabels(merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// applyProjectDefaultLabels merges the project's default labels
+// into those of the instance if ireq asks for them.
+func (c *Client) applyProjectDefaultLabels(ctx context.Context, ireq *InstanceRequest, instance *compute.Instance) error {
+	if !ireq.ApplyProjectDefaultLabels {
+		return nil
+	}
+	labels, err := c.withProjectDefaultLabels(ctx, ireq.Project, ireq.Labels)
+	if err != nil {
+		return err
+	}
+	instance.Labels = labels
+	return nil
+}
@@ -0,0 +1,87 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestProjectDefaultLabels(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	defaults := "cost-center=web, team=platform"
+	var inserted *compute.Instance
+	fs := newFakeStorage()
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/storage/v1/") {
+			fs.ServeHTTP(w, req)
+			return
+		}
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET /compute/v1/projects/sample":
+			writeJSON(w, &compute.Project{
+				Name: "sample",
+				CommonInstanceMetadata: &compute.Metadata{
+					Items: []*compute.MetadataItems{{Key: ProjectDefaultLabelsKey, Value: &defaults}},
+				},
+			})
+		case "GET " + zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+		case "POST " + zonePath + "/instances":
+			inserted = new(compute.Instance)
+			readJSON(t, req, inserted)
+			writeJSON(w, &compute.Operation{Name: "op-insert"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	got, err := client.ProjectDefaultLabels(ctx, "sample")
+	if err != nil {
+		t.Fatalf("ProjectDefaultLabels: %v", err)
+	}
+	if want := map[string]string{"cost-center": "web", "team": "platform"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("defaults: got %v want %v", got, want)
+	}
+
+	explicit := map[string]string{"team": "frontend", "env": "prod"}
+	want := map[string]string{"cost-center": "web", "team": "frontend", "env": "prod"}
+	results, err := client.CreateInstances(ctx, []*InstanceRequest{{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		Labels:           explicit,
+
+		ApplyProjectDefaultLabels: true,
+	}})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("CreateInstances: %v %v", err, results[0].Err)
+	}
+	if !reflect.DeepEqual(inserted.Labels, want) {
+		t.Errorf("instance labels: got %v want %v", inserted.Labels, want)
+	}
+
+	bucket, err := client.EnsureBucketExists(ctx, &BucketCheck{
+		Project: "sample",
+		Bucket:  "assets",
+		Labels:  explicit,
+
+		ApplyProjectDefaultLabels: true,
+	})
+	if err != nil {
+		t.Fatalf("EnsureBucketExists: %v", err)
+	}
+	if !reflect.DeepEqual(bucket.Labels, want) {
+		t.Errorf("bucket labels: got %v want %v", bucket.Labels, want)
+	}
+
+	defaults = "team"
+	if _, err := client.ProjectDefaultLabels(ctx, "sample"); err == nil || !strings.Contains(err.Error(), "is not a label") {
+		t.Errorf("malformed defaults: got err %v", err)
+	}
+}
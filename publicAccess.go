@@ -0,0 +1,72 @@
+package infra
+
+import (
+	"context"
+
+	"google.golang.org/api/storage/v1"
+)
+
+// allUsersReadRoles are the bucket IAM roles that, granted to allUsers,
+// let anyone read the bucket's objects.
+var allUsersReadRoles = map[string]bool{
+	"roles/storage.objectViewer":       true,
+	"roles/storage.legacyObjectReader": true,
+	"roles/storage.objectAdmin":        true,
+	"roles/storage.admin":              true,
+}
+
+// IsObjectPublic reports whether anyone can read the object, so that its
+// ObjectURL is usable without credentials. Access granted to allUsers by
+// the bucket's IAM policy counts; the object's ACL is only consulted if
+// the bucket doesn't enforce uniform bucket-level access.
+func (c *Client) IsObjectPublic(ctx context.Context, bucket, object string) (bool, error) {
+	if bucket == "" {
+		return false, errEmptyBucket
+	}
+	if object == "" {
+		return false, errEmptyName
+	}
+
+	b, err := c.bucketsService().Get(bucket).Context(ctx).Do()
+	if err != nil {
+		return false, err
+	}
+	ic := b.IamConfiguration
+	if ic != nil && ic.PublicAccessPrevention == "enforced" {
+		return false, nil
+	}
+
+	policy, err := c.bucketsService().GetIamPolicy(bucket).Context(ctx).Do()
+	if err != nil {
+		return false, err
+	}
+	for _, binding := range policy.Bindings {
+		if !allUsersReadRoles[binding.Role] {
+			continue
+		}
+		for _, member := range binding.Members {
+			if member == "allUsers" {
+				return true, nil
+			}
+		}
+	}
+
+	if ic != nil && ic.UniformBucketLevelAccess != nil && ic.UniformBucketLevelAccess.Enabled {
+		return false, nil
+	}
+
+	obj, err := c.objectsService().Get(bucket, object).Projection("full").Context(ctx).Do()
+	if err != nil {
+		return false, err
+	}
+	return aclAllowsAllUsers(obj.Acl), nil
+}
+
+func aclAllowsAllUsers(acl []*storage.ObjectAccessControl) bool {
+	for _, ac := range acl {
+		if ac.Entity == "allUsers" && (ac.Role == "READER" || ac.Role == "OWNER") {
+			return true
+		}
+	}
+	return false
+}
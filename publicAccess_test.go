@@ -0,0 +1,108 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/storage/v1"
+)
+
+func TestIsObjectPublic(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket *storage.Bucket
+		policy *storage.Policy
+		acl    []*storage.ObjectAccessControl
+		want   bool
+	}{
+		{
+			name:   "public object",
+			bucket: &storage.Bucket{Name: "site"},
+			policy: &storage.Policy{},
+			acl: []*storage.ObjectAccessControl{
+				{Entity: "project-owners-123", Role: "OWNER"},
+				{Entity: "allUsers", Role: "READER"},
+			},
+			want: true,
+		},
+		{
+			name:   "private object",
+			bucket: &storage.Bucket{Name: "site"},
+			policy: &storage.Policy{},
+			acl: []*storage.ObjectAccessControl{
+				{Entity: "project-owners-123", Role: "OWNER"},
+			},
+		},
+		{
+			name: "uniform access granting allUsers",
+			bucket: &storage.Bucket{
+				Name: "site",
+				IamConfiguration: &storage.BucketIamConfiguration{
+					UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{Enabled: true},
+				},
+			},
+			policy: &storage.Policy{
+				Bindings: []*storage.PolicyBindings{
+					{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "uniform access ignores the object ACL",
+			bucket: &storage.Bucket{
+				Name: "site",
+				IamConfiguration: &storage.BucketIamConfiguration{
+					UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{Enabled: true},
+				},
+			},
+			policy: &storage.Policy{
+				Bindings: []*storage.PolicyBindings{
+					{Role: "roles/storage.objectViewer", Members: []string{"allAuthenticatedUsers"}},
+				},
+			},
+			acl: []*storage.ObjectAccessControl{{Entity: "allUsers", Role: "READER"}},
+		},
+		{
+			name: "public access prevention",
+			bucket: &storage.Bucket{
+				Name:             "site",
+				IamConfiguration: &storage.BucketIamConfiguration{PublicAccessPrevention: "enforced"},
+			},
+			policy: &storage.Policy{
+				Bindings: []*storage.PolicyBindings{
+					{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+				switch route := req.Method + " " + req.URL.Path; route {
+				case "GET /storage/v1/b/site":
+					writeJSON(w, tt.bucket)
+				case "GET /storage/v1/b/site/iam":
+					writeJSON(w, tt.policy)
+				case "GET /storage/v1/b/site/o/index.html":
+					if got := req.URL.Query().Get("projection"); got != "full" {
+						t.Errorf("projection: got %q want %q", got, "full")
+					}
+					writeJSON(w, &storage.Object{Bucket: "site", Name: "index.html", Acl: tt.acl})
+				default:
+					http.Error(w, "unexpected route "+route, http.StatusNotFound)
+				}
+			})
+
+			got, err := client.IsObjectPublic(context.Background(), "site", "index.html")
+			if err != nil {
+				t.Fatalf("IsObjectPublic: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %t want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package infra
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+)
+
+// publicHTTPClient is the unauthenticated HTTP client of public
+// clients and is only a variable so that tests can replace it.
+var publicHTTPClient = http.DefaultClient
+
+var errPublicClient = errors.New("a public client can only read public objects, expecting a client with credentials")
+
+// refusingTransport fails every request with errPublicClient. Public
+// clients send their compute and DNS calls through it, since those all
+// need credentials and would otherwise fail with an opaque 401.
+type refusingTransport struct{}
+
+func (refusingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errPublicClient
+}
+
+// NewPublicClient creates a client without any credentials, for
+// downloading the objects of public buckets with Download and Object.
+// Uploads and other changes, as well as every compute and DNS call such
+// as CreateInstance and AddRecordSets, fail with an error instead of
+// being sent, while reading private objects fails as the API denies
+// access to them.
+func NewPublicClient() *Client {
+	// NewWithHTTPClient only fails for a nil HTTP client.
+	c, _ := NewWithHTTPClient(publicHTTPClient)
+	refusing := &http.Client{Transport: refusingTransport{}}
+	c.computeSrvc, _ = compute.New(refusing)
+	c.dnsSrvc, _ = dns.New(refusing)
+	c.public = true
+	return c
+}
@@ -0,0 +1,79 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewPublicClient(t *testing.T) {
+	var calls int
+	h := func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if auth := req.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no credentials, got Authorization %q", auth)
+		}
+		if req.URL.Path != "/storage/v1/b/public-assets/o/logo.svg" || req.URL.Query().Get("alt") != "media" {
+			http.Error(w, "unexpected route "+req.URL.Path, http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, "<svg/>")
+	}
+
+	defer func(hc *http.Client) { publicHTTPClient = hc }(publicHTTPClient)
+	publicHTTPClient = &http.Client{Transport: &handlerTransport{h: http.HandlerFunc(h)}}
+
+	client := NewPublicClient()
+	ctx := context.Background()
+	body, err := client.Download(ctx, "public-assets", "logo.svg")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	defer body.Close()
+	blob, _ := io.ReadAll(body)
+	if got, want := string(blob), "<svg/>"; got != want {
+		t.Errorf("body: got %q want %q", got, want)
+	}
+
+	_, err = client.UploadWithParams(ctx, &UploadParams{
+		Project: "sample",
+		Bucket:  "public-assets",
+		Name:    "logo.svg",
+		Reader:  func() io.Reader { return strings.NewReader("<svg/>") },
+	})
+	if err != errPublicClient {
+		t.Errorf("upload: got err %v want %v", err, errPublicClient)
+	}
+	if err := client.DeleteObjectGeneration(ctx, "public-assets", "logo.svg", 1); err != errPublicClient {
+		t.Errorf("delete: got err %v want %v", err, errPublicClient)
+	}
+
+	// Compute and DNS calls all need credentials.
+	if _, err := client.FindInstance(ctx, &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "web"}); !errors.Is(err, errPublicClient) {
+		t.Errorf("find instance: got err %v want %v", err, errPublicClient)
+	}
+	_, err = client.CreateInstance(ctx, &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	})
+	if !errors.Is(err, errPublicClient) {
+		t.Errorf("create instance: got err %v want %v", err, errPublicClient)
+	}
+	_, err = client.AddRecordSets(ctx, &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+		Records: []*Record{{Type: AName, DNSName: "www.orijtech.com.", IPV4Addresses: []string{"35.1.2.3"}}},
+	})
+	if !errors.Is(err, errPublicClient) {
+		t.Errorf("add record sets: got err %v want %v", err, errPublicClient)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected only the download to be sent, got %d calls", calls)
+	}
+}
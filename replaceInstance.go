@@ -0,0 +1,248 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func (c *Client) addressesService() *compute.AddressesService {
+	return compute.NewAddressesService(c.computeSrvc)
+}
+
+// ReplaceInstance deletes the instance described by ireq and recreates it
+// from ireq with the same name, such as to roll out a new configuration
+// of immutable machines, waiting for the new instance to be RUNNING.
+// The instance keeps its external IP, which if ephemeral is reserved as
+// a static address named after the instance so that it outlives the old
+// instance. Unless ireq sets BootImage, the new instance boots from the
+// image that the old boot disk was created from. The new instance is
+// checked like with CreateInstance before the old one is deleted.
+//
+// If the instance can't be recreated, whatever was created of it is
+// deleted and the old instance is restored from its configuration, with
+// a new boot disk from the same image and the disks that outlived it,
+// and a *RollbackError is returned whose Restored is set. Data disks
+// that were deleted with the old instance can't be restored. Should the
+// restoration fail too, whatever was restored is deleted. Either way the
+// address stays reserved for a retry.
+func (c *Client) ReplaceInstance(ctx context.Context, ireq *InstanceRequest) (*compute.Instance, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForCreate(); err != nil {
+		return nil, err
+	}
+	current, err := c.FindInstance(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement := *ireq
+	replacement.BlockUntilCompletion = true
+	natIP := externalIP(current)
+	if natIP != "" {
+		if err := c.reserveAddress(ctx, ireq.Project, regionFromZone(ireq.Zone), ireq.Name, natIP); err != nil {
+			return nil, err
+		}
+		replacement.NetworkInterface = withNatIP(ireq.NetworkInterface, natIP)
+	}
+	bootDisk, err := c.bootDisk(ctx, ireq.Project, ireq.Zone, current)
+	if err != nil {
+		return nil, err
+	}
+	if replacement.BootImage == "" && bootDisk != nil {
+		replacement.BootImage = bootDisk.SourceImage
+	}
+	// Build and check the new instance before deleting the
+	// old one, to not delete it for an invalid request.
+	toCreate, err := replacement.buildInstance()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.prepareInstance(ctx, &replacement, toCreate); err != nil {
+		return nil, err
+	}
+
+	op, err := c.instancesService().Delete(ireq.Project, ireq.Zone, ireq.Name).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.waitForZoneOperation(ctx, ireq.Project, ireq.Zone, op); err != nil {
+		return nil, err
+	}
+
+	instance, err := c.recreateInstance(ctx, &replacement, toCreate)
+	if err == nil {
+		return instance, nil
+	}
+	re := &RollbackError{Err: err, Instance: ireq.Name}
+	if re.RollbackErr = c.deleteIfExists(ctx, ireq); re.RollbackErr != nil {
+		return nil, re
+	}
+	original, toRestore := restoration(ireq, current, natIP, bootDisk)
+	if original == nil {
+		re.RollbackErr = fmt.Errorf("can't restore instance %q without the image of its boot disk", ireq.Name)
+		return nil, re
+	}
+	if _, err := c.recreateInstance(ctx, original, toRestore); err != nil {
+		re.RollbackErr = err
+		if err := c.deleteIfExists(ctx, ireq); err != nil {
+			re.RollbackErr = fmt.Errorf("%v, then deleting what was restored: %v", re.RollbackErr, err)
+		}
+		return nil, re
+	}
+	re.Restored = true
+	return nil, re
+}
+
+func (c *Client) recreateInstance(ctx context.Context, ireq *InstanceRequest, toCreate *compute.Instance) (*compute.Instance, error) {
+	if _, err := c.insertPreparedInstance(ctx, ireq, toCreate); err != nil {
+		return nil, err
+	}
+	return c.waitForInstanceStatus(ctx, ireq, "RUNNING")
+}
+
+// restoration returns the request and the instance that recreate current,
+// the instance that ireq replaces, once it was deleted: with its external
+// IP natIP, its labels, scheduling and accelerators, the disks that
+// outlived it, and if its boot disk was deleted with it, a new one from
+// bootDisk's image. It returns nils if the boot disk's image isn't known.
+func restoration(ireq *InstanceRequest, current *compute.Instance, natIP string, bootDisk *compute.Disk) (*InstanceRequest, *compute.Instance) {
+	original := InstanceRequestFromInstance(current)
+	original.Project, original.Zone = ireq.Project, ireq.Zone
+	original.BlockUntilCompletion = true
+	if original.NetworkInterface != nil {
+		nic := withNatIP(original.NetworkInterface, natIP)
+		nic.Fingerprint = ""
+		original.NetworkInterface = nic
+	}
+
+	original.Disks = nil
+	for _, disk := range current.Disks {
+		switch {
+		case !disk.AutoDelete:
+			original.Disks = append(original.Disks, disk)
+		case disk.Boot:
+			if bootDisk == nil || bootDisk.SourceImage == "" {
+				return nil, nil
+			}
+			original.Disks = append(original.Disks, &compute.AttachedDisk{
+				AutoDelete: true,
+				Boot:       true,
+				Type:       "PERSISTENT",
+				Mode:       "READ_WRITE",
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					SourceImage: bootDisk.SourceImage,
+					DiskSizeGb:  bootDisk.SizeGb,
+					DiskType:    bootDisk.Type,
+				},
+			})
+		}
+	}
+	toRestore := original.toInstance()
+	toRestore.Labels = current.Labels
+	toRestore.Scheduling = current.Scheduling
+	toRestore.GuestAccelerators = current.GuestAccelerators
+	return original, toRestore
+}
+
+// waitForInstanceStatus polls the instance until it has
+// that status, giving up after Client.OperationPollTimeout.
+func (c *Client) waitForInstanceStatus(ctx context.Context, ireq *InstanceRequest, status string) (*compute.Instance, error) {
+	timeout := c.operationPollTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	backoff := c.pollBackoff()
+
+	for {
+		instance, err := c.FindInstance(ctx, ireq)
+		if err != nil {
+			return nil, err
+		}
+		if instance.Status == status {
+			return instance, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, fmt.Errorf("instance %q was not %s within %s", ireq.Name, status, timeout)
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
+
+func (c *Client) deleteIfExists(ctx context.Context, ireq *InstanceRequest) error {
+	op, err := c.instancesService().Delete(ireq.Project, ireq.Zone, ireq.Name).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	_, err = c.waitForZoneOperation(ctx, ireq.Project, ireq.Zone, op)
+	return err
+}
+
+// externalIP returns the first external IP
+// of the instance's network interfaces if any.
+func externalIP(instance *compute.Instance) string {
+	for _, nic := range instance.NetworkInterfaces {
+		for _, ac := range nic.AccessConfigs {
+			if ac.NatIP != "" {
+				return ac.NatIP
+			}
+		}
+	}
+	return ""
+}
+
+// withNatIP returns a copy of nic whose
+// external NAT access config uses natIP.
+func withNatIP(nic *compute.NetworkInterface, natIP string) *compute.NetworkInterface {
+	copied := *nic
+	copied.AccessConfigs = nil
+	for _, ac := range nic.AccessConfigs {
+		acCopy := *ac
+		if acCopy.Type == "ONE_TO_ONE_NAT" {
+			acCopy.NatIP = natIP
+		}
+		copied.AccessConfigs = append(copied.AccessConfigs, &acCopy)
+	}
+	if len(copied.AccessConfigs) == 0 {
+		copied.AccessConfigs = ExternalNATInterface(natIP).AccessConfigs
+	}
+	return &copied
+}
+
+// reserveAddress reserves natIP as a static address called name,
+// unless it is already reserved.
+func (c *Client) reserveAddress(ctx context.Context, project, region, name, natIP string) error {
+	filter := fmt.Sprintf("address = %q", natIP)
+	list, err := c.addressesService().List(project, region).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if len(list.Items) > 0 {
+		return nil
+	}
+	op, err := c.addressesService().Insert(project, region, &compute.Address{Name: name, Address: natIP}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	_, err = c.waitForRegionOperation(ctx, project, region, op)
+	return err
+}
+
+// bootDisk returns the instance's boot disk, such as to find
+// the image that it was created from, or nil if it has none.
+func (c *Client) bootDisk(ctx context.Context, project, zone string, instance *compute.Instance) (*compute.Disk, error) {
+	for _, disk := range instance.Disks {
+		if disk.Boot && disk.Source != "" {
+			return c.disksService().Get(project, zone, lastURLSegment(disk.Source)).Context(ctx).Do()
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,212 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+type fakeReplacement struct {
+	t *testing.T
+
+	current     *compute.Instance
+	inserted    *compute.Instance
+	reserved    *compute.Address
+	deletes     int
+	failInserts int
+	zoneStatus  string
+}
+
+func (fr *fakeReplacement) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	const (
+		zonePath     = "/compute/v1/projects/sample/zones/us-central1-c"
+		instancePath = zonePath + "/instances/web"
+		regionPath   = "/compute/v1/projects/sample/regions/us-central1"
+	)
+	switch route := req.Method + " " + req.URL.Path; route {
+	case "GET " + zonePath:
+		writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: fr.zoneStatus})
+	case "GET " + instancePath:
+		switch {
+		case fr.current != nil:
+			writeJSON(w, fr.current)
+		case fr.inserted != nil:
+			writeJSON(w, fr.inserted)
+		default:
+			writeNotFound(w)
+		}
+	case "GET " + zonePath + "/disks/web":
+		writeJSON(w, &compute.Disk{Name: "web", SizeGb: 20, SourceImage: "projects/debian-cloud/global/images/debian-11-v1"})
+	case "GET " + regionPath + "/addresses":
+		writeJSON(w, &compute.AddressList{})
+	case "POST " + regionPath + "/addresses":
+		fr.reserved = new(compute.Address)
+		readJSON(fr.t, req, fr.reserved)
+		writeJSON(w, &compute.Operation{Name: "op-address", Status: "DONE"})
+	case "DELETE " + instancePath:
+		fr.deletes++
+		if fr.current == nil && fr.inserted == nil {
+			writeNotFound(w)
+			return
+		}
+		fr.current, fr.inserted = nil, nil
+		writeJSON(w, &compute.Operation{Name: "op-delete", Status: "DONE"})
+	case "POST " + zonePath + "/instances":
+		if fr.failInserts > 0 {
+			fr.failInserts--
+			http.Error(w, "quota exceeded", http.StatusForbidden)
+			return
+		}
+		fr.inserted = new(compute.Instance)
+		readJSON(fr.t, req, fr.inserted)
+		fr.inserted.Status = "RUNNING"
+		writeJSON(w, &compute.Operation{Name: "op-insert", Status: "DONE"})
+	default:
+		http.Error(w, "unexpected route "+route, http.StatusNotFound)
+	}
+}
+
+func newFakeReplacement(t *testing.T) *fakeReplacement {
+	return &fakeReplacement{
+		t:          t,
+		zoneStatus: "UP",
+		current: &compute.Instance{
+			Name:   "web",
+			Status: "RUNNING",
+			Labels: map[string]string{"env": "prod"},
+			Disks: []*compute.AttachedDisk{
+				{Boot: true, AutoDelete: true, Source: SelfLink("sample", "zones", "us-central1-c", "disks", "web")},
+				{AutoDelete: false, Source: SelfLink("sample", "zones", "us-central1-c", "disks", "web-data")},
+			},
+			NetworkInterfaces: []*compute.NetworkInterface{{
+				NetworkIP:     "10.128.0.5",
+				AccessConfigs: []*compute.AccessConfig{{Name: "External NAT", Type: "ONE_TO_ONE_NAT", NatIP: "35.1.2.3"}},
+			}},
+		},
+	}
+}
+
+func TestReplaceInstance(t *testing.T) {
+	fr := newFakeReplacement(t)
+	client := newTestClient(t, fr.ServeHTTP)
+	client.OperationPollInterval = time.Millisecond
+
+	ireq := &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	}
+	instance, err := client.ReplaceInstance(context.Background(), ireq)
+	if err != nil {
+		t.Fatalf("ReplaceInstance: %v", err)
+	}
+	if instance.Status != "RUNNING" || fr.deletes != 1 {
+		t.Errorf("got status %q after %d deletes, want the instance replaced", instance.Status, fr.deletes)
+	}
+	if fr.reserved == nil || fr.reserved.Address != "35.1.2.3" || fr.reserved.Name != "web" {
+		t.Errorf("expected the ephemeral IP to be reserved, got %+v", fr.reserved)
+	}
+	if got := externalIP(fr.inserted); got != "35.1.2.3" {
+		t.Errorf("external IP: got %q want %q", got, "35.1.2.3")
+	}
+	if got, want := fr.inserted.Disks[0].InitializeParams.SourceImage, "projects/debian-cloud/global/images/debian-11-v1"; got != want {
+		t.Errorf("boot image: got %q want %q", got, want)
+	}
+	if BasicExternalNATNetworkInterface.AccessConfigs[0].NatIP != "" {
+		t.Errorf("the shared network interface was modified")
+	}
+}
+
+func TestReplaceInstanceRestoresOnFailure(t *testing.T) {
+	fr := newFakeReplacement(t)
+	fr.failInserts = 1
+	client := newTestClient(t, fr.ServeHTTP)
+	client.OperationPollInterval = time.Millisecond
+
+	_, err := client.ReplaceInstance(context.Background(), &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	})
+	var re *RollbackError
+	if !errors.As(err, &re) {
+		t.Fatalf("got err %v, want a *RollbackError", err)
+	}
+	if re.Instance != "web" || re.RollbackErr != nil || !re.Restored {
+		t.Fatalf("rollback: got %+v want the instance restored", re)
+	}
+	if fr.reserved == nil {
+		t.Errorf("expected the address to stay reserved for a retry")
+	}
+
+	restored := fr.inserted
+	if restored == nil {
+		t.Fatal("expected the old instance to be restored")
+	}
+	if got := externalIP(restored); got != "35.1.2.3" {
+		t.Errorf("restored external IP: got %q want %q", got, "35.1.2.3")
+	}
+	if got := restored.Labels["env"]; got != "prod" {
+		t.Errorf("restored labels: got %v want env=prod", restored.Labels)
+	}
+	if len(restored.Disks) != 2 {
+		t.Fatalf("restored disks: got %d want the new boot disk and the data disk", len(restored.Disks))
+	}
+	boot := restored.Disks[0]
+	if !boot.Boot || boot.InitializeParams == nil || boot.InitializeParams.SourceImage != "projects/debian-cloud/global/images/debian-11-v1" || boot.InitializeParams.DiskSizeGb != 20 {
+		t.Errorf("restored boot disk: got %+v", boot)
+	}
+	if got := lastURLSegment(restored.Disks[1].Source); got != "web-data" {
+		t.Errorf("restored data disk: got %q want %q", got, "web-data")
+	}
+}
+
+func TestReplaceInstanceDeletesFailedRestoration(t *testing.T) {
+	fr := newFakeReplacement(t)
+	fr.failInserts = 2
+	client := newTestClient(t, fr.ServeHTTP)
+	client.OperationPollInterval = time.Millisecond
+
+	_, err := client.ReplaceInstance(context.Background(), &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	})
+	var re *RollbackError
+	if !errors.As(err, &re) {
+		t.Fatalf("got err %v, want a *RollbackError", err)
+	}
+	if re.RollbackErr == nil || re.Restored {
+		t.Errorf("rollback: got %+v want the restoration to have failed", re)
+	}
+	if fr.current != nil || fr.inserted != nil {
+		t.Errorf("expected no instance left behind")
+	}
+}
+
+func TestReplaceInstanceChecksBeforeDeleting(t *testing.T) {
+	fr := newFakeReplacement(t)
+	fr.zoneStatus = "DOWN"
+	client := newTestClient(t, fr.ServeHTTP)
+	client.OperationPollInterval = time.Millisecond
+
+	_, err := client.ReplaceInstance(context.Background(), &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	})
+	if err == nil {
+		t.Fatal("expected an error for a zone that is down")
+	}
+	if fr.deletes != 0 || fr.current == nil {
+		t.Errorf("got %d deletes, want the old instance kept", fr.deletes)
+	}
+}
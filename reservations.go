@@ -0,0 +1,88 @@
+package infra
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/api/compute/v1"
+)
+
+type ReservationRequest struct {
+	Project     string       `json:"project"`
+	Zone        string       `json:"zone"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	MachineType *MachineType `json:"machine_type,omitempty"`
+
+	// Count is the number of instances of MachineType to reserve.
+	Count int64 `json:"count"`
+
+	// SpecificReservationRequired when set means that the reservation
+	// can only be consumed by instances that target it by name, see
+	// SpecificReservationAffinity.
+	SpecificReservationRequired bool `json:"specific_reservation_required,omitempty"`
+}
+
+var errNonPositiveCount = errors.New("expecting a positive count")
+
+func (rreq *ReservationRequest) Validate() error {
+	if rreq == nil || rreq.Project == "" {
+		return errEmptyProject
+	}
+	if rreq.Zone == "" {
+		return errEmptyZone
+	}
+	if rreq.Name == "" {
+		return errBlankName
+	}
+	if rreq.Count <= 0 {
+		return errNonPositiveCount
+	}
+	return rreq.machineTypeOrDefault().Validate()
+}
+
+func (rreq *ReservationRequest) machineTypeOrDefault() *MachineType {
+	if rreq.MachineType == nil {
+		return basic1VCPUMachine
+	}
+	return rreq.MachineType
+}
+
+func (rreq *ReservationRequest) toReservation() *compute.Reservation {
+	return &compute.Reservation{
+		Name:        rreq.Name,
+		Description: rreq.Description,
+
+		SpecificReservationRequired: rreq.SpecificReservationRequired,
+		SpecificReservation: &compute.AllocationSpecificSKUReservation{
+			Count: rreq.Count,
+			InstanceProperties: &compute.AllocationSpecificSKUAllocationReservedInstanceProperties{
+				MachineType: rreq.machineTypeOrDefault().name(),
+			},
+		},
+	}
+}
+
+func (c *Client) reservationsService() *compute.ReservationsService {
+	return compute.NewReservationsService(c.computeSrvc)
+}
+
+func (c *Client) CreateReservation(ctx context.Context, rreq *ReservationRequest) (*compute.Operation, error) {
+	rreq = inProject(rreq, c.DefaultProject)
+	if err := rreq.Validate(); err != nil {
+		return nil, err
+	}
+	req := c.reservationsService().Insert(rreq.Project, rreq.Zone, rreq.toReservation())
+	return req.Context(ctx).Do()
+}
+
+// SpecificReservationAffinity returns the affinity that makes an
+// instance consume capacity only from the named reservation. It is
+// meant to be set as InstanceRequest.ReservationAffinity.
+func SpecificReservationAffinity(reservationName string) *compute.ReservationAffinity {
+	return &compute.ReservationAffinity{
+		ConsumeReservationType: "SPECIFIC_RESERVATION",
+		Key:                    "compute.googleapis.com/reservation-name",
+		Values:                 []string{reservationName},
+	}
+}
@@ -0,0 +1,90 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestCreateReservation(t *testing.T) {
+	var got *compute.Reservation
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/compute/v1/projects/sample/zones/us-central1-c/reservations" {
+			http.Error(w, "unexpected route "+req.Method+" "+req.URL.Path, http.StatusNotFound)
+			return
+		}
+		got = new(compute.Reservation)
+		readJSON(t, req, got)
+		writeJSON(w, &compute.Operation{Name: "op-1", Status: "PENDING"})
+	})
+
+	op, err := client.CreateReservation(context.Background(), &ReservationRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "batch-capacity",
+		Count:   3,
+
+		MachineType:                 &MachineType{CPUCount: 2, MemoryMBs: 4096},
+		SpecificReservationRequired: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateReservation: %v", err)
+	}
+	if op.Name != "op-1" {
+		t.Errorf("operation name: got %q want %q", op.Name, "op-1")
+	}
+	if got == nil {
+		t.Fatal("reservation was not sent")
+	}
+	if got.Name != "batch-capacity" || !got.SpecificReservationRequired {
+		t.Errorf("unexpected reservation: %+v", got)
+	}
+	if sr := got.SpecificReservation; sr == nil || sr.Count != 3 || sr.InstanceProperties.MachineType != "custom-2-4096" {
+		t.Errorf("unexpected specific reservation: %+v", sr)
+	}
+}
+
+func TestCreateReservationValidation(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+	})
+
+	tests := [...]struct {
+		req  *ReservationRequest
+		want error
+	}{
+		0: {req: nil, want: errEmptyProject},
+		1: {req: &ReservationRequest{Project: "sample"}, want: errEmptyZone},
+		2: {req: &ReservationRequest{Project: "sample", Zone: "us-central1-c"}, want: errBlankName},
+		3: {req: &ReservationRequest{Project: "sample", Zone: "us-central1-c", Name: "r"}, want: errNonPositiveCount},
+	}
+
+	for i, tt := range tests {
+		if _, err := client.CreateReservation(context.Background(), tt.req); err != tt.want {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.want)
+		}
+	}
+}
+
+func TestInstanceRequestReservationAffinity(t *testing.T) {
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "worker",
+
+		NetworkInterface:    BasicExternalNATNetworkInterface,
+		ReservationAffinity: SpecificReservationAffinity("batch-capacity"),
+	}
+
+	want := &compute.ReservationAffinity{
+		ConsumeReservationType: "SPECIFIC_RESERVATION",
+		Key:                    "compute.googleapis.com/reservation-name",
+		Values:                 []string{"batch-capacity"},
+	}
+	if got := ireq.toInstance().ReservationAffinity; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+}
@@ -0,0 +1,22 @@
+package infra
+
+import (
+	"context"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// ResetInstance hard resets the instance identified by ireq, like
+// pressing its reset button: the instance restarts without shutting
+// down its guest OS, keeping its disks. It is a way out for instances
+// that hang, such as while running their startup scripts. If
+// ireq.BlockUntilCompletion is set, it waits for the reset to complete.
+func (c *Client) ResetInstance(ctx context.Context, ireq *InstanceRequest) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+
+	op, err := c.instancesService().Reset(ireq.Project, ireq.Zone, ireq.Name).Context(ctx).Do()
+	return c.maybeWaitForZoneOperation(ctx, ireq, op, err)
+}
@@ -0,0 +1,64 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestResetInstance(t *testing.T) {
+	const (
+		resetPath = "/compute/v1/projects/sample/zones/us-central1-c/instances/web/reset"
+		opPath    = "/compute/v1/projects/sample/zones/us-central1-c/operations/reset-1"
+	)
+	resets, polls := 0, 0
+	var failWith *compute.OperationError
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if isWaitRoute(req) {
+			writeWaitUnsupported(w)
+			return
+		}
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "POST " + resetPath:
+			resets++
+			writeJSON(w, &compute.Operation{Name: "reset-1", Status: "RUNNING"})
+		case "GET " + opPath:
+			polls++
+			writeJSON(w, &compute.Operation{Name: "reset-1", Status: "DONE", Error: failWith})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+	client.OperationPollInterval = time.Millisecond
+	ctx := context.Background()
+
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "web"}
+	op, err := client.ResetInstance(ctx, ireq)
+	if err != nil {
+		t.Fatalf("ResetInstance: %v", err)
+	}
+	if op.Status != "RUNNING" || resets != 1 || polls != 0 {
+		t.Errorf("got status %q after %d resets and %d polls, want the running operation without polling", op.Status, resets, polls)
+	}
+
+	ireq.BlockUntilCompletion = true
+	if op, err = client.ResetInstance(ctx, ireq); err != nil {
+		t.Fatalf("ResetInstance blocking: %v", err)
+	}
+	if op.Status != "DONE" || polls != 1 {
+		t.Errorf("got status %q after %d polls, want DONE after 1", op.Status, polls)
+	}
+
+	failWith = &compute.OperationError{Errors: []*compute.OperationErrorErrors{{Code: "RESOURCE_NOT_READY", Message: "instance is stopping"}}}
+	if _, err := client.ResetInstance(ctx, ireq); err == nil || !strings.Contains(err.Error(), "RESOURCE_NOT_READY") {
+		t.Errorf("expected the operation's error, got %v", err)
+	}
+
+	if _, err := client.ResetInstance(ctx, &InstanceRequest{Project: "sample", Zone: "us-central1-c"}); err != errBlankName {
+		t.Errorf("got err %v want %v", err, errBlankName)
+	}
+}
@@ -0,0 +1,26 @@
+package infra
+
+import "strings"
+
+// regionFromZone returns the region that zone is in
+// e.g. "us-central1" for "us-central1-c".
+func regionFromZone(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+// resourcePolicyURLs expands the short names in policies to the self-links
+// of the resource policies of that name in the request's region. Anything
+// that already looks like a URL or a partial path is passed as is.
+func (ireq *InstanceRequest) resourcePolicyURLs(policies []string) []string {
+	var urls []string
+	for _, policy := range policies {
+		if !strings.Contains(policy, "/") {
+			policy = SelfLink(ireq.Project, "regions", regionFromZone(ireq.Zone), "resourcePolicies", policy)
+		}
+		urls = append(urls, policy)
+	}
+	return urls
+}
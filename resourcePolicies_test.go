@@ -0,0 +1,43 @@
+package infra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResourcePoliciesExpansion(t *testing.T) {
+	ireq := &InstanceRequest{
+		Project: "sample",
+		Zone:    "us-central1-c",
+		Name:    "db",
+
+		NetworkInterface: BasicExternalNATNetworkInterface,
+		ResourcePolicies: []string{
+			"spread",
+			"projects/shared/regions/us-central1/resourcePolicies/compact",
+		},
+		BootDiskResourcePolicies: []string{"daily-snapshots"},
+	}
+
+	instance := ireq.toInstance()
+	wantPolicies := []string{
+		"https://www.googleapis.com/compute/v1/projects/sample/regions/us-central1/resourcePolicies/spread",
+		"projects/shared/regions/us-central1/resourcePolicies/compact",
+	}
+	if !reflect.DeepEqual(instance.ResourcePolicies, wantPolicies) {
+		t.Errorf("instance policies:\ngot  %q\nwant %q", instance.ResourcePolicies, wantPolicies)
+	}
+
+	if len(instance.Disks) != 1 || !instance.Disks[0].Boot {
+		t.Fatalf("expected a single boot disk, got %+v", instance.Disks)
+	}
+	wantDiskPolicies := []string{
+		"https://www.googleapis.com/compute/v1/projects/sample/regions/us-central1/resourcePolicies/daily-snapshots",
+	}
+	if got := instance.Disks[0].InitializeParams.ResourcePolicies; !reflect.DeepEqual(got, wantDiskPolicies) {
+		t.Errorf("boot disk policies:\ngot  %q\nwant %q", got, wantDiskPolicies)
+	}
+	if got := BasicAttachedDisk.InitializeParams.ResourcePolicies; len(got) != 0 {
+		t.Errorf("BasicAttachedDisk was modified, its policies are now %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// defaultMaxRetryAttempts is how many times retryable calls
+	// are attempted unless Client.MaxRetryAttempts is set.
+	defaultMaxRetryAttempts = 3
+
+	// retryDelay is how long to wait, jittered, before the second
+	// attempt; the wait doubles for each later one up to maxRetryDelay.
+	retryDelay    = 250 * time.Millisecond
+	maxRetryDelay = 2 * time.Second
+)
+
+// DefaultRetryPolicy reports whether err is worth retrying, which it is for
+// the responses that Google APIs give to transient failures: 429 Too Many
+// Requests and 5XX server errors. Custom Client.RetryPolicy functions can
+// wrap it to retry more errors, such as some 403 quota errors.
+func DefaultRetryPolicy(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	return gerr.Code == http.StatusTooManyRequests || gerr.Code >= 500
+}
+
+func (c *Client) maxRetryAttempts() int {
+	if c.MaxRetryAttempts > 0 {
+		return c.MaxRetryAttempts
+	}
+	return defaultMaxRetryAttempts
+}
+
+func (c *Client) isRetryable(err error) bool {
+	if c.RetryPolicy != nil {
+		return c.RetryPolicy(err)
+	}
+	return DefaultRetryPolicy(err)
+}
+
+// retry calls fn until it succeeds, fails with an error that
+// isn't retryable, or has been attempted Client.MaxRetryAttempts times.
+// It is only for idempotent calls, such as gets and lists, see
+// retryMutation for calls that create or change resources.
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	return c.retryIf(ctx, fn, c.isRetryable)
+}
+
+// retryMutation is like retry but for calls that create or change
+// resources, which can't be blindly retried: a 5XX or a timeout
+// doesn't tell whether the change was made, and repeating it could
+// for example create a duplicate. So it only retries failures to
+// send the request at all, such as to connect to the API, and 429
+// Too Many Requests responses since rate limited calls aren't run.
+func (c *Client) retryMutation(ctx context.Context, fn func() error) error {
+	return c.retryIf(ctx, fn, func(err error) bool {
+		return isUnsent(err) || isRateLimited(err)
+	})
+}
+
+func isRateLimited(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusTooManyRequests
+}
+
+// isUnsent reports whether err is a failure to
+// connect, before the request could be sent.
+func isUnsent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (c *Client) retryIf(ctx context.Context, fn func() error, retryable func(error) bool) error {
+	backoff := &Backoff{Initial: retryDelay, Max: maxRetryDelay, Factor: 2, Jitter: true}
+	maxAttempts := c.maxRetryAttempts()
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= maxAttempts || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
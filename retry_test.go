@@ -0,0 +1,249 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &googleapi.Error{Code: http.StatusTooManyRequests}, want: true},
+		{err: &googleapi.Error{Code: http.StatusServiceUnavailable}, want: true},
+		{err: &googleapi.Error{Code: http.StatusForbidden}, want: false},
+		{err: &googleapi.Error{Code: http.StatusNotFound}, want: false},
+		{err: errors.New("not an API error"), want: false},
+	}
+	for _, tt := range tests {
+		if got := DefaultRetryPolicy(tt.err); got != tt.want {
+			t.Errorf("%v: got %t want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCustomRetryPolicy(t *testing.T) {
+	attempts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			writeJSON(w, map[string]interface{}{"error": map[string]interface{}{
+				"code":    http.StatusForbidden,
+				"message": "Quota exceeded",
+				"errors":  []map[string]string{{"reason": "quotaExceeded", "message": "Quota exceeded"}},
+			}})
+			return
+		}
+		writeJSON(w, &compute.Instance{Name: "web"})
+	})
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "web"}
+
+	// By default a 403 is fatal.
+	if _, err := client.FindInstance(context.Background(), ireq); err == nil {
+		t.Fatal("expected the 403 to fail FindInstance")
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts: got %d want 1", attempts)
+	}
+
+	attempts = 0
+	client.RetryPolicy = func(err error) bool {
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == http.StatusForbidden {
+			for _, item := range gerr.Errors {
+				if strings.HasPrefix(item.Reason, "quota") {
+					return true
+				}
+			}
+		}
+		return DefaultRetryPolicy(err)
+	}
+	instance, err := client.FindInstance(context.Background(), ireq)
+	if err != nil {
+		t.Fatalf("FindInstance: %v", err)
+	}
+	if instance.Name != "web" || attempts != 2 {
+		t.Errorf("got instance %q after %d attempts, want %q after 2", instance.Name, attempts, "web")
+	}
+}
+
+func TestRetryOnlyIdempotentCalls(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances"
+	gets, inserts := 0, 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET /compute/v1/projects/sample/zones/us-central1-c":
+			writeJSON(w, &compute.Zone{Status: "UP"})
+		case "GET " + instancePath + "/web":
+			gets++
+			if gets == 1 {
+				http.Error(w, "backend error", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, &compute.Instance{Name: "web"})
+		case "POST " + instancePath:
+			inserts++
+			http.Error(w, "backend error", http.StatusInternalServerError)
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+	ireq := &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	}
+
+	// The 500 leaves it unknown whether the instance was created,
+	// so retrying could create it twice.
+	if _, err := client.CreateInstance(context.Background(), ireq); err == nil {
+		t.Fatal("expected the 500 to fail CreateInstance")
+	}
+	if inserts != 1 {
+		t.Errorf("inserts: got %d want 1", inserts)
+	}
+
+	if _, err := client.FindInstance(context.Background(), ireq); err != nil {
+		t.Fatalf("FindInstance: %v", err)
+	}
+	if gets != 2 {
+		t.Errorf("gets: got %d want 2", gets)
+	}
+}
+
+// failingDialTransport fails its first request as if
+// it couldn't connect, then passes requests to next.
+type failingDialTransport struct {
+	next     http.RoundTripper
+	attempts int
+}
+
+func (ft *failingDialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ft.attempts++
+	if ft.attempts == 1 {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return ft.next.RoundTrip(req)
+}
+
+func TestRetryMutationWhenUnsent(t *testing.T) {
+	ft := &failingDialTransport{next: &handlerTransport{h: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, &compute.Operation{Name: "op-1", Status: "DONE"})
+	})}}
+	client, err := NewWithHTTPClient(&http.Client{Transport: ft})
+	if err != nil {
+		t.Fatalf("NewWithHTTPClient: %v", err)
+	}
+
+	err = client.retryMutation(context.Background(), func() error {
+		_, err := client.instancesService().Insert("sample", "us-central1-c", &compute.Instance{Name: "web"}).Do()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("retryMutation: %v", err)
+	}
+	if ft.attempts != 2 {
+		t.Errorf("attempts: got %d want 2", ft.attempts)
+	}
+}
+
+func TestListZonesRetriesTransientErrors(t *testing.T) {
+	var statuses []int
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if len(statuses) > 0 {
+			code := statuses[0]
+			statuses = statuses[1:]
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		writeJSON(w, &compute.ZoneList{Items: []*compute.Zone{{Name: "us-central1-c"}}})
+	})
+	listZones := func() (zones []string, err error) {
+		zres, err := client.ListZones(context.Background(), &ZoneRequest{Project: "sample"})
+		if err != nil {
+			t.Fatalf("ListZones: %v", err)
+		}
+		for page := range zres.Pages {
+			if page.Err != nil {
+				return nil, page.Err
+			}
+			for _, zone := range page.Zones {
+				zones = append(zones, zone.Name)
+			}
+		}
+		return zones, nil
+	}
+
+	statuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	zones, err := listZones()
+	if err != nil {
+		t.Fatalf("expected the 429 and 503 to be retried, got %v", err)
+	}
+	if len(zones) != 1 {
+		t.Errorf("got zones %q", zones)
+	}
+
+	client.MaxRetryAttempts = 2
+	statuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	var gerr *googleapi.Error
+	if _, err := listZones(); !errors.As(err, &gerr) || gerr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the 503 on the page after 2 attempts, got %v", err)
+	}
+	if len(statuses) != 0 {
+		t.Errorf("%d responses left unused", len(statuses))
+	}
+}
+
+func TestUpdateRecordSetsRetriesRateLimits(t *testing.T) {
+	fz := new(fakeZone)
+	var statuses []int
+	changes := 0
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "POST" {
+			changes++
+			if len(statuses) > 0 {
+				code := statuses[0]
+				statuses = statuses[1:]
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+		}
+		fz.ServeHTTP(w, req)
+	})
+	ureq := &UpdateRequest{
+		Project: "sample",
+		Zone:    "zone",
+		Additions: []*Record{
+			{Type: AName, DNSName: "www.orijtech.com", IPV4Addresses: []string{"10.0.0.1"}},
+		},
+	}
+
+	statuses = []int{http.StatusTooManyRequests}
+	if _, err := client.UpdateRecordSets(context.Background(), ureq); err != nil {
+		t.Fatalf("expected the 429 to be retried, got %v", err)
+	}
+	if changes != 2 || len(fz.rrsets) != 1 {
+		t.Errorf("got %d change requests and record sets %+v, want 2 requests adding 1 record set", changes, fz.rrsets)
+	}
+
+	// A 503 leaves it unknown whether the change was applied.
+	changes = 0
+	statuses = []int{http.StatusServiceUnavailable}
+	ureq.Additions[0].DNSName = "api.orijtech.com"
+	if _, err := client.UpdateRecordSets(context.Background(), ureq); err == nil {
+		t.Fatal("expected the 503 to fail UpdateRecordSets")
+	}
+	if changes != 1 {
+		t.Errorf("got %d change requests want 1", changes)
+	}
+}
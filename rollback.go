@@ -0,0 +1,153 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+)
+
+// RollbackError is returned by FullSetup when it fails after creating
+// the machine, which it then deletes along with the DNS records that it
+// registered to not leave them behind, and by ReplaceInstance when it
+// fails to recreate the instance.
+type RollbackError struct {
+	// Err is the failure that caused the rollback.
+	Err error
+
+	// Instance is the name of the machine that was rolled back.
+	Instance string
+
+	// SnapshotName is the name of the snapshot of the machine's boot
+	// disk, taken before deleting it if Setup.SnapshotBeforeRollback
+	// was set.
+	SnapshotName string
+
+	// RollbackErr if set is why the rollback failed, in which case
+	// the machine or its DNS records might not have been deleted.
+	RollbackErr error
+
+	// Restored is set when ReplaceInstance rolled back by restoring
+	// the instance that it was replacing.
+	Restored bool
+}
+
+var _ error = (*RollbackError)(nil)
+
+func (re *RollbackError) Error() string {
+	msg := fmt.Sprintf("%v: rolled back instance %q", re.Err, re.Instance)
+	if re.Restored {
+		msg += " by restoring it"
+	}
+	if re.SnapshotName != "" {
+		msg += fmt.Sprintf(", its boot disk was snapshotted to %q", re.SnapshotName)
+	}
+	if re.RollbackErr != nil {
+		msg += fmt.Sprintf(", but the rollback failed: %v", re.RollbackErr)
+	}
+	return msg
+}
+
+func (re *RollbackError) Unwrap() error { return re.Err }
+
+var errNoBootDisk = errors.New("instance has no boot disk")
+
+func (c *Client) disksService() *compute.DisksService {
+	return compute.NewDisksService(c.computeSrvc)
+}
+
+// setupChange is a DNS change that FullSetup made in a managed zone.
+type setupChange struct {
+	project, zone string
+	change        *dns.Change
+}
+
+// rollbackSetup deletes the instance that FullSetup created, if any,
+// after it failed with err, snapshotting its boot disk first if
+// requested. The DNS changes that registered the instance are reverted
+// first, latest first, so that no record is left pointing at its
+// released addresses. It returns the error that FullSetup should return.
+func (c *Client) rollbackSetup(ctx context.Context, req *Setup, created *compute.Instance, changes []*setupChange, err error) error {
+	if created == nil {
+		return err
+	}
+
+	re := &RollbackError{Err: err, Instance: created.Name}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if re.RollbackErr = c.revertSetupChange(ctx, changes[i]); re.RollbackErr != nil {
+			// Keep the instance that the remaining records point at.
+			return re
+		}
+	}
+	if req.SnapshotBeforeRollback {
+		re.SnapshotName, re.RollbackErr = c.snapshotBootDisk(ctx, req.Project, req.Zone, created)
+		if re.RollbackErr != nil {
+			// Keep the instance rather than losing its data.
+			return re
+		}
+	}
+
+	op, err := c.instancesService().Delete(req.Project, req.Zone, created.Name).Context(ctx).Do()
+	if err != nil {
+		re.RollbackErr = err
+		return re
+	}
+	_, re.RollbackErr = c.waitForZoneOperation(ctx, req.Project, req.Zone, op)
+	return re
+}
+
+// revertSetupChange reverts the DNS change, waiting for it to be done.
+func (c *Client) revertSetupChange(ctx context.Context, sc *setupChange) error {
+	inverse, err := c.RevertChange(ctx, sc.project, sc.zone, sc.change)
+	if err != nil {
+		return fmt.Errorf("reverting DNS change %q in zone %q: %w", sc.change.Id, sc.zone, err)
+	}
+	if _, err := c.waitForChange(ctx, sc.project, sc.zone, inverse); err != nil {
+		return fmt.Errorf("reverting DNS change %q in zone %q: %w", sc.change.Id, sc.zone, err)
+	}
+	return nil
+}
+
+// snapshotBootDisk snapshots the boot disk of the instance,
+// waiting for the snapshot to be taken, and returns its name.
+func (c *Client) snapshotBootDisk(ctx context.Context, project, zone string, instance *compute.Instance) (string, error) {
+	diskName := ""
+	for _, disk := range instance.Disks {
+		if disk.Boot && disk.Source != "" {
+			diskName = lastURLSegment(disk.Source)
+			break
+		}
+	}
+	if diskName == "" {
+		return "", errNoBootDisk
+	}
+
+	snapshotName := rollbackSnapshotName(diskName, time.Now())
+	req := c.disksService().CreateSnapshot(project, zone, diskName, &compute.Snapshot{Name: snapshotName})
+	op, err := req.Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.waitForZoneOperation(ctx, project, zone, op); err != nil {
+		return "", err
+	}
+	return snapshotName, nil
+}
+
+// maxSnapshotNameLength is the longest name that GCE accepts for a snapshot.
+const maxSnapshotNameLength = 63
+
+// rollbackSnapshotName returns the name of the snapshot of the disk
+// taken at now before a rollback, shortening the disk's name as needed
+// for the snapshot's to fit in maxSnapshotNameLength.
+func rollbackSnapshotName(diskName string, now time.Time) string {
+	suffix := "-rollback-" + now.UTC().Format("20060102-150405")
+	if max := maxSnapshotNameLength - len(suffix); len(diskName) > max {
+		diskName = strings.TrimRight(diskName[:max], "-")
+	}
+	return diskName + suffix
+}
@@ -0,0 +1,109 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+var (
+	errNilScheduling      = errors.New("expecting a non-nil scheduling")
+	errInstanceNotStopped = errors.New("instance must be stopped to change whether it is preemptible or its provisioning model")
+
+	errRunDurationAndTerminationTime = errors.New("expecting only one of MaxRunDuration and TerminationTime")
+	errNonPositiveRunDuration        = errors.New("expecting a positive MaxRunDuration")
+	errInvalidTerminationAction      = errors.New(`expecting TerminationAction to be "STOP" or "DELETE"`)
+)
+
+// SetScheduling updates the scheduling options, such as the restart
+// and host maintenance policies, of the instance identified by ireq.
+// Changing whether the instance is preemptible or its provisioning
+// model requires the instance to be stopped first.
+func (c *Client) SetScheduling(ctx context.Context, ireq *InstanceRequest, sched *compute.Scheduling) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+	if sched == nil {
+		return nil, errNilScheduling
+	}
+
+	instance, err := c.FindInstance(ctx, ireq)
+	if err != nil {
+		return nil, err
+	}
+	if instance.Status != "TERMINATED" && requiresStoppedInstance(instance.Scheduling, sched) {
+		return nil, errInstanceNotStopped
+	}
+
+	req := c.instancesService().SetScheduling(ireq.Project, ireq.Zone, ireq.Name, sched)
+	return req.Context(ctx).Do()
+}
+
+// requiresStoppedInstance reports whether changing the
+// scheduling from current to next can only be done while
+// the instance is stopped.
+func requiresStoppedInstance(current, next *compute.Scheduling) bool {
+	if current == nil {
+		current = new(compute.Scheduling)
+	}
+	if next.Preemptible != current.Preemptible {
+		return true
+	}
+	return next.ProvisioningModel != "" && next.ProvisioningModel != current.ProvisioningModel
+}
+
+// validateRunDuration checks MaxRunDuration, TerminationTime
+// and the TerminationAction that applies to them.
+func (ireq *InstanceRequest) validateRunDuration() error {
+	if ireq.MaxRunDuration != 0 && !ireq.TerminationTime.IsZero() {
+		return errRunDurationAndTerminationTime
+	}
+	if ireq.MaxRunDuration < 0 {
+		return errNonPositiveRunDuration
+	}
+	switch ireq.TerminationAction {
+	case "", "STOP", "DELETE":
+		return nil
+	default:
+		return errInvalidTerminationAction
+	}
+}
+
+// scheduling returns the scheduling that the instance needs, if any:
+// instances with accelerators can't be live migrated so they have
+// to be terminated for host maintenance, and ones with a bounded run
+// are terminated once MaxRunDuration passes or at TerminationTime.
+func (ireq *InstanceRequest) scheduling() *compute.Scheduling {
+	var sched *compute.Scheduling
+	if len(ireq.Accelerators) > 0 {
+		sched = &compute.Scheduling{OnHostMaintenance: "TERMINATE"}
+	}
+	if ireq.MaxRunDuration == 0 && ireq.TerminationTime.IsZero() {
+		return sched
+	}
+	if sched == nil {
+		sched = new(compute.Scheduling)
+	}
+	sched.InstanceTerminationAction = ireq.TerminationAction
+	if sched.InstanceTerminationAction == "" {
+		sched.InstanceTerminationAction = "STOP"
+	}
+	if ireq.MaxRunDuration != 0 {
+		sched.MaxRunDuration = computeDuration(ireq.MaxRunDuration)
+	} else {
+		sched.TerminationTime = ireq.TerminationTime.UTC().Format(time.RFC3339)
+	}
+	return sched
+}
+
+// computeDuration converts d into the seconds and nanos
+// that the compute API expresses durations in.
+func computeDuration(d time.Duration) *compute.Duration {
+	return &compute.Duration{
+		Seconds: int64(d / time.Second),
+		Nanos:   int64(d % time.Second),
+	}
+}
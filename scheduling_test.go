@@ -0,0 +1,138 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestSetScheduling(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/batch"
+	status := "RUNNING"
+	var set *compute.Scheduling
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + instancePath:
+			writeJSON(w, &compute.Instance{
+				Name:       "batch",
+				Status:     status,
+				Scheduling: &compute.Scheduling{ProvisioningModel: "STANDARD", OnHostMaintenance: "MIGRATE"},
+			})
+		case "POST " + instancePath + "/setScheduling":
+			set = new(compute.Scheduling)
+			readJSON(t, req, set)
+			writeJSON(w, &compute.Operation{Name: "op-scheduling"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "batch"}
+
+	// Host maintenance can be changed while the instance runs.
+	if _, err := client.SetScheduling(ctx, ireq, &compute.Scheduling{ProvisioningModel: "STANDARD", OnHostMaintenance: "TERMINATE"}); err != nil {
+		t.Fatalf("changing host maintenance: %v", err)
+	}
+	if set == nil || set.OnHostMaintenance != "TERMINATE" {
+		t.Errorf("got scheduling %+v want OnHostMaintenance TERMINATE", set)
+	}
+
+	// Becoming a spot instance requires the instance to be stopped.
+	set = nil
+	spot := &compute.Scheduling{ProvisioningModel: "SPOT", InstanceTerminationAction: "STOP"}
+	if _, err := client.SetScheduling(ctx, ireq, spot); err != errInstanceNotStopped {
+		t.Errorf("running instance: got err %v want %v", err, errInstanceNotStopped)
+	}
+	if _, err := client.SetScheduling(ctx, ireq, &compute.Scheduling{Preemptible: true}); err != errInstanceNotStopped {
+		t.Errorf("running instance: got err %v want %v", err, errInstanceNotStopped)
+	}
+	if set != nil {
+		t.Errorf("expected no scheduling to be set on the running instance, got %+v", set)
+	}
+
+	status = "TERMINATED"
+	if _, err := client.SetScheduling(ctx, ireq, spot); err != nil {
+		t.Fatalf("stopped instance: %v", err)
+	}
+	if set == nil || set.ProvisioningModel != "SPOT" {
+		t.Errorf("got scheduling %+v want ProvisioningModel SPOT", set)
+	}
+
+	if _, err := client.SetScheduling(ctx, ireq, nil); err != errNilScheduling {
+		t.Errorf("nil scheduling: got err %v want %v", err, errNilScheduling)
+	}
+}
+
+func TestComputeDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want *compute.Duration
+	}{
+		{d: 2 * time.Hour, want: &compute.Duration{Seconds: 7200}},
+		{d: 90*time.Second + 250*time.Millisecond, want: &compute.Duration{Seconds: 90, Nanos: 250000000}},
+		{d: 500 * time.Nanosecond, want: &compute.Duration{Nanos: 500}},
+	}
+
+	for _, tt := range tests {
+		if got := computeDuration(tt.d); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v want %+v", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRunDurationScheduling(t *testing.T) {
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "ci", MaxRunDuration: 90*time.Minute + 500*time.Millisecond}
+	want := &compute.Scheduling{
+		InstanceTerminationAction: "STOP",
+		MaxRunDuration:            &compute.Duration{Seconds: 5400, Nanos: 500000000},
+	}
+	if got := ireq.toInstance().Scheduling; !reflect.DeepEqual(got, want) {
+		t.Errorf("max run duration: got %+v want %+v", got, want)
+	}
+
+	ireq.MaxRunDuration = 0
+	ireq.TerminationTime = time.Date(2030, 1, 2, 3, 4, 5, 0, time.FixedZone("PST", -8*3600))
+	ireq.TerminationAction = "DELETE"
+	ireq.Accelerators = []*Accelerator{{Type: "nvidia-tesla-t4", Count: 1}}
+	want = &compute.Scheduling{
+		OnHostMaintenance:         "TERMINATE",
+		InstanceTerminationAction: "DELETE",
+		TerminationTime:           "2030-01-02T11:04:05Z",
+	}
+	if got := ireq.toInstance().Scheduling; !reflect.DeepEqual(got, want) {
+		t.Errorf("termination time: got %+v want %+v", got, want)
+	}
+}
+
+func TestValidateRunDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		ireq    *InstanceRequest
+		wantErr error
+	}{
+		{name: "neither", ireq: &InstanceRequest{}},
+		{name: "duration", ireq: &InstanceRequest{MaxRunDuration: time.Hour}},
+		{name: "time", ireq: &InstanceRequest{TerminationTime: time.Now().Add(time.Hour), TerminationAction: "DELETE"}},
+		{
+			name: "both",
+			ireq: &InstanceRequest{
+				MaxRunDuration:  time.Hour,
+				TerminationTime: time.Now().Add(time.Hour),
+			},
+			wantErr: errRunDurationAndTerminationTime,
+		},
+		{name: "negative", ireq: &InstanceRequest{MaxRunDuration: -time.Hour}, wantErr: errNonPositiveRunDuration},
+		{name: "action", ireq: &InstanceRequest{MaxRunDuration: time.Hour, TerminationAction: "SUSPEND"}, wantErr: errInvalidTerminationAction},
+	}
+
+	for _, tt := range tests {
+		if err := tt.ireq.validateRunDuration(); err != tt.wantErr {
+			t.Errorf("%s: got err %v want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package infra
+
+import "strings"
+
+// computeSelfLinkPrefix is what the self-links of compute resources start with.
+const computeSelfLinkPrefix = "https://www.googleapis.com/compute/v1/"
+
+// SelfLink returns the full URL of the compute resource of the project that
+// parts lead to, e.g for a zonal disk SelfLink("sample", "zones",
+// "us-central1-c", "disks", "web") returns
+// "https://www.googleapis.com/compute/v1/projects/sample/zones/us-central1-c/disks/web",
+// and for a global network SelfLink("sample", "global", "networks", "default").
+func SelfLink(project string, parts ...string) string {
+	return computeSelfLinkPrefix + strings.Join(append([]string{"projects", project}, parts...), "/")
+}
@@ -0,0 +1,34 @@
+package infra
+
+import "testing"
+
+func TestSelfLink(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{
+			parts: []string{"zones", "us-central1-c", "disks", "web"},
+			want:  "https://www.googleapis.com/compute/v1/projects/sample/zones/us-central1-c/disks/web",
+		},
+		{
+			parts: []string{"regions", "us-central1", "subnetworks", "apps"},
+			want:  "https://www.googleapis.com/compute/v1/projects/sample/regions/us-central1/subnetworks/apps",
+		},
+		{
+			parts: []string{"global", "networks", "default"},
+			want:  "https://www.googleapis.com/compute/v1/projects/sample/global/networks/default",
+		},
+	}
+	for _, tt := range tests {
+		if got := SelfLink("sample", tt.parts...); got != tt.want {
+			t.Errorf("%q: got %q want %q", tt.parts, got, tt.want)
+		}
+	}
+
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", MachineType: &MachineType{CPUCount: 2, MemoryMBs: 4096}}
+	want := "https://www.googleapis.com/compute/v1/projects/sample/zones/us-central1-c/machineTypes/custom-2-4096"
+	if got := ireq.toInstance().MachineType; got != want {
+		t.Errorf("machine type: got %q want %q", got, want)
+	}
+}
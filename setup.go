@@ -47,10 +47,46 @@ type Setup struct {
 
 	Environ    []string `json:"environ"`
 	TargetGOOS string   `json:"target_goos"`
+
+	// SetupLabels if set are merged into the labels of the machine,
+	// whether it was created by FullSetup or it already existed.
+	SetupLabels map[string]string `json:"setup_labels,omitempty"`
+
+	// SkipBinary when set only sets up the machine and the DNS records,
+	// without generating nor uploading the frontender binary, for
+	// when the application is deployed separately.
+	SkipBinary bool `json:"skip_binary,omitempty"`
+
+	// SnapshotBeforeRollback when set snapshots the boot disk of the
+	// machine that FullSetup created before deleting it, if a later
+	// step fails, so that none of its data is lost. FullSetup always
+	// deletes a machine that it created when a later step, such as the
+	// DNS changes or the binary's upload, fails, reverting the DNS
+	// records that it registered for it first, and returns a
+	// *RollbackError. A machine that already existed is never deleted.
+	SnapshotBeforeRollback bool `json:"snapshot_before_rollback,omitempty"`
+
+	// CommonLabels if set are the labels of every resource that
+	// FullSetup creates, that is the machine and the bucket of the
+	// binaries, for example to attribute their costs.
+	CommonLabels map[string]string `json:"common_labels,omitempty"`
+
+	// DryRun when set makes FullSetup only plan the setup, without any
+	// API calls: it returns the domains, the DNS record sets and the
+	// machine that it would create along with where the binary would
+	// be uploaded to, in SetupResponse.Plan.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// PrivateZone if set is a private managed zone, created if it doesn't
+	// exist yet, in which FullSetup registers the machine's internal IPs
+	// as "<MachineName>.<PrivateZone.DNSName>". It defaults to the
+	// project of the setup.
+	PrivateZone *PrivateZoneRequest `json:"private_zone,omitempty"`
 }
 
 var (
-	errEmptyDomainName = errors.New("expecting a non-empty domain name")
+	errEmptyDomainName  = errors.New("expecting a non-empty domain name")
+	errEmptyMachineName = errors.New("expecting a non-empty machine name to register in the private zone")
 )
 
 func (req *Setup) Validate() error {
@@ -63,13 +99,38 @@ func (req *Setup) Validate() error {
 	if req.DomainName == "" {
 		return errEmptyDomainName
 	}
-	return nil
+	if err := validateLabels(req.CommonLabels); err != nil {
+		return err
+	}
+	if req.PrivateZone != nil {
+		if req.MachineName == "" {
+			return errEmptyMachineName
+		}
+		if err := inProject(req.PrivateZone, req.Project).Validate(); err != nil {
+			return err
+		}
+	}
+	return validateLabels(req.SetupLabels)
 }
 
-func (c *Client) generateMachineAndIPV4Addresses(ctx context.Context, req *Setup) ([]string, error) {
-	instance, err := c.generateMachine(ctx, req)
+// frontenderBinariesBucket is the bucket that FullSetup uploads binaries to.
+const frontenderBinariesBucket = "frontender-binaries"
+
+// generateBinary generates the frontender binary and is
+// only a variable so that tests can skip building it.
+var generateBinary = frontender.GenerateBinary
+
+// generateMachineAndIPV4Addresses returns the IPv4 addresses of the
+// machine, along with the machine if it was created rather than reused
+// so that it can be rolled back.
+func (c *Client) generateMachineAndIPV4Addresses(ctx context.Context, req *Setup) ([]string, *compute.Instance, error) {
+	instance, created, err := c.generateMachine(ctx, req)
+	var createdInstance *compute.Instance
+	if created {
+		createdInstance = instance
+	}
 	if err != nil {
-		return nil, err
+		return nil, createdInstance, err
 	}
 
 	if len(instance.NetworkInterfaces) == 0 {
@@ -84,7 +145,7 @@ func (c *Client) generateMachineAndIPV4Addresses(ctx context.Context, req *Setup
 		}
 	}
 
-	return ipv4AddressesFromInstance(instance), nil
+	return ipv4AddressesFromInstance(instance), createdInstance, nil
 }
 
 func ipv4AddressesFromInstance(instance *compute.Instance) []string {
@@ -96,8 +157,35 @@ func ipv4AddressesFromInstance(instance *compute.Instance) []string {
 
 }
 
-func (c *Client) generateMachine(ctx context.Context, req *Setup) (*compute.Instance, error) {
-	return c.CreateInstance(ctx, &InstanceRequest{
+// generateMachine returns the machine, creating it if it doesn't
+// exist yet in which case created is set, even if it then fails.
+func (c *Client) generateMachine(ctx context.Context, req *Setup) (instance *compute.Instance, created bool, err error) {
+	ireq := req.instanceRequest()
+
+	// Reuse the machine if it already exists.
+	instance, err = c.FindInstance(ctx, ireq)
+	if err != nil {
+		if !isNotFound(err) {
+			return nil, false, err
+		}
+		instance, err = c.CreateInstance(ctx, ireq)
+		if err != nil {
+			return nil, false, err
+		}
+		created = true
+	}
+
+	if len(req.SetupLabels) > 0 {
+		if _, err := c.SetInstanceLabels(ctx, ireq, req.SetupLabels); err != nil {
+			return instance, created, err
+		}
+	}
+	return instance, created, nil
+}
+
+// instanceRequest returns the request of the machine that FullSetup creates.
+func (req *Setup) instanceRequest() *InstanceRequest {
+	return &InstanceRequest{
 		Description: req.ProjectDescription,
 
 		Project: req.Project,
@@ -105,33 +193,130 @@ func (c *Client) generateMachine(ctx context.Context, req *Setup) (*compute.Inst
 		Name:    req.MachineName,
 
 		NetworkInterface: BasicExternalNATNetworkInterface,
-	})
-}
 
-func (c *Client) generateRecordSets(ctx context.Context, req *Setup, ipv4Addresses ...string) (*dns.Change, error) {
-	ireq := &UpdateRequest{
-		Project: req.Project,
-		Zone:    req.Zone,
+		Labels: req.CommonLabels,
+	}
+}
 
-		Records: []*Record{
-			{
-				Type: AName, DNSName: req.DomainName,
-				IPV4Addresses: ipv4Addresses[:],
-			},
+// records returns the A record of the domain name and the CNAME records of its aliases.
+func (req *Setup) records(ipv4Addresses ...string) []*Record {
+	records := []*Record{
+		{
+			Type: AName, DNSName: req.DomainName,
+			IPV4Addresses: ipv4Addresses[:],
 		},
 	}
 
 	for _, alias := range req.Aliases {
-		ireq.Records = append(ireq.Records, &Record{
+		records = append(records, &Record{
 			Type:          CName,
 			DNSName:       alias,
 			CanonicalName: req.DomainName,
 		})
 	}
+	return records
+}
+
+func (c *Client) generateRecordSets(ctx context.Context, req *Setup, ipv4Addresses ...string) (*dns.Change, error) {
+	ireq := &UpdateRequest{
+		Project: req.Project,
+		Zone:    req.Zone,
+
+		Records: req.records(ipv4Addresses...),
+	}
 
 	return c.AddRecordSets(ctx, ireq)
 }
 
+// registerInPrivateZone ensures that the private zone of req exists
+// and registers the internal IPs of the machine in it. The machine is
+// looked up for those since req.IPV4Addresses, when set, are the
+// addresses of the public record which might not be internal ones.
+// It returns the change that registered the machine.
+func (c *Client) registerInPrivateZone(ctx context.Context, req *Setup) (*setupChange, error) {
+	instance, err := c.FindInstance(ctx, &InstanceRequest{
+		Project: req.Project,
+		Zone:    req.Zone,
+		Name:    req.MachineName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	preq := inProject(req.PrivateZone, req.Project)
+	mz, err := c.EnsurePrivateZone(ctx, preq)
+	if err != nil {
+		return nil, err
+	}
+	change, err := c.AddRecordSets(ctx, &UpdateRequest{
+		Project: preq.Project,
+		Zone:    preq.Name,
+
+		Records: []*Record{internalRecord(mz, req.MachineName, ipv4AddressesFromInstance(instance)...)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &setupChange{project: preq.Project, zone: preq.Name, change: change}, nil
+}
+
+// SetupPlan is what FullSetup would do for a dry run.
+type SetupPlan struct {
+	// Instance is the machine that would be created, or reused if
+	// it already exists, when no IPv4 addresses were provided.
+	Instance *InstanceRequest `json:"instance,omitempty"`
+
+	// UploadBucket is the bucket that the binary would be uploaded
+	// to, unless the binary is skipped.
+	UploadBucket string `json:"upload_bucket,omitempty"`
+
+	// PrivateZone is the private zone that would be created, or reused
+	// if it already exists, and PrivateRecordSets the record sets of the
+	// machine that would be added to it, without data since the
+	// machine's internal IPs aren't known without looking it up.
+	PrivateZone       *PrivateZoneRequest      `json:"private_zone,omitempty"`
+	PrivateRecordSets []*dns.ResourceRecordSet `json:"private_record_sets,omitempty"`
+}
+
+// planSetup returns what FullSetup would do for req without making any
+// API calls. The addresses of a machine that would be created aren't
+// known yet, so its A record set then has no data.
+func planSetup(req *Setup) (*SetupResponse, error) {
+	plan := new(SetupPlan)
+	ipv4Addresses := req.IPV4Addresses
+	if len(ipv4Addresses) == 0 {
+		plan.Instance = req.instanceRequest()
+		if err := plan.Instance.validateForCreate(); err != nil {
+			return nil, err
+		}
+	}
+	if !req.SkipBinary {
+		plan.UploadBucket = frontenderBinariesBucket
+	}
+	if req.PrivateZone != nil {
+		plan.PrivateZone = inProject(req.PrivateZone, req.Project)
+		mz := &dns.ManagedZone{DnsName: plan.PrivateZone.DNSName}
+		plan.PrivateRecordSets = internalRecord(mz, req.MachineName).recordSets()
+	}
+
+	var additions []*dns.ResourceRecordSet
+	for _, rec := range req.records(ipv4Addresses...) {
+		if err := rec.Validate(); err != nil && !(rec.Type == AName && plan.Instance != nil) {
+			return nil, err
+		}
+		additions = append(additions, rec.recordSets()...)
+	}
+
+	resp := &SetupResponse{
+		DNSAdditions: additions,
+		Domains:      recordSetsToDomainNames(additions, httpsify),
+
+		NonHTTPSRedirectURL: httpsify(req.DomainName),
+
+		Plan: plan,
+	}
+	return resp, nil
+}
+
 func stripTrailingDot(s string) string { return strings.TrimSuffix(s, ".") }
 
 func recordSetsToDomainNames(recordSets []*dns.ResourceRecordSet, fn func(string) string) []string {
@@ -155,32 +340,56 @@ func httpsify(s string) string {
 }
 
 func (c *Client) FullSetup(ctx context.Context, req *Setup) (*SetupResponse, error) {
+	req = inProject(req, c.DefaultProject)
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return planSetup(req)
+	}
 
 	ipv4Addresses := req.IPV4Addresses
+	var createdInstance *compute.Instance
 	if len(ipv4Addresses) == 0 {
 		// Time to generate that server
 		var err error
-		ipv4Addresses, err = c.generateMachineAndIPV4Addresses(ctx, req)
+		ipv4Addresses, createdInstance, err = c.generateMachineAndIPV4Addresses(ctx, req)
 		if err != nil {
-			return nil, err
+			return nil, c.rollbackSetup(ctx, req, createdInstance, nil, err)
 		}
 	}
 
 	// Now create that DNS mapping:
 	dnsChange, err := c.generateRecordSets(ctx, req, ipv4Addresses...)
 	if err != nil {
-		return nil, err
+		return nil, c.rollbackSetup(ctx, req, createdInstance, nil, err)
+	}
+	dnsChanges := []*setupChange{{project: req.Project, zone: req.Zone, change: dnsChange}}
+
+	if req.PrivateZone != nil {
+		privateChange, err := c.registerInPrivateZone(ctx, req)
+		if err != nil {
+			return nil, c.rollbackSetup(ctx, req, createdInstance, dnsChanges, err)
+		}
+		dnsChanges = append(dnsChanges, privateChange)
 	}
 
 	// Now convert the DNS change additions to https based domains
 	httpsDomains := recordSetsToDomainNames(dnsChange.Additions, httpsify)
 	nonHTTPSRedirectURL := httpsify(req.DomainName)
 
+	if req.SkipBinary {
+		resp := &SetupResponse{
+			DNSAdditions: dnsChange.Additions,
+			Domains:      httpsDomains,
+
+			NonHTTPSRedirectURL: nonHTTPSRedirectURL,
+		}
+		return resp, nil
+	}
+
 	// Now generate the binary
-	rc, err := frontender.GenerateBinary(&frontender.DeployInfo{
+	rc, err := generateBinary(&frontender.DeployInfo{
 		FrontendConfig: &frontender.Request{
 			Domains:    httpsDomains,
 			Environ:    req.Environ[:],
@@ -192,20 +401,29 @@ func (c *Client) FullSetup(ctx context.Context, req *Setup) (*SetupResponse, err
 		},
 	})
 	if err != nil {
-		return nil, err
+		return nil, c.rollbackSetup(ctx, req, createdInstance, dnsChanges, err)
 	}
 
-	// Now upload the binary
+	// Now upload the binary, creating its bucket with the common labels
+	// upfront since uploads create missing buckets without any labels.
+	if _, err := c.EnsureBucketExists(ctx, &BucketCheck{
+		Project: req.Project,
+		Bucket:  frontenderBinariesBucket,
+		Labels:  req.CommonLabels,
+	}); err != nil {
+		_ = rc.Close()
+		return nil, c.rollbackSetup(ctx, req, createdInstance, dnsChanges, err)
+	}
 	obj, err := c.UploadWithParams(ctx, &UploadParams{
 		Project: req.Project,
 		Public:  true,
-		Bucket:  "frontender-binaries",
+		Bucket:  frontenderBinariesBucket,
 		Name:    fmt.Sprintf("generated-binary-%s", uuid.NewRandom()),
 		Reader:  func() io.Reader { return rc },
 	})
 	_ = rc.Close()
 	if err != nil {
-		return nil, err
+		return nil, c.rollbackSetup(ctx, req, createdInstance, dnsChanges, err)
 	}
 
 	resp := &SetupResponse{
@@ -226,4 +444,7 @@ type SetupResponse struct {
 	DNSAdditions []*dns.ResourceRecordSet `json:"dns_additions"`
 
 	NonHTTPSRedirectURL string `json:"non_https_redirect_url"`
+
+	// Plan is only set for dry runs, where nothing was set up.
+	Plan *SetupPlan `json:"plan,omitempty"`
 }
@@ -0,0 +1,449 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+
+	"github.com/orijtech/frontender"
+)
+
+func TestFullSetupSkipBinary(t *testing.T) {
+	fz := new(fakeZone)
+	var storageRequests []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/storage/v1/") {
+			storageRequests = append(storageRequests, req.Method+" "+req.URL.Path)
+			http.Error(w, "unexpected storage request", http.StatusBadRequest)
+			return
+		}
+		fz.ServeHTTP(w, req)
+	})
+
+	resp, err := client.FullSetup(context.Background(), &Setup{
+		Project:       "sample",
+		Zone:          "zone",
+		DomainName:    "www.orijtech.com",
+		IPV4Addresses: []string{"10.0.0.1"},
+		SkipBinary:    true,
+	})
+	if err != nil {
+		t.Fatalf("FullSetup: %v", err)
+	}
+	if len(storageRequests) != 0 {
+		t.Errorf("expected no uploads, got requests %q", storageRequests)
+	}
+	if resp.BinaryURL != "" {
+		t.Errorf("expected no binary URL, got %q", resp.BinaryURL)
+	}
+	if want := []string{"https://www.orijtech.com"}; !reflect.DeepEqual(resp.Domains, want) {
+		t.Errorf("domains: got %q want %q", resp.Domains, want)
+	}
+	if len(resp.DNSAdditions) != 1 || len(fz.rrsets) != 1 {
+		t.Errorf("expected the A record to be created, got additions %+v", resp.DNSAdditions)
+	}
+}
+
+func TestFullSetupRollbackSnapshotsBootDisk(t *testing.T) {
+	const (
+		zonePath     = "/compute/v1/projects/sample/zones/us-central1-c"
+		instancePath = zonePath + "/instances/frontend"
+	)
+
+	for _, snapshotFirst := range []bool{true, false} {
+		var (
+			created  bool
+			deleted  bool
+			waited   bool
+			snapshot *compute.Snapshot
+		)
+		client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+			switch route := req.Method + " " + req.URL.Path; route {
+			case "GET " + instancePath:
+				if !created || deleted {
+					writeNotFound(w)
+					return
+				}
+				writeJSON(w, &compute.Instance{
+					Name:              "frontend",
+					Disks:             []*compute.AttachedDisk{{Boot: true, Source: "https://www.googleapis.com/compute/v1/projects/sample/zones/us-central1-c/disks/frontend"}},
+					NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.128.0.5"}},
+				})
+			case "GET " + zonePath:
+				writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+			case "POST " + zonePath + "/instances":
+				created = true
+				writeJSON(w, &compute.Operation{Name: "op-insert"})
+			case "POST " + zonePath + "/disks/frontend/createSnapshot":
+				if deleted {
+					http.Error(w, "the disk was deleted before being snapshotted", http.StatusBadRequest)
+					return
+				}
+				snapshot = new(compute.Snapshot)
+				readJSON(t, req, snapshot)
+				writeJSON(w, &compute.Operation{Name: "op-snapshot", Status: "DONE"})
+			case "DELETE " + instancePath:
+				deleted = true
+				writeJSON(w, &compute.Operation{Name: "op-delete", Status: "RUNNING"})
+			case "POST " + zonePath + "/operations/op-delete/wait":
+				waited = true
+				writeJSON(w, &compute.Operation{Name: "op-delete", Status: "DONE"})
+			case "GET /dns/v1/projects/sample/managedZones/us-central1-c/rrsets":
+				writeJSON(w, &dns.ResourceRecordSetsListResponse{})
+			case "POST /dns/v1/projects/sample/managedZones/us-central1-c/changes":
+				w.WriteHeader(http.StatusConflict)
+				writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": http.StatusConflict, "message": "already exists"}})
+			default:
+				http.Error(w, "unexpected route "+route, http.StatusNotFound)
+			}
+		})
+
+		_, err := client.FullSetup(context.Background(), &Setup{
+			Project:     "sample",
+			Zone:        "us-central1-c",
+			DomainName:  "www.orijtech.com",
+			MachineName: "frontend",
+
+			SnapshotBeforeRollback: snapshotFirst,
+		})
+		re, ok := err.(*RollbackError)
+		if !ok {
+			t.Fatalf("snapshotFirst %t: got err %v, want a *RollbackError", snapshotFirst, err)
+		}
+		if !deleted || !waited || re.Instance != "frontend" || re.RollbackErr != nil {
+			t.Errorf("snapshotFirst %t: expected the created instance to be deleted, got %+v", snapshotFirst, re)
+		}
+		var gerr *googleapi.Error
+		if !errors.As(err, &gerr) || gerr.Code != http.StatusConflict {
+			t.Errorf("snapshotFirst %t: got cause %v, want the DNS conflict", snapshotFirst, re.Err)
+		}
+
+		if !snapshotFirst {
+			if snapshot != nil || re.SnapshotName != "" {
+				t.Errorf("expected no snapshot, got %+v", snapshot)
+			}
+			continue
+		}
+		if snapshot == nil {
+			t.Fatal("expected the boot disk to be snapshotted")
+		}
+		if snapshot.Name != re.SnapshotName || !strings.HasPrefix(re.SnapshotName, "frontend-rollback-") {
+			t.Errorf("snapshot name: got %q, error reports %q", snapshot.Name, re.SnapshotName)
+		}
+		if !strings.Contains(err.Error(), re.SnapshotName) {
+			t.Errorf("expected the error %q to mention the snapshot %q", err, re.SnapshotName)
+		}
+	}
+}
+
+func TestRollbackSetupReportsFailedDelete(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "DELETE " + zonePath + "/instances/frontend":
+			writeJSON(w, &compute.Operation{Name: "op-delete", Status: "RUNNING"})
+		case "POST " + zonePath + "/operations/op-delete/wait":
+			writeJSON(w, &compute.Operation{
+				Name:   "op-delete",
+				Status: "DONE",
+				Error:  &compute.OperationError{Errors: []*compute.OperationErrorErrors{{Code: "RESOURCE_IN_USE_BY_ANOTHER_RESOURCE", Message: "in use"}}},
+			})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+	client.OperationPollInterval = time.Millisecond
+
+	cause := errors.New("DNS change failed")
+	req := &Setup{Project: "sample", Zone: "us-central1-c"}
+	err := client.rollbackSetup(context.Background(), req, &compute.Instance{Name: "frontend"}, nil, cause)
+	re, ok := err.(*RollbackError)
+	if !ok {
+		t.Fatalf("got err %v, want a *RollbackError", err)
+	}
+	if re.Err != cause {
+		t.Errorf("cause: got %v want %v", re.Err, cause)
+	}
+	if re.RollbackErr == nil || !strings.Contains(re.RollbackErr.Error(), "RESOURCE_IN_USE_BY_ANOTHER_RESOURCE") {
+		t.Errorf("expected the delete operation's error, got %v", re.RollbackErr)
+	}
+}
+
+func TestFullSetupRollbackRevertsDNS(t *testing.T) {
+	const (
+		zonePath     = "/compute/v1/projects/sample/zones/us-central1-c"
+		instancePath = zonePath + "/instances/frontend"
+	)
+
+	defer func(fn func(*frontender.DeployInfo) (io.ReadCloser, error)) { generateBinary = fn }(generateBinary)
+	generateBinary = func(*frontender.DeployInfo) (io.ReadCloser, error) {
+		return nil, errors.New("build failed")
+	}
+
+	fz := new(fakeZone)
+	var created, deleted bool
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; {
+		case strings.HasPrefix(req.URL.Path, "/dns/v1/"):
+			req.URL.Path = strings.Replace(req.URL.Path, "/managedZones/us-central1-c/", "/managedZones/zone/", 1)
+			fz.ServeHTTP(w, req)
+		case route == "GET "+zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+		case route == "GET "+instancePath:
+			if !created || deleted {
+				writeNotFound(w)
+				return
+			}
+			writeJSON(w, &compute.Instance{Name: "frontend", NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.128.0.5"}}})
+		case route == "POST "+zonePath+"/instances":
+			created = true
+			writeJSON(w, &compute.Operation{Name: "op-insert"})
+		case route == "DELETE "+instancePath:
+			if len(fz.rrsets) != 0 {
+				http.Error(w, "deleted while DNS records still point at it", http.StatusBadRequest)
+				return
+			}
+			deleted = true
+			writeJSON(w, &compute.Operation{Name: "op-delete", Status: "DONE"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	_, err := client.FullSetup(context.Background(), &Setup{
+		Project:     "sample",
+		Zone:        "us-central1-c",
+		DomainName:  "www.orijtech.com",
+		Aliases:     []string{"orijtech.com"},
+		MachineName: "frontend",
+	})
+	re, ok := err.(*RollbackError)
+	if !ok {
+		t.Fatalf("got err %v, want a *RollbackError", err)
+	}
+	if re.RollbackErr != nil || !deleted {
+		t.Errorf("expected the instance to be deleted, got %+v", re)
+	}
+	if len(fz.changes) != 2 {
+		t.Errorf("expected the registration and its revert, got %d changes", len(fz.changes))
+	}
+	if len(fz.rrsets) != 0 {
+		t.Errorf("expected the DNS records to be removed, got %+v", fz.rrsets)
+	}
+}
+
+func TestRollbackSnapshotName(t *testing.T) {
+	now := time.Date(2026, 10, 16, 4, 5, 6, 0, time.UTC)
+	nameRegexp := regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+	tests := []struct {
+		diskName string
+		want     string
+	}{
+		{diskName: "frontend", want: "frontend-rollback-20261016-040506"},
+		{
+			diskName: "frontend-production-us-central1-c-bootdisk",
+			want:     "frontend-production-us-central1-c-boot-rollback-20261016-040506",
+		},
+		{
+			// Truncated where it leaves a dash, which is dropped.
+			diskName: "frontend-production-us-central1-c-abc-disk",
+			want:     "frontend-production-us-central1-c-abc-rollback-20261016-040506",
+		},
+	}
+	for _, tt := range tests {
+		got := rollbackSnapshotName(tt.diskName, now)
+		if got != tt.want {
+			t.Errorf("%q: got %q want %q", tt.diskName, got, tt.want)
+		}
+		if !nameRegexp.MatchString(got) {
+			t.Errorf("%q: %q (%d characters) isn't a valid snapshot name", tt.diskName, got, len(got))
+		}
+	}
+}
+
+func TestFullSetupCommonLabels(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+
+	defer func(fn func(*frontender.DeployInfo) (io.ReadCloser, error)) { generateBinary = fn }(generateBinary)
+	generateBinary = func(*frontender.DeployInfo) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("binary")), nil
+	}
+
+	var inserted *compute.Instance
+	fz, fs := new(fakeZone), newFakeStorage()
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; {
+		case strings.Contains(req.URL.Path, "/storage/v1/"):
+			fs.ServeHTTP(w, req)
+		case strings.HasPrefix(req.URL.Path, "/dns/v1/"):
+			// The managed zone is named after the machine's zone.
+			req.URL.Path = strings.Replace(req.URL.Path, "/managedZones/us-central1-c/", "/managedZones/zone/", 1)
+			fz.ServeHTTP(w, req)
+		case route == "GET "+zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "UP"})
+		case route == "GET "+zonePath+"/instances/frontend":
+			if inserted == nil {
+				writeNotFound(w)
+				return
+			}
+			writeJSON(w, inserted)
+		case route == "POST "+zonePath+"/instances":
+			inserted = new(compute.Instance)
+			readJSON(t, req, inserted)
+			inserted.NetworkInterfaces = []*compute.NetworkInterface{{NetworkIP: "10.128.0.5"}}
+			writeJSON(w, &compute.Operation{Name: "op-insert"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	labels := map[string]string{"cost-center": "web", "team": "frontend_1"}
+	_, err := client.FullSetup(context.Background(), &Setup{
+		Project:     "sample",
+		Zone:        "us-central1-c",
+		DomainName:  "www.orijtech.com",
+		MachineName: "frontend",
+
+		CommonLabels: labels,
+	})
+	if err != nil {
+		t.Fatalf("FullSetup: %v", err)
+	}
+	if inserted == nil || !reflect.DeepEqual(inserted.Labels, labels) {
+		t.Errorf("instance labels: got %+v want %v", inserted, labels)
+	}
+	bucket := fs.buckets[frontenderBinariesBucket]
+	if bucket == nil || !reflect.DeepEqual(bucket.Labels, labels) {
+		t.Errorf("bucket labels: got %+v want %v", bucket, labels)
+	}
+	if len(fs.objects) != 1 {
+		t.Errorf("expected the binary to be uploaded, got %d objects", len(fs.objects))
+	}
+}
+
+func TestSetupValidateLabels(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxLabels; i++ {
+		tooMany[fmt.Sprintf("key-%d", i)] = ""
+	}
+
+	tests := []struct {
+		labels  map[string]string
+		wantErr string
+	}{
+		{labels: map[string]string{"env": "prod", "owner": ""}},
+		{labels: map[string]string{"Env": "prod"}, wantErr: "not a valid label key"},
+		{labels: map[string]string{"1env": "prod"}, wantErr: "not a valid label key"},
+		{labels: map[string]string{strings.Repeat("k", 64): "prod"}, wantErr: "not a valid label key"},
+		{labels: map[string]string{"env": "Prod"}, wantErr: "not a valid label value"},
+		{labels: map[string]string{"env": "a.b"}, wantErr: "not a valid label value"},
+		{labels: tooMany, wantErr: "expecting at most 64"},
+	}
+
+	for i, tt := range tests {
+		req := &Setup{Project: "sample", Zone: "zone", DomainName: "www.orijtech.com", CommonLabels: tt.labels}
+		err := req.Validate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("#%d: got err %v, want it to contain %q", i, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFullSetupDryRun(t *testing.T) {
+	defer func(fn func(*frontender.DeployInfo) (io.ReadCloser, error)) { generateBinary = fn }(generateBinary)
+	generateBinary = func(*frontender.DeployInfo) (io.ReadCloser, error) {
+		t.Error("unexpected binary generation")
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	var calls []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, req.Method+" "+req.URL.Path)
+		http.Error(w, "unexpected call", http.StatusBadRequest)
+	})
+
+	resp, err := client.FullSetup(context.Background(), &Setup{
+		Project:     "sample",
+		Zone:        "us-central1-c",
+		DomainName:  "www.orijtech.com",
+		Aliases:     []string{"orijtech.com"},
+		MachineName: "frontend",
+
+		CommonLabels: map[string]string{"env": "prod"},
+		DryRun:       true,
+	})
+	if err != nil {
+		t.Fatalf("FullSetup: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected no API calls in a dry run, got %q", calls)
+	}
+
+	plan := resp.Plan
+	if plan == nil || plan.Instance == nil {
+		t.Fatalf("expected a plan with the machine to create, got %+v", plan)
+	}
+	if plan.Instance.Name != "frontend" || plan.Instance.Labels["env"] != "prod" {
+		t.Errorf("planned instance: got %+v", plan.Instance)
+	}
+	if plan.UploadBucket != frontenderBinariesBucket {
+		t.Errorf("upload bucket: got %q want %q", plan.UploadBucket, frontenderBinariesBucket)
+	}
+	if want := []string{"https://www.orijtech.com", "https://orijtech.com"}; !reflect.DeepEqual(resp.Domains, want) {
+		t.Errorf("domains: got %q want %q", resp.Domains, want)
+	}
+	if len(resp.DNSAdditions) != 2 || resp.DNSAdditions[0].Type != "A" || resp.DNSAdditions[1].Type != "CNAME" {
+		t.Errorf("expected an A and a CNAME record set, got %+v", resp.DNSAdditions)
+	}
+	if resp.BinaryURL != "" {
+		t.Errorf("expected no binary URL, got %q", resp.BinaryURL)
+	}
+
+	// With the addresses provided, no machine is planned.
+	resp, err = client.FullSetup(context.Background(), &Setup{
+		Project:       "sample",
+		Zone:          "us-central1-c",
+		DomainName:    "www.orijtech.com",
+		IPV4Addresses: []string{"10.0.0.1"},
+		MachineName:   "frontend",
+		SkipBinary:    true,
+		DryRun:        true,
+		PrivateZone:   &PrivateZoneRequest{Name: "internal", DNSName: "internal.orijtech.com", Networks: []string{"default"}},
+	})
+	if err != nil {
+		t.Fatalf("FullSetup with addresses: %v", err)
+	}
+	if resp.Plan.Instance != nil || resp.Plan.UploadBucket != "" {
+		t.Errorf("expected neither a machine nor an upload, got %+v", resp.Plan)
+	}
+	if got := resp.DNSAdditions[0].Rrdatas; !reflect.DeepEqual(got, []string{"10.0.0.1"}) {
+		t.Errorf("A record data: got %q", got)
+	}
+	if pz := resp.Plan.PrivateZone; pz == nil || pz.Name != "internal" || pz.Project != "sample" {
+		t.Errorf("planned private zone: got %+v", pz)
+	}
+	if got := resp.Plan.PrivateRecordSets; len(got) != 1 || got[0].Name != "frontend.internal.orijtech.com." || got[0].Type != "A" {
+		t.Errorf("planned private record sets: got %+v", got)
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected no API calls in a dry run, got %q", calls)
+	}
+}
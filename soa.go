@@ -0,0 +1,96 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/dns/v1"
+)
+
+// SOAParams are the timers of a zone's SOA record, in seconds.
+// Unset timers keep their current values.
+type SOAParams struct {
+	Refresh int64 `json:"refresh,omitempty"`
+	Retry   int64 `json:"retry,omitempty"`
+	Expire  int64 `json:"expire,omitempty"`
+
+	// Minimum is the TTL of negative responses.
+	Minimum int64 `json:"minimum,omitempty"`
+}
+
+var (
+	errNegativeSOATimer = errors.New("expecting non-negative SOA timers")
+	errNoSOARecord      = errors.New("zone has no SOA record")
+)
+
+func (params *SOAParams) Validate() error {
+	if params.Refresh < 0 || params.Retry < 0 || params.Expire < 0 || params.Minimum < 0 {
+		return errNegativeSOATimer
+	}
+	return nil
+}
+
+// SetSOAParams replaces the timers of the zone's SOA record with the
+// ones set in params, preserving its primary name server, its
+// responsible party and its serial number.
+func (c *Client) SetSOAParams(ctx context.Context, project, zone string, params SOAParams) (*dns.Change, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return nil, errEmptyProject
+	}
+	if zone == "" {
+		return nil, errEmptyZone
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	mz, err := c.managedZonesService().Get(project, zone).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.recordSetsService().List(project, zone).Name(mz.DnsName).Type("SOA").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rrsets) == 0 || len(res.Rrsets[0].Rrdatas) == 0 {
+		return nil, errNoSOARecord
+	}
+	current := res.Rrsets[0]
+
+	rrdata, err := params.apply(current.Rrdatas[0])
+	if err != nil {
+		return nil, err
+	}
+	updated := &dns.ResourceRecordSet{
+		Name:    current.Name,
+		Type:    current.Type,
+		Ttl:     current.Ttl,
+		Rrdatas: []string{rrdata},
+	}
+	change := &dns.Change{
+		Additions: []*dns.ResourceRecordSet{updated},
+		Deletions: []*dns.ResourceRecordSet{current},
+	}
+	return c.changesService().Create(project, zone, change).Context(ctx).Do()
+}
+
+// apply returns the SOA rrdata, in the form
+// "MNAME RNAME SERIAL REFRESH RETRY EXPIRE MINIMUM",
+// with its timers replaced by those set in params.
+func (params *SOAParams) apply(rrdata string) (string, error) {
+	fields := strings.Fields(rrdata)
+	if len(fields) != 7 {
+		return "", fmt.Errorf("malformed SOA record %q, expecting 7 fields", rrdata)
+	}
+	timers := []int64{params.Refresh, params.Retry, params.Expire, params.Minimum}
+	for i, timer := range timers {
+		if timer > 0 {
+			fields[3+i] = strconv.FormatInt(timer, 10)
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
@@ -0,0 +1,44 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/dns/v1"
+)
+
+func TestSetSOAParams(t *testing.T) {
+	const currentSOA = "ns-cloud-a1.googledomains.com. cloud-dns-hostmaster.google.com. 7 21600 3600 259200 300"
+	fz := &fakeZone{
+		rrsets: []*dns.ResourceRecordSet{
+			{Name: "orijtech.com.", Type: "SOA", Ttl: 21600, Rrdatas: []string{currentSOA}},
+		},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "GET" && req.URL.Path == fakeZonePath {
+			writeJSON(w, &dns.ManagedZone{Name: "zone", DnsName: "orijtech.com."})
+			return
+		}
+		fz.ServeHTTP(w, req)
+	})
+
+	_, err := client.SetSOAParams(context.Background(), "sample", "zone", SOAParams{Refresh: 7200, Minimum: 60})
+	if err != nil {
+		t.Fatalf("SetSOAParams: %v", err)
+	}
+	if len(fz.rrsets) != 1 {
+		t.Fatalf("expected a single SOA record set, got %+v", fz.rrsets)
+	}
+	want := "ns-cloud-a1.googledomains.com. cloud-dns-hostmaster.google.com. 7 7200 3600 259200 60"
+	if got := fz.rrsets[0].Rrdatas[0]; got != want {
+		t.Errorf("SOA:\ngot  %s\nwant %s", got, want)
+	}
+	if ttl := fz.rrsets[0].Ttl; ttl != 21600 {
+		t.Errorf("TTL: got %d want %d", ttl, 21600)
+	}
+
+	if _, err := client.SetSOAParams(context.Background(), "sample", "zone", SOAParams{Retry: -1}); err != errNegativeSOATimer {
+		t.Errorf("got err %v want %v", err, errNegativeSOATimer)
+	}
+}
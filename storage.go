@@ -1,11 +1,17 @@
 package infra
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
 
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/storage/v1"
 
 	"github.com/orijtech/otils"
@@ -17,36 +23,210 @@ type UploadParams struct {
 	Bucket  string `json:"bucket"`
 	Name    string `json:"path"`
 
+	// StorageClass if set is the storage class of the uploaded
+	// object e.g "COLDLINE", otherwise the object gets the
+	// bucket's default storage class.
+	StorageClass string `json:"storage_class,omitempty"`
+
+	// KMSKeyName if set is the Cloud KMS key that the object is
+	// encrypted with e.g "projects/P/locations/L/keyRings/R/cryptoKeys/K",
+	// otherwise the object gets the bucket's default encryption.
+	KMSKeyName string `json:"kms_key_name,omitempty"`
+
+	// ContentType if set is the object's MIME type e.g "text/html",
+	// otherwise it is sniffed from the content.
+	ContentType string `json:"content_type,omitempty"`
+
+	// CacheControl if set is the Cache-Control header that the object
+	// is served with e.g "public, max-age=3600", otherwise objects that
+	// are publicly readable are cached for an hour.
+	CacheControl string `json:"cache_control,omitempty"`
+
+	// TemporaryHold and EventBasedHold when set place those holds on
+	// the uploaded object, which can't be deleted or replaced until
+	// they are released with SetObjectHold.
+	TemporaryHold  bool `json:"temporary_hold,omitempty"`
+	EventBasedHold bool `json:"event_based_hold,omitempty"`
+
+	// InheritBucketACL when set uploads the object without a predefined
+	// ACL, so that it gets the bucket's default object ACL, rather than
+	// the "private" ACL or with Public the "publicRead" ACL.
+	InheritBucketACL bool `json:"inherit_bucket_acl,omitempty"`
+
+	// ContentAddressed when set and Name is blank names the object
+	// NamePrefix followed by the hex SHA-256 digest of its content, so
+	// that identical content is stored once under the same name. The
+	// content of Reader is then buffered in memory to be hashed before
+	// it is uploaded, while that of ReaderAt is read twice instead.
+	ContentAddressed bool   `json:"content_addressed,omitempty"`
+	NamePrefix       string `json:"name_prefix,omitempty"`
+
+	// Reader returns the content to upload, streaming it. Its size
+	// doesn't need to be known ahead of time, such as for the output
+	// of a program: the content is read and uploaded a chunk of
+	// ChunkSize bytes at a time, so at most a chunk is buffered.
+	// Exactly one of Reader and ReaderAt must be set.
 	Reader func() io.Reader `json:"-"`
+
+	// ReaderAt with Size, the content's length in bytes, is the
+	// content to upload when it can be read at any offset, such as
+	// from a file. Only its first Size bytes are uploaded, and as
+	// they can be read again, ContentAddressed hashes them without
+	// buffering them. Like that of Reader, the content is uploaded in
+	// chunks of ChunkSize bytes, each retried from its buffer.
+	ReaderAt io.ReaderAt `json:"-"`
+	Size     int64       `json:"size,omitempty"`
+
+	// ChunkSize is the size of the chunks that the content is
+	// uploaded in, rounded up to a multiple of 256KiB. Each chunk is
+	// sent as soon as it is full, so smaller chunks use less memory
+	// and get streamed content stored sooner, at the cost of more
+	// requests. If unset, googleapi.DefaultUploadChunkSize is used.
+	ChunkSize int `json:"chunk_size,omitempty"`
 }
 
 var (
-	errBlankReaderFunc = errors.New("expecting a non-blank reader function")
+	errBlankReaderFunc   = errors.New("expecting a non-blank reader function")
+	errReaderAndReaderAt = errors.New("expecting only one of Reader and ReaderAt")
+	errNonPositiveSize   = errors.New("expecting a positive size with ReaderAt")
+
+	errPublicAndInheritACL = errors.New("expecting only one of Public and InheritBucketACL")
 
 	errEmptyName   = errors.New("expecting a non-empty name")
 	errEmptyBucket = errors.New("expecting a non-empty bucket")
 )
 
+// storageClasses are the storage classes that objects can be in.
+var storageClasses = map[string]bool{
+	"STANDARD": true,
+	"NEARLINE": true,
+	"COLDLINE": true,
+	"ARCHIVE":  true,
+
+	// Legacy storage classes.
+	"MULTI_REGIONAL":               true,
+	"REGIONAL":                     true,
+	"DURABLE_REDUCED_AVAILABILITY": true,
+}
+
+func validateStorageClass(class string) error {
+	if !storageClasses[class] {
+		return fmt.Errorf("%q is not a storage class, expecting one such as %q or %q", class, "STANDARD", "COLDLINE")
+	}
+	return nil
+}
+
 func (params *UploadParams) Validate() error {
-	if params == nil || params.Reader == nil {
+	if params == nil || (params.Reader == nil && params.ReaderAt == nil) {
 		return errBlankReaderFunc
 	}
-	if params.Name == "" {
+	if params.Reader != nil && params.ReaderAt != nil {
+		return errReaderAndReaderAt
+	}
+	if params.ReaderAt != nil && params.Size <= 0 {
+		return errNonPositiveSize
+	}
+	if params.Name == "" && !params.ContentAddressed {
 		return errEmptyName
 	}
 	if params.Bucket == "" {
 		return errEmptyBucket
 	}
+	if params.Public && params.InheritBucketACL {
+		return errPublicAndInheritACL
+	}
+	if params.StorageClass != "" {
+		if err := validateStorageClass(params.StorageClass); err != nil {
+			return err
+		}
+	}
+	if params.KMSKeyName != "" {
+		if err := validateKMSKeyName(params.KMSKeyName); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (params *UploadParams) chunkSize() int {
+	if params.ChunkSize > 0 {
+		return params.ChunkSize
+	}
+	return googleapi.DefaultUploadChunkSize
+}
+
 type BucketCheck struct {
 	Project string `json:"project"`
 	Bucket  string `json:"bucket"`
 	Public  bool   `json:"public"`
+
+	// Labels are the labels that the bucket is created with,
+	// they aren't applied to a bucket that already exists.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Location if set is where the bucket is created e.g "US" or
+	// the dual-region "NAM4", otherwise it is created in the US.
+	Location string `json:"location,omitempty"`
+
+	// Autoclass when set creates the bucket with Autoclass, which moves
+	// each object to the storage class that suits how it is accessed.
+	Autoclass bool `json:"autoclass,omitempty"`
+
+	// TurboReplication when set creates the bucket with turbo
+	// replication, which replicates objects between its regions
+	// within 15 minutes. Only dual-region buckets support it.
+	TurboReplication bool `json:"turbo_replication,omitempty"`
+
+	// ApplyProjectDefaultLabels when set creates the bucket with the
+	// project's default labels, see ProjectDefaultLabels, along with
+	// Labels whose values win over the defaults of the same keys.
+	ApplyProjectDefaultLabels bool `json:"apply_project_default_labels,omitempty"`
+}
+
+// dualRegions are the predefined dual-region bucket locations.
+var dualRegions = map[string]bool{
+	"ASIA1": true,
+	"EUR4":  true,
+	"EUR5":  true,
+	"EUR7":  true,
+	"EUR8":  true,
+	"NAM4":  true,
+}
+
+func (bc *BucketCheck) Validate() error {
+	if bc == nil || bc.Bucket == "" {
+		return errEmptyBucket
+	}
+	if bc.TurboReplication && !dualRegions[strings.ToUpper(bc.Location)] {
+		return fmt.Errorf("turbo replication is only for dual-region buckets, got location %q, expecting one such as %q", bc.Location, "NAM4")
+	}
+	return nil
+}
+
+// newBucket returns the bucket that bc asks to create.
+func (bc *BucketCheck) newBucket() *storage.Bucket {
+	bucket := &storage.Bucket{
+		Name:     bc.Bucket,
+		Labels:   bc.Labels,
+		Location: bc.Location,
+	}
+	if bc.Autoclass {
+		bucket.Autoclass = &storage.BucketAutoclass{Enabled: true}
+	}
+	if bc.TurboReplication {
+		bucket.Rpo = "ASYNC_TURBO"
+	}
+	return bucket
 }
 
 func (c *Client) EnsureBucketExists(ctx context.Context, bc *BucketCheck) (*storage.Bucket, error) {
+	bc = inProject(bc, c.DefaultProject)
+	if c.public {
+		return nil, errPublicClient
+	}
+	if err := bc.Validate(); err != nil {
+		return nil, err
+	}
 	foundBucket, err := c.bucketsService().Get(bc.Bucket).Context(ctx).Do()
 	if err != nil {
 		// TODO: Handle the respective error cases e.g:
@@ -59,7 +239,13 @@ func (c *Client) EnsureBucketExists(ctx context.Context, bc *BucketCheck) (*stor
 	}
 
 	// Otherwise it is time to create that bucket.
-	bIns := c.bucketsService().Insert(bc.Project, &storage.Bucket{Name: bc.Bucket}).Context(ctx)
+	bucket := bc.newBucket()
+	if bc.ApplyProjectDefaultLabels {
+		if bucket.Labels, err = c.withProjectDefaultLabels(ctx, bc.Project, bc.Labels); err != nil {
+			return nil, err
+		}
+	}
+	bIns := c.bucketsService().Insert(bc.Project, bucket).Context(ctx)
 
 	var acl = "private"
 	if bc.Public {
@@ -77,10 +263,49 @@ func (c *Client) bucketsService() *storage.BucketsService {
 	return storage.NewBucketsService(c.storageSrvc)
 }
 
+type DownloadParams struct {
+	Bucket string `json:"bucket"`
+	Name   string `json:"path"`
+
+	// IfGenerationNotMatch if set makes the download conditional:
+	// if the object's current generation matches it, the object
+	// hasn't changed and ErrNotModified is returned without
+	// transferring the object's content.
+	IfGenerationNotMatch int64 `json:"if_generation_not_match,omitempty"`
+}
+
+// ErrNotModified is returned by conditional downloads
+// whose object hasn't changed since the given generation.
+var ErrNotModified = errors.New("object not modified")
+
+func (params *DownloadParams) Validate() error {
+	if params == nil || params.Bucket == "" {
+		return errEmptyBucket
+	}
+	if params.Name == "" {
+		return errEmptyName
+	}
+	return nil
+}
+
 func (c *Client) Download(ctx context.Context, bucket, path string) (io.ReadCloser, error) {
-	objGetCall := c.objectsService().Get(bucket, path).Context(ctx)
+	return c.DownloadWithParams(ctx, &DownloadParams{Bucket: bucket, Name: path})
+}
+
+func (c *Client) DownloadWithParams(ctx context.Context, params *DownloadParams) (io.ReadCloser, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	objGetCall := c.objectsService().Get(params.Bucket, params.Name).Context(ctx)
+	if params.IfGenerationNotMatch != 0 {
+		objGetCall = objGetCall.IfGenerationNotMatch(params.IfGenerationNotMatch)
+	}
 	res, err := objGetCall.Download()
 	if err != nil {
+		if googleapi.IsNotModified(err) {
+			return nil, ErrNotModified
+		}
 		return nil, err
 	}
 
@@ -102,9 +327,19 @@ func (c *Client) Object(ctx context.Context, bucket, path string) (*storage.Obje
 }
 
 func (c *Client) UploadWithParams(ctx context.Context, params *UploadParams) (*storage.Object, error) {
+	params = inProject(params, c.DefaultProject)
+	if c.public {
+		return nil, errPublicClient
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
+	if params.Name == "" {
+		var err error
+		if params, err = params.withContentAddressedName(); err != nil {
+			return nil, err
+		}
+	}
 
 	bucket, err := c.EnsureBucketExists(ctx, &BucketCheck{
 		Project: params.Project,
@@ -115,20 +350,180 @@ func (c *Client) UploadWithParams(ctx context.Context, params *UploadParams) (*s
 	}
 
 	obj := &storage.Object{
-		Name:   params.Name,
-		Bucket: bucket.Name,
+		Name:         params.Name,
+		Bucket:       bucket.Name,
+		StorageClass: params.StorageClass,
+		KmsKeyName:   params.KMSKeyName,
+		ContentType:  params.ContentType,
+		CacheControl: params.CacheControl,
+
+		TemporaryHold:  params.TemporaryHold,
+		EventBasedHold: params.EventBasedHold,
 	}
 
 	oIns := c.objectsService().Insert(params.Bucket, obj).Context(ctx)
+	if !params.InheritBucketACL {
+		var acl = "private"
+		if params.Public {
+			acl = "publicRead"
+		}
+		oIns = oIns.PredefinedAcl(acl)
+	}
+	var content io.Reader
+	if params.ReaderAt != nil {
+		content = io.NewSectionReader(params.ReaderAt, 0, params.Size)
+	} else {
+		content = params.Reader()
+	}
+	mediaOptions := []googleapi.MediaOption{googleapi.ChunkSize(params.chunkSize())}
+	if params.ContentType != "" {
+		mediaOptions = append(mediaOptions, googleapi.ContentType(params.ContentType))
+	}
+	oIns = oIns.Media(content, mediaOptions...)
+	return oIns.Do()
+}
 
-	var acl = "private"
-	if params.Public {
-		acl = "publicRead"
+// SetObjectStorageClass moves the object to the storage class e.g
+// "COLDLINE", by rewriting it in place, and returns the rewritten object.
+func (c *Client) SetObjectStorageClass(ctx context.Context, bucket, object, class string) (*storage.Object, error) {
+	if c.public {
+		return nil, errPublicClient
+	}
+	if bucket == "" {
+		return nil, errEmptyBucket
+	}
+	if object == "" {
+		return nil, errEmptyName
+	}
+	if err := validateStorageClass(class); err != nil {
+		return nil, err
 	}
 
-	oIns = oIns.PredefinedAcl(acl)
-	oIns = oIns.Media(params.Reader())
-	return oIns.Do()
+	// Large objects can take several calls to be rewritten, each
+	// call resuming from where the previous one stopped.
+	rewriteToken := ""
+	for {
+		req := c.objectsService().Rewrite(bucket, object, bucket, object, &storage.Object{StorageClass: class})
+		if rewriteToken != "" {
+			req = req.RewriteToken(rewriteToken)
+		}
+		res, err := req.Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		if res.Done {
+			return res.Resource, nil
+		}
+		rewriteToken = res.RewriteToken
+	}
+}
+
+type ObjectsRequest struct {
+	Bucket string `json:"bucket"`
+
+	// Prefix if set restricts listing to the objects whose names start with it.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Versions when set lists every generation of the objects of a
+	// versioned bucket, including the noncurrent ones, rather than
+	// only their live generations.
+	Versions bool `json:"versions,omitempty"`
+}
+
+func (oreq *ObjectsRequest) Validate() error {
+	if oreq == nil || oreq.Bucket == "" {
+		return errEmptyBucket
+	}
+	return nil
+}
+
+// ListObjects returns the objects of the bucket, and with Versions set
+// all their generations e.g to clean up the noncurrent ones.
+func (c *Client) ListObjects(ctx context.Context, oreq *ObjectsRequest) ([]*storage.Object, error) {
+	if err := oreq.Validate(); err != nil {
+		return nil, err
+	}
+
+	req := c.objectsService().List(oreq.Bucket).Versions(oreq.Versions)
+	if oreq.Prefix != "" {
+		req = req.Prefix(oreq.Prefix)
+	}
+	var objects []*storage.Object
+	err := req.Pages(ctx, func(objs *storage.Objects) error {
+		objects = append(objects, objs.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+var errNonPositiveGeneration = errors.New("expecting a positive generation")
+
+// DeleteObjectGeneration permanently deletes that generation of the
+// object, which for a versioned bucket can be a noncurrent one.
+// Deleting a held or retained object fails with ErrObjectRetained.
+func (c *Client) DeleteObjectGeneration(ctx context.Context, bucket, object string, generation int64) error {
+	if c.public {
+		return errPublicClient
+	}
+	if bucket == "" {
+		return errEmptyBucket
+	}
+	if object == "" {
+		return errEmptyName
+	}
+	if generation <= 0 {
+		return errNonPositiveGeneration
+	}
+	err := c.objectsService().Delete(bucket, object).Generation(generation).Context(ctx).Do()
+	return retentionBlockedError(object, err)
+}
+
+// PublishFile uploads the content of r as the object called name, readable
+// by anyone, to the bucket which is created if it doesn't exist yet, and
+// returns the object's public URL. The object's content type is that of
+// name's extension e.g "text/css" for "site.css", otherwise it is sniffed.
+func (c *Client) PublishFile(ctx context.Context, project, bucket, name string, r io.Reader) (string, error) {
+	project = c.projectOrDefault(project)
+	if r == nil {
+		return "", errBlankReaderFunc
+	}
+	if _, err := c.EnsureBucketExists(ctx, &BucketCheck{
+		Project: project,
+		Bucket:  bucket,
+		Public:  true,
+	}); err != nil {
+		return "", err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		// Sniff the type from the start of the content, then
+		// upload that start followed by the rest of the content.
+		head := make([]byte, 512)
+		n, err := io.ReadFull(r, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		head = head[:n]
+		contentType = http.DetectContentType(head)
+		r = io.MultiReader(bytes.NewReader(head), r)
+	}
+
+	obj, err := c.UploadWithParams(ctx, &UploadParams{
+		Project:     project,
+		Bucket:      bucket,
+		Name:        name,
+		Public:      true,
+		ContentType: contentType,
+		Reader:      func() io.Reader { return r },
+	})
+	if err != nil {
+		return "", err
+	}
+	return ObjectURL(obj), nil
 }
 
 func ObjectURL(obj *storage.Object) string {
@@ -0,0 +1,625 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/storage/v1"
+)
+
+// fakeStorage is an in-memory Cloud Storage that
+// supports creating buckets and simple object uploads.
+type fakeStorage struct {
+	mu       sync.Mutex
+	buckets  map[string]*storage.Bucket
+	objects  map[string]*storage.Object
+	contents map[string][]byte
+	rewrites int
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		buckets:  make(map[string]*storage.Bucket),
+		objects:  make(map[string]*storage.Object),
+		contents: make(map[string][]byte),
+	}
+}
+
+func (fs *fakeStorage) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	switch p := req.URL.Path; {
+	case req.Method == "POST" && p == "/storage/v1/b":
+		bucket := new(storage.Bucket)
+		if err := json.NewDecoder(req.Body).Decode(bucket); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fs.buckets[bucket.Name] = bucket
+		writeJSON(w, bucket)
+
+	case req.Method == "POST" && strings.HasPrefix(p, "/upload/storage/v1/b/"):
+		bucketName := strings.TrimSuffix(strings.TrimPrefix(p, "/upload/storage/v1/b/"), "/o")
+		obj, body, err := readMultipartUpload(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		obj.Bucket = bucketName
+		obj.Size = uint64(len(body))
+		fs.objects[bucketName+"/"+obj.Name] = obj
+		fs.contents[bucketName+"/"+obj.Name] = body
+		writeJSON(w, obj)
+
+	case req.Method == "POST" && strings.HasPrefix(p, "/storage/v1/b/") && strings.Contains(p, "/rewriteTo/"):
+		// Only in place rewrites are supported.
+		i := strings.Index(p, "/rewriteTo/")
+		key := strings.Replace(strings.TrimPrefix(p[:i], "/storage/v1/b/"), "/o/", "/", 1)
+		obj, ok := fs.objects[key]
+		if !ok {
+			writeNotFound(w)
+			return
+		}
+		update := new(storage.Object)
+		if err := json.NewDecoder(req.Body).Decode(update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fs.rewrites++
+		// Take two calls to complete to exercise resumption.
+		if req.URL.Query().Get("rewriteToken") == "" {
+			writeJSON(w, &storage.RewriteResponse{RewriteToken: "resume"})
+			return
+		}
+		rewritten := *obj
+		rewritten.StorageClass = update.StorageClass
+		rewritten.Generation++
+		fs.objects[key] = &rewritten
+		writeJSON(w, &storage.RewriteResponse{Done: true, Resource: &rewritten})
+
+	case req.Method == "PATCH" && strings.HasPrefix(p, "/storage/v1/b/") && strings.Contains(p, "/o/"):
+		key := strings.Replace(strings.TrimPrefix(p, "/storage/v1/b/"), "/o/", "/", 1)
+		obj, ok := fs.objects[key]
+		if !ok {
+			writeNotFound(w)
+			return
+		}
+		var update map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		patched := *obj
+		if hold, ok := update["temporaryHold"].(bool); ok {
+			patched.TemporaryHold = hold
+		}
+		if hold, ok := update["eventBasedHold"].(bool); ok {
+			patched.EventBasedHold = hold
+		}
+		fs.objects[key] = &patched
+		writeJSON(w, &patched)
+
+	case req.Method == "DELETE" && strings.HasPrefix(p, "/storage/v1/b/") && strings.Contains(p, "/o/"):
+		key := strings.Replace(strings.TrimPrefix(p, "/storage/v1/b/"), "/o/", "/", 1)
+		obj, ok := fs.objects[key]
+		if !ok {
+			writeNotFound(w)
+			return
+		}
+		if obj.TemporaryHold || obj.EventBasedHold {
+			w.WriteHeader(http.StatusForbidden)
+			writeJSON(w, map[string]interface{}{"error": map[string]interface{}{
+				"code":    http.StatusForbidden,
+				"message": "Object '" + key + "' is under active Temporary hold and cannot be deleted, overwritten or archived until hold is removed.",
+			}})
+			return
+		}
+		delete(fs.objects, key)
+		delete(fs.contents, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	case req.Method == "GET" && strings.HasPrefix(p, "/storage/v1/b/") && strings.Contains(p, "/o/"):
+		key := strings.Replace(strings.TrimPrefix(p, "/storage/v1/b/"), "/o/", "/", 1)
+		obj, ok := fs.objects[key]
+		if !ok {
+			writeNotFound(w)
+			return
+		}
+		if req.URL.Query().Get("alt") == "media" {
+			_, _ = w.Write(fs.contents[key])
+			return
+		}
+		writeJSON(w, obj)
+
+	case req.Method == "GET" && strings.HasPrefix(p, "/storage/v1/b/"):
+		bucket, ok := fs.buckets[strings.TrimPrefix(p, "/storage/v1/b/")]
+		if !ok {
+			writeNotFound(w)
+			return
+		}
+		writeJSON(w, bucket)
+
+	default:
+		http.Error(w, "unexpected route "+req.Method+" "+p, http.StatusNotFound)
+	}
+}
+
+func readMultipartUpload(req *http.Request) (*storage.Object, []byte, error) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	mr := multipart.NewReader(req.Body, params["boundary"])
+	metadataPart, err := mr.NextPart()
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := new(storage.Object)
+	if err := json.NewDecoder(metadataPart).Decode(obj); err != nil {
+		return nil, nil, err
+	}
+	mediaPart, err := mr.NextPart()
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := io.ReadAll(mediaPart)
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, body, nil
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": http.StatusNotFound, "message": "not found"}})
+}
+
+func TestDownloadIfGenerationNotMatch(t *testing.T) {
+	const currentGeneration = 1700000000000001
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/storage/v1/b/assets/o/app.js" || req.URL.Query().Get("alt") != "media" {
+			http.Error(w, "unexpected route "+req.URL.Path, http.StatusNotFound)
+			return
+		}
+		if req.URL.Query().Get("ifGenerationNotMatch") == strconv.Itoa(currentGeneration) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, "console.log('hello');")
+	})
+
+	ctx := context.Background()
+	body, err := client.DownloadWithParams(ctx, &DownloadParams{
+		Bucket: "assets",
+		Name:   "app.js",
+
+		IfGenerationNotMatch: currentGeneration,
+	})
+	if err != ErrNotModified {
+		t.Errorf("matching generation: got err %v want %v", err, ErrNotModified)
+	}
+	if body != nil {
+		t.Errorf("matching generation: expected no body to be transferred")
+	}
+
+	body, err = client.DownloadWithParams(ctx, &DownloadParams{
+		Bucket: "assets",
+		Name:   "app.js",
+
+		IfGenerationNotMatch: currentGeneration - 1,
+	})
+	if err != nil {
+		t.Fatalf("stale generation: unexpected err: %v", err)
+	}
+	defer body.Close()
+	blob, _ := io.ReadAll(body)
+	if got, want := string(blob), "console.log('hello');"; got != want {
+		t.Errorf("body: got %q want %q", got, want)
+	}
+}
+
+func TestObjectStorageClass(t *testing.T) {
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+
+	ctx := context.Background()
+	obj, err := client.UploadWithParams(ctx, &UploadParams{
+		Project:      "sample",
+		Bucket:       "backups",
+		Name:         "2017-06-01.tar.gz",
+		StorageClass: "NEARLINE",
+		Reader:       func() io.Reader { return strings.NewReader("backup") },
+	})
+	if err != nil {
+		t.Fatalf("UploadWithParams: %v", err)
+	}
+	if obj.StorageClass != "NEARLINE" {
+		t.Errorf("upload: got storage class %q want %q", obj.StorageClass, "NEARLINE")
+	}
+
+	obj, err = client.SetObjectStorageClass(ctx, "backups", "2017-06-01.tar.gz", "COLDLINE")
+	if err != nil {
+		t.Fatalf("SetObjectStorageClass: %v", err)
+	}
+	if obj.StorageClass != "COLDLINE" {
+		t.Errorf("rewrite: got storage class %q want %q", obj.StorageClass, "COLDLINE")
+	}
+	if fs.rewrites != 2 {
+		t.Errorf("rewrite calls: got %d want %d", fs.rewrites, 2)
+	}
+	if got := fs.objects["backups/2017-06-01.tar.gz"].StorageClass; got != "COLDLINE" {
+		t.Errorf("stored object: got storage class %q want %q", got, "COLDLINE")
+	}
+
+	if _, err := client.SetObjectStorageClass(ctx, "backups", "2017-06-01.tar.gz", "FROZEN"); err == nil {
+		t.Error("expected an error for an unknown storage class")
+	}
+	_, err = client.UploadWithParams(ctx, &UploadParams{
+		Bucket:       "backups",
+		Name:         "bad.tar.gz",
+		StorageClass: "coldest",
+		Reader:       func() io.Reader { return strings.NewReader("backup") },
+	})
+	if err == nil {
+		t.Error("upload: expected an error for an unknown storage class")
+	}
+}
+
+func TestUploadReaderAt(t *testing.T) {
+	const chunkSize = 256 * 1024
+	content := bytes.Repeat([]byte("0123456789abcdef"), (3*chunkSize)/16)
+	// Only the first Size bytes of the ReaderAt are the content.
+	readerAt := bytes.NewReader(append(append([]byte(nil), content...), "trailing bytes"...))
+
+	var (
+		uploaded []byte
+		offsets  []string
+		failed   bool
+	)
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch q := req.URL.Query(); {
+		case req.Method == "GET" && req.URL.Path == "/storage/v1/b/backups":
+			writeJSON(w, &storage.Bucket{Name: "backups"})
+
+		case req.URL.Path != "/upload/storage/v1/b/backups/o" || q.Get("uploadType") != "resumable":
+			http.Error(w, "unexpected route "+req.Method+" "+req.URL.Path, http.StatusNotFound)
+
+		case q.Get("upload_id") == "":
+			// Starting the upload session.
+			w.Header().Set("Location", "https://storage.googleapis.com/upload/storage/v1/b/backups/o?uploadType=resumable&upload_id=session-1")
+			w.WriteHeader(http.StatusOK)
+
+		default:
+			contentRange := req.Header.Get("Content-Range")
+			offsets = append(offsets, contentRange)
+			chunk, _ := io.ReadAll(req.Body)
+			if len(offsets) == 2 && !failed {
+				// The second chunk fails the first time around.
+				failed = true
+				http.Error(w, "backend unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			uploaded = append(uploaded, chunk...)
+			if strings.HasSuffix(contentRange, "/*") {
+				w.Header().Set("X-Http-Status-Code-Override", "308")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			writeJSON(w, &storage.Object{Name: "db.dump", Bucket: "backups", Size: uint64(len(uploaded))})
+		}
+	})
+
+	obj, err := client.UploadWithParams(context.Background(), &UploadParams{
+		Bucket:    "backups",
+		Name:      "db.dump",
+		ReaderAt:  readerAt,
+		Size:      int64(len(content)),
+		ChunkSize: chunkSize,
+	})
+	if err != nil {
+		t.Fatalf("UploadWithParams: %v", err)
+	}
+	if !failed {
+		t.Fatal("expected the simulated failure to have happened")
+	}
+
+	wantOffsets := []string{
+		"bytes 0-262143/*",
+		"bytes 262144-524287/*",
+		"bytes 262144-524287/*",
+		"bytes 524288-786431/*",
+	}
+	if len(offsets) < len(wantOffsets) || !reflect.DeepEqual(offsets[:len(wantOffsets)], wantOffsets) {
+		t.Errorf("chunk ranges:\ngot  %q\nwant %q followed by the final chunk", offsets, wantOffsets)
+	}
+	if !bytes.Equal(uploaded, content) {
+		t.Errorf("uploaded %d bytes that don't match the %d bytes of content", len(uploaded), len(content))
+	}
+	if obj.Size != uint64(len(content)) {
+		t.Errorf("object size: got %d want %d", obj.Size, len(content))
+	}
+}
+
+func TestUploadParamsValidate(t *testing.T) {
+	reader := func() io.Reader { return strings.NewReader("content") }
+	tests := [...]struct {
+		params *UploadParams
+		want   error
+	}{
+		0: {params: &UploadParams{Bucket: "b", Name: "n", Reader: reader}},
+		1: {params: &UploadParams{Bucket: "b", Name: "n", ReaderAt: strings.NewReader("content"), Size: 7}},
+		2: {params: &UploadParams{Bucket: "b", Name: "n"}, want: errBlankReaderFunc},
+		3: {params: &UploadParams{Bucket: "b", Name: "n", Reader: reader, ReaderAt: strings.NewReader("content"), Size: 7}, want: errReaderAndReaderAt},
+		4: {params: &UploadParams{Bucket: "b", Name: "n", ReaderAt: strings.NewReader("content")}, want: errNonPositiveSize},
+	}
+
+	for i, tt := range tests {
+		if err := tt.params.Validate(); err != tt.want {
+			t.Errorf("#%d: got err %v want %v", i, err, tt.want)
+		}
+	}
+}
+
+func TestUploadStreamsInChunks(t *testing.T) {
+	const (
+		chunkSize = 256 * 1024
+		chunks    = 5
+	)
+
+	var (
+		mu            sync.Mutex
+		written       int
+		maxChunk      int
+		writtenAtRecv []int
+		uploaded      int
+	)
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch q := req.URL.Query(); {
+		case req.Method == "GET" && req.URL.Path == "/storage/v1/b/logs":
+			writeJSON(w, &storage.Bucket{Name: "logs"})
+
+		case req.URL.Path != "/upload/storage/v1/b/logs/o" || q.Get("uploadType") != "resumable":
+			http.Error(w, "unexpected route "+req.Method+" "+req.URL.Path, http.StatusNotFound)
+
+		case q.Get("upload_id") == "":
+			w.Header().Set("Location", "https://storage.googleapis.com/upload/storage/v1/b/logs/o?uploadType=resumable&upload_id=session-1")
+			w.WriteHeader(http.StatusOK)
+
+		default:
+			chunk, _ := io.ReadAll(req.Body)
+			mu.Lock()
+			writtenAtRecv = append(writtenAtRecv, written)
+			mu.Unlock()
+			if len(chunk) > maxChunk {
+				maxChunk = len(chunk)
+			}
+			uploaded += len(chunk)
+			if strings.HasSuffix(req.Header.Get("Content-Range"), "/*") {
+				w.Header().Set("X-Http-Status-Code-Override", "308")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			writeJSON(w, &storage.Object{Name: "build.log", Bucket: "logs", Size: uint64(uploaded)})
+		}
+	})
+
+	// The content comes from a pipe, so it can't be seeked nor sized.
+	pr, pw := io.Pipe()
+	go func() {
+		line := bytes.Repeat([]byte("x"), 1024)
+		for i := 0; i < chunks*chunkSize/len(line); i++ {
+			if _, err := pw.Write(line); err != nil {
+				return
+			}
+			mu.Lock()
+			written += len(line)
+			mu.Unlock()
+		}
+		pw.Close()
+	}()
+
+	obj, err := client.UploadWithParams(context.Background(), &UploadParams{
+		Bucket:    "logs",
+		Name:      "build.log",
+		Reader:    func() io.Reader { return pr },
+		ChunkSize: chunkSize,
+	})
+	if err != nil {
+		t.Fatalf("UploadWithParams: %v", err)
+	}
+	if obj.Size != chunks*chunkSize {
+		t.Errorf("uploaded %d bytes want %d", obj.Size, chunks*chunkSize)
+	}
+	if maxChunk > chunkSize {
+		t.Errorf("got a chunk of %d bytes, want at most %d", maxChunk, chunkSize)
+	}
+	if len(writtenAtRecv) < chunks {
+		t.Fatalf("got %d chunk requests want at least %d", len(writtenAtRecv), chunks)
+	}
+	// Only a chunk is buffered ahead so the first chunk must be sent
+	// long before the writer is done, rather than after buffering it all.
+	if first := writtenAtRecv[0]; first > 2*chunkSize {
+		t.Errorf("the first chunk was sent after %d bytes were written, want at most %d buffered", first, 2*chunkSize)
+	}
+}
+
+func TestListAndDeleteObjectGenerations(t *testing.T) {
+	generations := []*storage.Object{
+		{Bucket: "backups", Name: "db.sql", Generation: 1},
+		{Bucket: "backups", Name: "db.sql", Generation: 2},
+		{Bucket: "backups", Name: "db.sql", Generation: 3},
+	}
+	var deleted []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET /storage/v1/b/backups/o":
+			items := generations[len(generations)-1:]
+			if req.URL.Query().Get("versions") == "true" {
+				items = generations
+			}
+			writeJSON(w, &storage.Objects{Items: items})
+		case "DELETE /storage/v1/b/backups/o/db.sql":
+			deleted = append(deleted, req.URL.Query().Get("generation"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	live, err := client.ListObjects(ctx, &ObjectsRequest{Bucket: "backups"})
+	if err != nil {
+		t.Fatalf("ListObjects: %v", err)
+	}
+	if len(live) != 1 || live[0].Generation != 3 {
+		t.Errorf("expected only the live generation, got %+v", live)
+	}
+
+	all, err := client.ListObjects(ctx, &ObjectsRequest{Bucket: "backups", Versions: true})
+	if err != nil {
+		t.Fatalf("ListObjects with versions: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected all 3 generations, got %d", len(all))
+	}
+
+	if err := client.DeleteObjectGeneration(ctx, "backups", "db.sql", all[0].Generation); err != nil {
+		t.Fatalf("DeleteObjectGeneration: %v", err)
+	}
+	if want := []string{"1"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted generations: got %q want %q", deleted, want)
+	}
+	if err := client.DeleteObjectGeneration(ctx, "backups", "db.sql", 0); err != errNonPositiveGeneration {
+		t.Errorf("zero generation: got err %v want %v", err, errNonPositiveGeneration)
+	}
+}
+
+func TestEnsureBucketExistsAutoclassAndTurboReplication(t *testing.T) {
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+
+	ctx := context.Background()
+	_, err := client.EnsureBucketExists(ctx, &BucketCheck{
+		Project:          "sample",
+		Bucket:           "assets",
+		Location:         "nam4",
+		Autoclass:        true,
+		TurboReplication: true,
+	})
+	if err != nil {
+		t.Fatalf("EnsureBucketExists: %v", err)
+	}
+	bucket := fs.buckets["assets"]
+	if bucket == nil {
+		t.Fatal("expected the bucket to be created")
+	}
+	if bucket.Autoclass == nil || !bucket.Autoclass.Enabled {
+		t.Errorf("expected Autoclass to be enabled, got %+v", bucket.Autoclass)
+	}
+	if bucket.Rpo != "ASYNC_TURBO" || bucket.Location != "nam4" {
+		t.Errorf("got RPO %q in %q, want turbo replication in nam4", bucket.Rpo, bucket.Location)
+	}
+
+	_, err = client.EnsureBucketExists(ctx, &BucketCheck{Project: "sample", Bucket: "regional", Location: "us-central1", TurboReplication: true})
+	if err == nil || !strings.Contains(err.Error(), "only for dual-region") {
+		t.Errorf("got err %v, want turbo replication rejected for a region", err)
+	}
+	if _, ok := fs.buckets["regional"]; ok {
+		t.Error("the regional bucket mustn't be created")
+	}
+}
+
+func TestPublishFile(t *testing.T) {
+	var bucketACL, objectACL string
+	fs := newFakeStorage()
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if acl := req.URL.Query().Get("predefinedDefaultObjectAcl"); acl != "" {
+			bucketACL = acl
+		}
+		if acl := req.URL.Query().Get("predefinedAcl"); acl != "" {
+			objectACL = acl
+		}
+		fs.ServeHTTP(w, req)
+	})
+
+	ctx := context.Background()
+	tests := []struct {
+		name, content, wantContentType string
+	}{
+		{name: "css/site.css", content: "body {}", wantContentType: "text/css; charset=utf-8"},
+		{name: "LICENSE", content: "%PDF-1.4 license", wantContentType: "application/pdf"},
+	}
+	for _, tt := range tests {
+		url, err := client.PublishFile(ctx, "sample", "assets", tt.name, strings.NewReader(tt.content))
+		if err != nil {
+			t.Fatalf("%q: PublishFile: %v", tt.name, err)
+		}
+		if want := "https://storage.googleapis.com/assets/" + tt.name; url != want {
+			t.Errorf("%q: URL: got %q want %q", tt.name, url, want)
+		}
+		if got := string(fs.contents["assets/"+tt.name]); got != tt.content {
+			t.Errorf("%q: content: got %q want %q", tt.name, got, tt.content)
+		}
+		if got := fs.objects["assets/"+tt.name].ContentType; got != tt.wantContentType {
+			t.Errorf("%q: content type: got %q want %q", tt.name, got, tt.wantContentType)
+		}
+	}
+	if _, ok := fs.buckets["assets"]; !ok {
+		t.Errorf("expected the bucket to be created")
+	}
+	if bucketACL != "publicRead" || objectACL != "publicRead" {
+		t.Errorf("ACLs: got bucket %q object %q, want both to be publicRead", bucketACL, objectACL)
+	}
+}
+
+func TestUploadInheritBucketACL(t *testing.T) {
+	var objectACLs []string
+	fs := newFakeStorage()
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/upload/") && req.URL.Query().Get("upload_id") == "" {
+			objectACLs = append(objectACLs, req.URL.Query().Get("predefinedAcl"))
+		}
+		fs.ServeHTTP(w, req)
+	})
+
+	ctx := context.Background()
+	for _, inherit := range []bool{false, true} {
+		_, err := client.UploadWithParams(ctx, &UploadParams{
+			Project: "sample",
+			Bucket:  "reports",
+			Name:    "q1.csv",
+			Reader:  func() io.Reader { return strings.NewReader("a,b\n") },
+
+			InheritBucketACL: inherit,
+		})
+		if err != nil {
+			t.Fatalf("inherit %t: UploadWithParams: %v", inherit, err)
+		}
+	}
+	if want := []string{"private", ""}; !reflect.DeepEqual(objectACLs, want) {
+		t.Errorf("predefined ACLs: got %q want %q", objectACLs, want)
+	}
+
+	err := (&UploadParams{
+		Bucket: "reports",
+		Name:   "q1.csv",
+		Reader: func() io.Reader { return strings.NewReader("a,b\n") },
+
+		Public:           true,
+		InheritBucketACL: true,
+	}).Validate()
+	if err != errPublicAndInheritACL {
+		t.Errorf("got err %v want %v", err, errPublicAndInheritACL)
+	}
+}
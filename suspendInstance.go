@@ -0,0 +1,50 @@
+package infra
+
+import (
+	"context"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// SuspendInstance suspends the instance identified by ireq: unlike
+// stopping it, its memory is saved to disk so that ResumeInstance later
+// resumes it where it left off. Instances can't be suspended if they
+// have more than 208GB of memory, GPUs or local SSDs, nor for some
+// machine families such as the memory-optimized M series, and a
+// suspended instance is stopped after 60 days. If
+// ireq.BlockUntilCompletion is set, it waits for the suspension to
+// complete.
+func (c *Client) SuspendInstance(ctx context.Context, ireq *InstanceRequest) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+
+	op, err := c.instancesService().Suspend(ireq.Project, ireq.Zone, ireq.Name).Context(ctx).Do()
+	return c.maybeWaitForZoneOperation(ctx, ireq, op, err)
+}
+
+// ResumeInstance resumes the instance, identified by ireq, that was
+// suspended with SuspendInstance. If ireq.BlockUntilCompletion is set,
+// it waits for the instance to be running again.
+func (c *Client) ResumeInstance(ctx context.Context, ireq *InstanceRequest) (*compute.Operation, error) {
+	ireq = inProject(ireq, c.DefaultProject)
+	if err := ireq.validateForByName(); err != nil {
+		return nil, err
+	}
+
+	op, err := c.instancesService().Resume(ireq.Project, ireq.Zone, ireq.Name).Context(ctx).Do()
+	return c.maybeWaitForZoneOperation(ctx, ireq, op, err)
+}
+
+// maybeWaitForZoneOperation returns the operation that was started for
+// ireq, waiting for it to complete if ireq.BlockUntilCompletion is set.
+func (c *Client) maybeWaitForZoneOperation(ctx context.Context, ireq *InstanceRequest, op *compute.Operation, err error) (*compute.Operation, error) {
+	if err != nil {
+		return nil, err
+	}
+	if !ireq.BlockUntilCompletion {
+		return op, operationError(op)
+	}
+	return c.waitForZoneOperation(ctx, ireq.Project, ireq.Zone, op)
+}
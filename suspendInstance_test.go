@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestSuspendAndResumeInstance(t *testing.T) {
+	const instancePath = "/compute/v1/projects/sample/zones/us-central1-c/instances/web"
+	var routes []string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		route := req.Method + " " + req.URL.Path
+		routes = append(routes, route)
+		switch route {
+		case "POST " + instancePath + "/suspend":
+			writeJSON(w, &compute.Operation{Name: "suspend-1", Status: "RUNNING"})
+		case "POST " + instancePath + "/resume":
+			writeJSON(w, &compute.Operation{Name: "resume-1", Status: "RUNNING"})
+		case "POST /compute/v1/projects/sample/zones/us-central1-c/operations/resume-1/wait":
+			writeJSON(w, &compute.Operation{Name: "resume-1", Status: "DONE"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+	client.OperationPollInterval = time.Millisecond
+	ctx := context.Background()
+
+	ireq := &InstanceRequest{Project: "sample", Zone: "us-central1-c", Name: "web"}
+	op, err := client.SuspendInstance(ctx, ireq)
+	if err != nil {
+		t.Fatalf("SuspendInstance: %v", err)
+	}
+	if op.Name != "suspend-1" {
+		t.Errorf("got operation %q want %q", op.Name, "suspend-1")
+	}
+
+	ireq.BlockUntilCompletion = true
+	if op, err = client.ResumeInstance(ctx, ireq); err != nil {
+		t.Fatalf("ResumeInstance: %v", err)
+	}
+	if op.Status != "DONE" {
+		t.Errorf("expected to wait for the resumption, got status %q", op.Status)
+	}
+
+	want := []string{
+		"POST " + instancePath + "/suspend",
+		"POST " + instancePath + "/resume",
+		"POST /compute/v1/projects/sample/zones/us-central1-c/operations/resume-1/wait",
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("routes: got %q want %q", routes, want)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route #%d: got %q want %q", i, routes[i], want[i])
+		}
+	}
+
+	blank := &InstanceRequest{Project: "sample", Zone: "us-central1-c"}
+	if _, err := client.SuspendInstance(ctx, blank); err != errBlankName {
+		t.Errorf("SuspendInstance: got err %v want %v", err, errBlankName)
+	}
+	if _, err := client.ResumeInstance(ctx, blank); err != errBlankName {
+		t.Errorf("ResumeInstance: got err %v want %v", err, errBlankName)
+	}
+}
@@ -0,0 +1,160 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/compute/v1"
+)
+
+var errBlankTemplateName = errors.New("expecting a non-blank template name")
+
+func (c *Client) instanceTemplatesService() *compute.InstanceTemplatesService {
+	return compute.NewInstanceTemplatesService(c.computeSrvc)
+}
+
+// url returns the URL of the template, as expected by the
+// sourceInstanceTemplate of the instances created from it.
+func (treq *InstanceTemplateRequest) url() string {
+	if treq.Region != "" {
+		return SelfLink(treq.Project, "regions", treq.Region, "instanceTemplates", treq.Name)
+	}
+	return SelfLink(treq.Project, "global", "instanceTemplates", treq.Name)
+}
+
+// CreateInstanceFromTemplate creates the instance called name in the
+// given project and zone, with all its properties taken from the global
+// instance template called templateName in the same project.
+func (c *Client) CreateInstanceFromTemplate(ctx context.Context, project, zone, name, templateName string) (*compute.Operation, error) {
+	treq := &InstanceTemplateRequest{Project: project, Name: templateName, Global: true}
+	return c.CreateInstanceFromInstanceTemplate(ctx, zone, name, treq)
+}
+
+var errTemplateRegion = errors.New("a regional instance template can only create instances in zones of its region")
+
+// CreateInstanceFromInstanceTemplate creates the instance called name in
+// the zone, in treq's project, with all its properties taken from the
+// global or regional instance template that treq identifies. A regional
+// template only creates instances in the zones of its region.
+func (c *Client) CreateInstanceFromInstanceTemplate(ctx context.Context, zone, name string, treq *InstanceTemplateRequest) (*compute.Operation, error) {
+	treq = inProject(treq, c.DefaultProject)
+	if err := treq.Validate(); err != nil {
+		return nil, err
+	}
+	ireq := &InstanceRequest{Project: treq.Project, Zone: zone, Name: name}
+	if err := ireq.validateBasic(); err != nil {
+		return nil, err
+	}
+	if treq.Region != "" && treq.Region != regionFromZone(zone) {
+		return nil, errTemplateRegion
+	}
+
+	// Look up the template first since a missing template
+	// otherwise surfaces as an obscure error from the insert.
+	if _, err := c.InstanceTemplate(ctx, treq); err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("instance template %q not found in project %q", treq.Name, treq.Project)
+		}
+		return nil, err
+	}
+
+	req := c.instancesService().Insert(treq.Project, zone, &compute.Instance{Name: name})
+	req = req.SourceInstanceTemplate(treq.url())
+	return req.Context(ctx).Do()
+}
+
+func (c *Client) regionInstanceTemplatesService() *compute.RegionInstanceTemplatesService {
+	return compute.NewRegionInstanceTemplatesService(c.computeSrvc)
+}
+
+type InstanceTemplateRequest struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+
+	// Exactly one of Global and Region must be set: Global for a
+	// global template usable in every region, or Region e.g
+	// "us-central1" for a regional template only usable in it.
+	Global bool   `json:"global,omitempty"`
+	Region string `json:"region,omitempty"`
+
+	// Instance is the configuration of the instances created from the
+	// template, whose Project, Zone and Name are ignored. It is only
+	// needed to create the template.
+	Instance *InstanceRequest `json:"instance,omitempty"`
+}
+
+var (
+	errGlobalOrRegion      = errors.New("expecting exactly one of Global and Region")
+	errNilTemplateInstance = errors.New("expecting a non-nil instance configuration")
+)
+
+func (treq *InstanceTemplateRequest) Validate() error {
+	if treq == nil || treq.Project == "" {
+		return errEmptyProject
+	}
+	if treq.Name == "" {
+		return errBlankTemplateName
+	}
+	if treq.Global == (treq.Region != "") {
+		return errGlobalOrRegion
+	}
+	return nil
+}
+
+// CreateInstanceTemplate creates the instance template described by treq,
+// in treq.Region if it is regional otherwise globally.
+func (c *Client) CreateInstanceTemplate(ctx context.Context, treq *InstanceTemplateRequest) (*compute.Operation, error) {
+	treq = inProject(treq, c.DefaultProject)
+	if err := treq.Validate(); err != nil {
+		return nil, err
+	}
+	if treq.Instance == nil {
+		return nil, errNilTemplateInstance
+	}
+
+	ireq := *treq.Instance
+	ireq.Project = treq.Project
+	ireq.Zone = ""
+	ireq.Name = ""
+	if err := ireq.validateProperties(); err != nil {
+		return nil, err
+	}
+	fromFiles, err := ireq.readMetadataFromFiles()
+	if err != nil {
+		return nil, err
+	}
+	template := &compute.InstanceTemplate{
+		Name:       treq.Name,
+		Properties: ireq.toInstanceProperties(fromFiles),
+	}
+
+	if treq.Region != "" {
+		return c.regionInstanceTemplatesService().Insert(treq.Project, treq.Region, template).Context(ctx).Do()
+	}
+	return c.instanceTemplatesService().Insert(treq.Project, template).Context(ctx).Do()
+}
+
+// InstanceTemplate returns the instance template identified by treq.
+func (c *Client) InstanceTemplate(ctx context.Context, treq *InstanceTemplateRequest) (*compute.InstanceTemplate, error) {
+	treq = inProject(treq, c.DefaultProject)
+	if err := treq.Validate(); err != nil {
+		return nil, err
+	}
+	if treq.Region != "" {
+		return c.regionInstanceTemplatesService().Get(treq.Project, treq.Region, treq.Name).Context(ctx).Do()
+	}
+	return c.instanceTemplatesService().Get(treq.Project, treq.Name).Context(ctx).Do()
+}
+
+// DeleteInstanceTemplate deletes the instance template identified by treq.
+func (c *Client) DeleteInstanceTemplate(ctx context.Context, treq *InstanceTemplateRequest) (*compute.Operation, error) {
+	treq = inProject(treq, c.DefaultProject)
+	if err := treq.Validate(); err != nil {
+		return nil, err
+	}
+	if treq.Region != "" {
+		return c.regionInstanceTemplatesService().Delete(treq.Project, treq.Region, treq.Name).Context(ctx).Do()
+	}
+	return c.instanceTemplatesService().Delete(treq.Project, treq.Name).Context(ctx).Do()
+}
@@ -0,0 +1,154 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestCreateInstanceFromTemplate(t *testing.T) {
+	var sourceTemplate string
+	var inserted *compute.Instance
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET /compute/v1/projects/sample/global/instanceTemplates/web":
+			writeJSON(w, &compute.InstanceTemplate{Name: "web"})
+		case "POST /compute/v1/projects/sample/zones/us-central1-c/instances":
+			sourceTemplate = req.URL.Query().Get("sourceInstanceTemplate")
+			inserted = new(compute.Instance)
+			readJSON(t, req, inserted)
+			writeJSON(w, &compute.Operation{Name: "op-1"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	op, err := client.CreateInstanceFromTemplate(context.Background(), "sample", "us-central1-c", "web-1", "web")
+	if err != nil {
+		t.Fatalf("CreateInstanceFromTemplate: %v", err)
+	}
+	if op.Name != "op-1" {
+		t.Errorf("operation name: got %q want %q", op.Name, "op-1")
+	}
+	if want := "https://www.googleapis.com/compute/v1/projects/sample/global/instanceTemplates/web"; sourceTemplate != want {
+		t.Errorf("sourceInstanceTemplate: got %q want %q", sourceTemplate, want)
+	}
+	if inserted == nil || inserted.Name != "web-1" {
+		t.Errorf("unexpected inserted instance: %+v", inserted)
+	}
+}
+
+func TestCreateInstanceFromRegionalTemplate(t *testing.T) {
+	var sourceTemplate string
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET /compute/v1/projects/sample/regions/us-central1/instanceTemplates/web":
+			writeJSON(w, &compute.InstanceTemplate{Name: "web"})
+		case "POST /compute/v1/projects/sample/zones/us-central1-c/instances":
+			sourceTemplate = req.URL.Query().Get("sourceInstanceTemplate")
+			writeJSON(w, &compute.Operation{Name: "op-1"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+	ctx := context.Background()
+
+	treq := &InstanceTemplateRequest{Project: "sample", Name: "web", Region: "us-central1"}
+	if _, err := client.CreateInstanceFromInstanceTemplate(ctx, "us-central1-c", "web-1", treq); err != nil {
+		t.Fatalf("CreateInstanceFromInstanceTemplate: %v", err)
+	}
+	if want := "https://www.googleapis.com/compute/v1/projects/sample/regions/us-central1/instanceTemplates/web"; sourceTemplate != want {
+		t.Errorf("sourceInstanceTemplate: got %q want %q", sourceTemplate, want)
+	}
+
+	if _, err := client.CreateInstanceFromInstanceTemplate(ctx, "europe-west1-b", "web-2", treq); err != errTemplateRegion {
+		t.Errorf("zone outside the region: got err %v want %v", err, errTemplateRegion)
+	}
+}
+
+func TestCreateInstanceFromMissingTemplate(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]*googleapi.Error{"error": {Code: http.StatusNotFound, Message: "not found"}})
+	})
+
+	_, err := client.CreateInstanceFromTemplate(context.Background(), "sample", "us-central1-c", "web-1", "missing")
+	if err == nil || !strings.Contains(err.Error(), `instance template "missing" not found`) {
+		t.Errorf("got err %v, want a template not found error", err)
+	}
+}
+
+func TestCreateInstanceTemplate(t *testing.T) {
+	var routes []string
+	var created *compute.InstanceTemplate
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		route := req.Method + " " + req.URL.Path
+		routes = append(routes, route)
+		switch route {
+		case "POST /compute/v1/projects/sample/regions/us-central1/instanceTemplates",
+			"POST /compute/v1/projects/sample/global/instanceTemplates":
+			created = new(compute.InstanceTemplate)
+			readJSON(t, req, created)
+			writeJSON(w, &compute.Operation{Name: "op-template"})
+		case "GET /compute/v1/projects/sample/regions/us-central1/instanceTemplates/web":
+			writeJSON(w, &compute.InstanceTemplate{Name: "web", Region: "us-central1"})
+		case "DELETE /compute/v1/projects/sample/regions/us-central1/instanceTemplates/web":
+			writeJSON(w, &compute.Operation{Name: "op-delete"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	treq := &InstanceTemplateRequest{
+		Project: "sample",
+		Name:    "web",
+		Region:  "us-central1",
+		Instance: &InstanceRequest{
+			NetworkInterface: BasicExternalNATNetworkInterface,
+			MachineType:      &MachineType{Type: "n2-standard-4"},
+			Labels:           map[string]string{"team": "frontend"},
+		},
+	}
+	if _, err := client.CreateInstanceTemplate(ctx, treq); err != nil {
+		t.Fatalf("CreateInstanceTemplate: %v", err)
+	}
+	if want := "POST /compute/v1/projects/sample/regions/us-central1/instanceTemplates"; len(routes) != 1 || routes[0] != want {
+		t.Errorf("routes: got %q want %q", routes, want)
+	}
+	if created.Name != "web" || created.Properties == nil || created.Properties.MachineType != "n2-standard-4" || created.Properties.Labels["team"] != "frontend" {
+		t.Errorf("unexpected template: %+v", created)
+	}
+	if template, err := client.InstanceTemplate(ctx, treq); err != nil || template.Region != "us-central1" {
+		t.Errorf("InstanceTemplate: got %+v, %v", template, err)
+	}
+	if _, err := client.DeleteInstanceTemplate(ctx, treq); err != nil {
+		t.Errorf("DeleteInstanceTemplate: %v", err)
+	}
+
+	routes = nil
+	global := *treq
+	global.Region, global.Global = "", true
+	if _, err := client.CreateInstanceTemplate(ctx, &global); err != nil {
+		t.Fatalf("global CreateInstanceTemplate: %v", err)
+	}
+	if want := "POST /compute/v1/projects/sample/global/instanceTemplates"; len(routes) != 1 || routes[0] != want {
+		t.Errorf("global routes: got %q want %q", routes, want)
+	}
+
+	for _, bad := range []*InstanceTemplateRequest{
+		{Project: "sample", Name: "web", Instance: treq.Instance},
+		{Project: "sample", Name: "web", Global: true, Region: "us-central1", Instance: treq.Instance},
+	} {
+		if _, err := client.CreateInstanceTemplate(ctx, bad); err != errGlobalOrRegion {
+			t.Errorf("global %v region %q: got err %v want %v", bad.Global, bad.Region, err, errGlobalOrRegion)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/api/storage/v1"
+)
+
+type UploadDirParams struct {
+	Project string `json:"project"`
+	Public  bool   `json:"public"`
+	Bucket  string `json:"bucket"`
+
+	// Dir is the local directory whose files are uploaded. Each
+	// file's object name is its path relative to Dir, with Prefix
+	// prepended if set.
+	Dir    string `json:"dir"`
+	Prefix string `json:"prefix"`
+
+	// Concurrency is the maximum number of files that are
+	// uploaded at once. If unset, defaultUploadDirConcurrency is used.
+	Concurrency int `json:"concurrency"`
+
+	// Manifest if set maps glob patterns, as matched by path.Match,
+	// to the metadata of the files that they match, such as their
+	// ContentType and CacheControl. See UploadDirWithManifest.
+	Manifest map[string]UploadParams `json:"manifest,omitempty"`
+
+	// OnFile if set is invoked after each file is uploaded with
+	// the file's path, the number of files uploaded so far and
+	// the total number of files. Invocations are serialized so
+	// OnFile doesn't need to guard its state.
+	OnFile func(path string, done, total int) `json:"-"`
+}
+
+type UploadDirResponse struct {
+	Objects []*storage.Object `json:"objects,omitempty"`
+
+	// Bytes is the total number of bytes uploaded.
+	Bytes int64 `json:"bytes"`
+}
+
+const defaultUploadDirConcurrency = 4
+
+var errEmptyDir = errors.New("expecting a non-empty dir")
+
+func (params *UploadDirParams) Validate() error {
+	if params == nil || params.Dir == "" {
+		return errEmptyDir
+	}
+	if params.Bucket == "" {
+		return errEmptyBucket
+	}
+	return validateManifest(params.Manifest)
+}
+
+func (params *UploadDirParams) concurrency() int {
+	if params.Concurrency > 0 {
+		return params.Concurrency
+	}
+	return defaultUploadDirConcurrency
+}
+
+// UploadDir uploads every regular file under params.Dir concurrently,
+// returning the first error encountered if any.
+func (c *Client) UploadDir(ctx context.Context, params *UploadDirParams) (*UploadDirResponse, error) {
+	params = inProject(params, c.DefaultProject)
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err := filepath.Walk(params.Dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure the bucket exists once upfront rather
+	// than having the concurrent uploads race to create it.
+	if _, err := c.EnsureBucketExists(ctx, &BucketCheck{
+		Project: params.Project,
+		Bucket:  params.Bucket,
+		Public:  params.Public,
+	}); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		done     int
+		wg       sync.WaitGroup
+	)
+
+	dres := new(UploadDirResponse)
+	sema := make(chan bool, params.concurrency())
+	for _, p := range paths {
+		sema <- true
+		wg.Add(1)
+		go func(p string) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			obj, err := c.uploadFile(ctx, params, p)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			done += 1
+			dres.Bytes += int64(obj.Size)
+			dres.Objects = append(dres.Objects, obj)
+			if params.OnFile != nil {
+				params.OnFile(p, done, len(paths))
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return dres, nil
+}
+
+func (c *Client) uploadFile(ctx context.Context, params *UploadDirParams, p string) (*storage.Object, error) {
+	relPath, err := filepath.Rel(params.Dir, p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	uparams := &UploadParams{
+		Project: params.Project,
+		Public:  params.Public,
+		Bucket:  params.Bucket,
+		Name:    path.Join(params.Prefix, filepath.ToSlash(relPath)),
+		Reader:  func() io.Reader { return f },
+	}
+	if entry := params.manifestEntry(filepath.ToSlash(relPath)); entry != nil {
+		entry.applyTo(uparams)
+	}
+	return c.UploadWithParams(ctx, uparams)
+}
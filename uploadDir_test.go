@@ -0,0 +1,75 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestUploadDirReportsProgress(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":     "<html></html>",
+		"css/site.css":   "body {}",
+		"js/app/main.js": "main();",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+
+	var dones []int
+	var paths []string
+	dres, err := client.UploadDir(context.Background(), &UploadDirParams{
+		Project: "sample",
+		Bucket:  "site",
+		Dir:     dir,
+		Prefix:  "v1",
+
+		Concurrency: 2,
+		OnFile: func(path string, done, total int) {
+			if total != len(files) {
+				t.Errorf("total: got %d want %d", total, len(files))
+			}
+			dones = append(dones, done)
+			paths = append(paths, path)
+		},
+	})
+	if err != nil {
+		t.Fatalf("UploadDir: %v", err)
+	}
+
+	if len(dones) != len(files) {
+		t.Fatalf("OnFile calls: got %d want %d", len(dones), len(files))
+	}
+	for i, done := range dones {
+		if done != i+1 {
+			t.Errorf("OnFile call #%d: got done=%d want %d", i, done, i+1)
+		}
+	}
+	sort.Strings(paths)
+	if paths[0] == paths[1] || paths[1] == paths[2] {
+		t.Errorf("OnFile invoked more than once for a file: %q", paths)
+	}
+
+	wantBytes := 0
+	for name, content := range files {
+		wantBytes += len(content)
+		if got := string(fs.contents["site/v1/"+name]); got != content {
+			t.Errorf("object %q: got %q want %q", name, got, content)
+		}
+	}
+	if dres.Bytes != int64(wantBytes) {
+		t.Errorf("bytes: got %d want %d", dres.Bytes, wantBytes)
+	}
+}
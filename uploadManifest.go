@@ -0,0 +1,91 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// UploadDirWithManifest uploads every regular file under localDir to the
+// bucket like UploadDir, naming each object its path relative to localDir
+// with prefix prepended if set. Files that a pattern of the manifest
+// matches, such as "*.css" or "assets/*.js", are uploaded with that
+// entry's metadata e.g its CacheControl, while the others are uploaded
+// with the defaults. When several patterns match a file, the longest
+// one wins as the most specific. Entries only set object metadata, so
+// ones that set Public, InheritBucketACL or ContentAddressed are rejected.
+func (c *Client) UploadDirWithManifest(ctx context.Context, localDir, bucket, prefix string, manifest map[string]UploadParams) (*UploadDirResponse, error) {
+	return c.UploadDir(ctx, &UploadDirParams{
+		Bucket:   bucket,
+		Dir:      localDir,
+		Prefix:   prefix,
+		Manifest: manifest,
+	})
+}
+
+var errManifestUploadSettings = errors.New("expecting manifest entries to only set object metadata, not Public, InheritBucketACL or ContentAddressed")
+
+func validateManifest(manifest map[string]UploadParams) error {
+	for pattern, entry := range manifest {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("manifest pattern %q: %w", pattern, err)
+		}
+		if entry.Public || entry.InheritBucketACL || entry.ContentAddressed {
+			return fmt.Errorf("manifest pattern %q: %w", pattern, errManifestUploadSettings)
+		}
+		if entry.StorageClass != "" {
+			if err := validateStorageClass(entry.StorageClass); err != nil {
+				return fmt.Errorf("manifest pattern %q: %w", pattern, err)
+			}
+		}
+		if entry.KMSKeyName != "" {
+			if err := validateKMSKeyName(entry.KMSKeyName); err != nil {
+				return fmt.Errorf("manifest pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// manifestEntry returns the manifest entry for the file at relPath, a
+// slash separated path relative to Dir, or nil if no pattern matches it.
+// Patterns without a slash are matched against the file's base name, so
+// that "*.css" matches CSS files in any directory. When several patterns
+// match, the longest one wins as the most specific.
+func (params *UploadDirParams) manifestEntry(relPath string) *UploadParams {
+	var matched []string
+	for pattern := range params.Manifest {
+		name := relPath
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relPath)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			matched = append(matched, pattern)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if len(matched[i]) != len(matched[j]) {
+			return len(matched[i]) > len(matched[j])
+		}
+		return matched[i] < matched[j]
+	})
+	entry := params.Manifest[matched[0]]
+	return &entry
+}
+
+// applyTo sets the object metadata of the manifest entry on params,
+// leaving where and how the file is uploaded to UploadDirParams.
+func (entry *UploadParams) applyTo(params *UploadParams) {
+	params.ContentType = entry.ContentType
+	params.CacheControl = entry.CacheControl
+	params.StorageClass = entry.StorageClass
+	params.KMSKeyName = entry.KMSKeyName
+	params.TemporaryHold = entry.TemporaryHold
+	params.EventBasedHold = entry.EventBasedHold
+}
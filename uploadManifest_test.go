@@ -0,0 +1,86 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadDirWithManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":      "<html></html>",
+		"css/site.css":    "body {}",
+		"css/vendor.css":  "p {}",
+		"js/app/main.js":  "main();",
+		"fonts/inter.txt": "not really a font",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := newFakeStorage()
+	client := newTestClient(t, fs.ServeHTTP)
+
+	manifest := map[string]UploadParams{
+		"*.css":          {ContentType: "text/css", CacheControl: "public, max-age=31536000, immutable"},
+		"css/vendor.css": {ContentType: "text/css", CacheControl: "no-cache"},
+		"js/*/*.js":      {ContentType: "application/javascript", CacheControl: "public, max-age=600"},
+	}
+	dres, err := client.UploadDirWithManifest(context.Background(), dir, "site", "v2", manifest)
+	if err != nil {
+		t.Fatalf("UploadDirWithManifest: %v", err)
+	}
+	if len(dres.Objects) != len(files) {
+		t.Fatalf("objects: got %d want %d", len(dres.Objects), len(files))
+	}
+
+	tests := []struct {
+		name             string
+		wantCacheControl string
+		wantContentType  string
+	}{
+		{name: "css/site.css", wantCacheControl: "public, max-age=31536000, immutable", wantContentType: "text/css"},
+		// The more specific pattern wins.
+		{name: "css/vendor.css", wantCacheControl: "no-cache", wantContentType: "text/css"},
+		{name: "js/app/main.js", wantCacheControl: "public, max-age=600", wantContentType: "application/javascript"},
+		// Unmatched files get the defaults.
+		{name: "index.html"},
+		{name: "fonts/inter.txt"},
+	}
+	for _, tt := range tests {
+		obj := fs.objects["site/v2/"+tt.name]
+		if obj == nil {
+			t.Errorf("%s: not uploaded", tt.name)
+			continue
+		}
+		if obj.CacheControl != tt.wantCacheControl {
+			t.Errorf("%s: cache-control: got %q want %q", tt.name, obj.CacheControl, tt.wantCacheControl)
+		}
+		if obj.ContentType != tt.wantContentType {
+			t.Errorf("%s: content-type: got %q want %q", tt.name, obj.ContentType, tt.wantContentType)
+		}
+		if got := string(fs.contents["site/v2/"+tt.name]); got != files[tt.name] {
+			t.Errorf("%s: content: got %q want %q", tt.name, got, files[tt.name])
+		}
+	}
+
+	bad := map[string]UploadParams{"[.css": {CacheControl: "no-cache"}}
+	if _, err := client.UploadDirWithManifest(context.Background(), dir, "site", "", bad); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+	for _, entry := range []UploadParams{{Public: true}, {InheritBucketACL: true}, {ContentAddressed: true}} {
+		manifest := map[string]UploadParams{"*.css": entry}
+		if _, err := client.UploadDirWithManifest(context.Background(), dir, "site", "", manifest); !errors.Is(err, errManifestUploadSettings) {
+			t.Errorf("%+v: got err %v want %v", entry, err, errManifestUploadSettings)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MultiError is a list of independent errors, such as
+// all the problems that ValidateSetup found at once.
+type MultiError []error
+
+var _ error = (MultiError)(nil)
+
+func (me MultiError) Error() string {
+	if len(me) == 1 {
+		return me[0].Error()
+	}
+	msgs := make([]string, 0, len(me))
+	for _, err := range me {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d errors: %s", len(me), strings.Join(msgs, "; "))
+}
+
+// Is reports whether any of the errors is target, for errors.Is.
+func (me MultiError) Is(target error) bool {
+	for _, err := range me {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	errBlankAlias          = errors.New("expecting non-blank aliases")
+	errAliasIsDomainName   = errors.New("alias is the domain name itself")
+	errDuplicateAlias      = errors.New("duplicate alias")
+	errNotIPV4Address      = errors.New("not an IPv4 address")
+	errNameOutsideZone     = errors.New("DNS name is outside of the managed zone")
+	errManagedZoneNotFound = errors.New("managed zone doesn't exist")
+)
+
+// ValidateSetup checks everything about req that would make FullSetup
+// fail, without setting anything up: unlike Setup.Validate it also
+// checks the machine to create, the IPv4 addresses, the aliases and
+// that the domain name and aliases are in the managed zone, along with
+// the compute zone and the private zone if any, reading them from the
+// APIs. It returns every problem found, as a MultiError, or nil.
+func (c *Client) ValidateSetup(ctx context.Context, req *Setup) error {
+	req = inProject(req, c.DefaultProject)
+	if req == nil {
+		return MultiError{errEmptyProject}
+	}
+
+	var errs MultiError
+	if strings.TrimSpace(req.Project) == "" {
+		errs = append(errs, errEmptyProject)
+	}
+	if strings.TrimSpace(req.Zone) == "" {
+		errs = append(errs, errEmptyZone)
+	}
+	if req.DomainName == "" {
+		errs = append(errs, errEmptyDomainName)
+	}
+	for _, labels := range []map[string]string{req.CommonLabels, req.SetupLabels} {
+		if err := validateLabels(labels); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, addr := range req.IPV4Addresses {
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() == nil {
+			errs = append(errs, fmt.Errorf("%w: %q", errNotIPV4Address, addr))
+		}
+	}
+	errs = append(errs, req.validateAliases()...)
+
+	if strings.TrimSpace(req.Project) == "" || strings.TrimSpace(req.Zone) == "" {
+		// Without them, nothing can be read from the APIs.
+		return errs
+	}
+
+	if len(req.IPV4Addresses) == 0 {
+		ireq := req.instanceRequest()
+		if err := ireq.validateForCreate(); err != nil {
+			errs = append(errs, fmt.Errorf("machine: %w", err))
+		} else if err := c.validateZoneForCreate(ctx, ireq); err != nil {
+			errs = append(errs, fmt.Errorf("machine: %w", err))
+		}
+	}
+
+	if req.DomainName != "" {
+		errs = append(errs, c.checkNamesInZone(ctx, req)...)
+	}
+
+	if req.PrivateZone != nil {
+		if req.MachineName == "" {
+			errs = append(errs, errEmptyMachineName)
+		}
+		preq := inProject(req.PrivateZone, req.Project)
+		if err := preq.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("private zone: %w", err))
+		} else if mz, err := c.managedZonesService().Get(preq.Project, preq.Name).Context(ctx).Do(); err == nil {
+			if err := preq.checkZone(mz); err != nil {
+				errs = append(errs, err)
+			}
+		} else if !isNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (req *Setup) validateAliases() (errs []error) {
+	domainName := ensureHasTrailingDot(req.DomainName)
+	seen := make(map[string]bool)
+	for _, alias := range req.Aliases {
+		if strings.TrimSpace(alias) == "" {
+			errs = append(errs, errBlankAlias)
+			continue
+		}
+		name := ensureHasTrailingDot(alias)
+		switch {
+		case name == domainName:
+			errs = append(errs, fmt.Errorf("%w: %q", errAliasIsDomainName, alias))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("%w: %q", errDuplicateAlias, alias))
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
+// checkNamesInZone checks that the managed zone of req exists and that
+// the domain name and the aliases are within its DNS name.
+func (c *Client) checkNamesInZone(ctx context.Context, req *Setup) (errs []error) {
+	mz, err := c.managedZonesService().Get(req.Project, req.Zone).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return []error{fmt.Errorf("%w: %q", errManagedZoneNotFound, req.Zone)}
+		}
+		return []error{err}
+	}
+
+	apex := ensureHasTrailingDot(mz.DnsName)
+	for _, name := range append([]string{req.DomainName}, req.Aliases...) {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if fqdn := ensureHasTrailingDot(name); fqdn != apex && !strings.HasSuffix(fqdn, "."+apex) {
+			errs = append(errs, fmt.Errorf("%w: %q isn't under %q", errNameOutsideZone, name, apex))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/dns/v1"
+)
+
+func TestValidateSetupReportsAllProblems(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + fakeZonePath:
+			writeJSON(w, &dns.ManagedZone{Name: "zone", DnsName: "orijtech.com."})
+		default:
+			writeNotFound(w)
+		}
+	})
+	ctx := context.Background()
+
+	err := client.ValidateSetup(ctx, &Setup{
+		Project:       "sample",
+		Zone:          "zone",
+		DomainName:    "www.orijtech.com",
+		IPV4Addresses: []string{"10.0.0.1", "10.0.0", "::1"},
+		Aliases:       []string{"orijtech.com", "www.orijtech.com", "orijtech.com.", "www.example.com"},
+	})
+	var errs MultiError
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected a MultiError, got %v", err)
+	}
+	wants := []error{errNotIPV4Address, errAliasIsDomainName, errDuplicateAlias, errNameOutsideZone}
+	for _, want := range wants {
+		if !errors.Is(err, want) {
+			t.Errorf("expected %q to be reported in %v", want, err)
+		}
+	}
+	// Both bad addresses are reported.
+	if len(errs) != len(wants)+1 {
+		t.Errorf("got %d errors want %d: %v", len(errs), len(wants)+1, err)
+	}
+
+	// Without addresses, the machine to create is checked too.
+	err = client.ValidateSetup(ctx, &Setup{Project: "sample", Zone: "staging", DomainName: "staging.orijtech.com"})
+	if !errors.Is(err, errBlankName) || !errors.Is(err, errManagedZoneNotFound) {
+		t.Errorf("expected the blank machine name and the missing zone, got %v", err)
+	}
+
+	err = client.ValidateSetup(ctx, &Setup{
+		Project:       "sample",
+		Zone:          "zone",
+		DomainName:    "www.orijtech.com",
+		IPV4Addresses: []string{"10.0.0.1"},
+		Aliases:       []string{"orijtech.com"},
+	})
+	if err != nil {
+		t.Errorf("ValidateSetup of a valid setup: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+)
+
+// ZoneStatus returns the status of the zone, "UP" if
+// it is available or "DOWN" if it is undergoing an outage.
+func (c *Client) ZoneStatus(ctx context.Context, project, zone string) (string, error) {
+	project = c.projectOrDefault(project)
+	if project == "" {
+		return "", errEmptyProject
+	}
+	if err := validateZone(zone); err != nil {
+		return "", err
+	}
+	z, err := c.zone(ctx, project, zone)
+	if err != nil {
+		return "", err
+	}
+	return z.Status, nil
+}
+
+// validateZoneForCreate checks, before an instance is created, that
+// its zone is UP unless ireq skips that check and that the zone offers
+// the CPU platform and the accelerators requested by ireq.
+func (c *Client) validateZoneForCreate(ctx context.Context, ireq *InstanceRequest) error {
+	if len(ireq.Accelerators) > 0 && !ireq.SkipAcceleratorCheck {
+		if err := c.checkAcceleratorsAvailable(ctx, ireq); err != nil {
+			return err
+		}
+	}
+	if ireq.SkipZoneStatusCheck && ireq.MinCPUPlatform == "" {
+		return nil
+	}
+	z, err := c.zone(ctx, ireq.Project, ireq.Zone)
+	if err != nil {
+		return err
+	}
+	if !ireq.SkipZoneStatusCheck && z.Status != "UP" {
+		return fmt.Errorf("zone %q is %s, expecting it to be UP before creating instances in it", ireq.Zone, z.Status)
+	}
+	return ireq.validateMinCPUPlatform(z.AvailableCpuPlatforms)
+}
@@ -0,0 +1,65 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestCreateInstanceRefusesDownZone(t *testing.T) {
+	const zonePath = "/compute/v1/projects/sample/zones/us-central1-c"
+	var inserts int
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch route := req.Method + " " + req.URL.Path; route {
+		case "GET " + zonePath:
+			writeJSON(w, &compute.Zone{Name: "us-central1-c", Status: "DOWN"})
+		case "POST " + zonePath + "/instances":
+			inserts++
+			writeJSON(w, &compute.Operation{Name: "op-insert"})
+		default:
+			http.Error(w, "unexpected route "+route, http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	status, err := client.ZoneStatus(ctx, "sample", "us-central1-c")
+	if err != nil {
+		t.Fatalf("ZoneStatus: %v", err)
+	}
+	if status != "DOWN" {
+		t.Errorf("status: got %q want %q", status, "DOWN")
+	}
+
+	ireq := &InstanceRequest{
+		Project:          "sample",
+		Zone:             "us-central1-c",
+		Name:             "web",
+		NetworkInterface: BasicExternalNATNetworkInterface,
+	}
+	_, err = client.CreateInstance(ctx, ireq)
+	if err == nil || !strings.Contains(err.Error(), `zone "us-central1-c" is DOWN`) {
+		t.Errorf("got err %v, want the zone to be reported DOWN", err)
+	}
+	results, err := client.CreateInstances(ctx, []*InstanceRequest{ireq})
+	if err != nil {
+		t.Fatalf("CreateInstances: %v", err)
+	}
+	if err := results[0].Err; err == nil || !strings.Contains(err.Error(), "is DOWN") {
+		t.Errorf("CreateInstances: got err %v, want the zone to be reported DOWN", err)
+	}
+	if inserts != 0 {
+		t.Errorf("expected no inserts, got %d", inserts)
+	}
+
+	ireq.SkipZoneStatusCheck = true
+	results, err = client.CreateInstances(ctx, []*InstanceRequest{ireq})
+	if err != nil {
+		t.Fatalf("CreateInstances: %v", err)
+	}
+	if results[0].Err != nil || inserts != 1 {
+		t.Errorf("skipped check: got err %v and %d inserts, want the instance inserted", results[0].Err, inserts)
+	}
+}